@@ -4,8 +4,10 @@ import (
 	"flag"
 	"github.com/ZZGADA/easy-deploy/internal/config"
 	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
 	"github.com/ZZGADA/easy-deploy/internal/model/scheduled_tasks"
 	"github.com/ZZGADA/easy-deploy/internal/model/server/http"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
@@ -31,6 +33,14 @@ func init() {
 	conf.InitRedis()
 	conf.InitWebSocketServer()
 
+	// 已注册集群的连接配置从数据库加载
+	conf.K8sClusterConfigLoader = k8s_manage.NewK8sClusterService(dao.NewUserK8sClusterDao(conf.DB), dao.NewUsersDao(conf.DB)).LoadRestConfig
+
+	// helm 类型的资源依赖服务器上的 helm 命令行，缺失时只影响 helm 资源，不阻止启动
+	if err := k8s_manage.CheckHelmBinary(); err != nil {
+		log.Errorf("helm 环境检查失败: %v", err)
+	}
+
 	// 初始化并启动 K8s 资源状态检查器
 	scheduled_tasks.Init()
 
@@ -40,6 +40,7 @@ type Config struct {
 		Port            int    `mapstructure:"port"`
 		Path            string `mapstructure:"path"`
 		PathK8s         string `mapstructure:"path_k8s"`
+		PathK8sExec     string `mapstructure:"path_k8s_exec"`
 		ReadBufferSize  int    `mapstructure:"read_buffer_size"`
 		WriteBufferSize int    `mapstructure:"write_buffer_size"`
 	}
@@ -57,6 +58,18 @@ type Config struct {
 		GroupId string   `mapstructure:"group_id"`
 	}
 
+	K8s struct {
+		ClusterSecretKey string   // 集群凭证加密密钥，从 .env 读取
+		HelmRepositories []string `mapstructure:"helm_repositories"` // helm chart 仓库白名单
+		// 兼容部署早于资源 ID 标签的对象：开启后没有标签的对象按 namespace 和名称推断所属资源
+		LegacyNameAttribution bool `mapstructure:"legacy_name_attribution"`
+	}
+
+	// kube 命令白名单：团队角色（creator / member / none）-> 允许的动词，未配置时使用内置默认值
+	K8sCommand struct {
+		Allowlist map[string][]string `mapstructure:"allowlist"`
+	} `mapstructure:"k8s_command"`
+
 	Smtp struct {
 		From     string `mapstructure:"from"`
 		Host     string `mapstructure:"host"`
@@ -101,5 +114,8 @@ func InitConfig(env string) error {
 	GlobalConfig.Github.ClientID = envViper.GetString("GITHUB_CLIENT_ID")
 	GlobalConfig.Github.ClientSecret = envViper.GetString("GITHUB_CLIENT_SECRET")
 
+	// 3.3 从 .env 文件映射集群凭证加密密钥
+	GlobalConfig.K8s.ClusterSecretKey = envViper.GetString("K8S_CLUSTER_SECRET_KEY")
+
 	return nil
 }
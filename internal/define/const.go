@@ -1,5 +1,7 @@
 package define
 
+import "time"
+
 const (
 	VerifyCodeEmail  = "verify_code_email:%s"
 	RegisterPassword = "register_password:%s"
@@ -13,15 +15,60 @@ const (
 )
 
 const (
-	K8sResourceStatusRun     = 1 // 1 运行正常
-	K8sResourceStatusStop    = 2 // 2 运行停止
-	K8sResourceStatusRestart = 3 // 3 容器重启
+	K8sResourceStatusRun       = 1 // 1 运行正常
+	K8sResourceStatusStop      = 2 // 2 运行停止
+	K8sResourceStatusRestart   = 3 // 3 容器重启
+	K8sResourceStatusSucceeded = 4 // 4 任务完成，Job 全部完成
+	K8sResourceStatusFailed    = 5 // 5 任务失败，Job 超过重试次数或运行时间
+)
+
+const (
+	K8sRolloutSucceeded   = "succeeded"   // 新版本的副本全部更新并可用
+	K8sRolloutFailed      = "failed"      // 超过 progressDeadlineSeconds 仍未完成
+	K8sRolloutTimeout     = "timeout"     // 平台等待超时，rollout 仍在进行
+	K8sRolloutProgressing = "progressing" // apply 完成后平台仍在跟踪 rollout
 )
 
 const (
 	K8sRunningResources = "k8s:running_resources:%d"
 )
 
+const (
+	K8sResourceUsageRetention = 24 * time.Hour // 资源用量采样的保留时间
+)
+
+const (
+	K8sClusterStatusUnknown   = 0 // 0 未检查
+	K8sClusterStatusHealthy   = 1 // 1 连接正常
+	K8sClusterStatusUnhealthy = 2 // 2 连接失败
+)
+
+const (
+	K8sClusterAuthKubeconfig = "kubeconfig"
+	K8sClusterAuthToken      = "token"
+)
+
+const (
+	K8sFieldManager = "easy-deploy" // server-side apply 的 field manager
+)
+
+const (
+	K8sLabelTeamID     = "easy-deploy.io/team-id"     // 团队 namespace 和平台部署对象的团队标签
+	K8sLabelManaged    = "easy-deploy.io/managed"     // 平台管理的 namespace 标签
+	K8sLabelResourceID = "easy-deploy.io/resource-id" // 平台部署对象对应的 k8s_resource_id，Pod 模板也带有该标签
+	K8sLabelUserID     = "easy-deploy.io/user-id"     // 平台部署对象的部署者
+
+	K8sTeamServiceAccount = "team-deployer" // 团队 namespace 内的 ServiceAccount / Role / RoleBinding 名称
+	K8sTeamResourceQuota  = "team-quota"
+	K8sTeamLimitRange     = "team-limits"
+)
+
+const (
+	TeamRoleCreator = "creator" // 团队创建者
+	TeamRoleMember  = "member"  // 团队成员
+	TeamRoleNone    = "none"    // 未加入团队
+)
+
 const (
 	TeamRequestStatusWait     = 0 // 0: 待处理,
 	TeamRequestStatusApproval = 1 // 1: 已同意,
@@ -3,17 +3,153 @@ package conf
 import (
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"path/filepath"
+	"sync"
 )
 
+// DefaultClusterID 默认集群（本机 ~/.kube/config）的集群 ID
+const DefaultClusterID uint32 = 0
+
 var (
 	KubeClient *kubernetes.Clientset
 	KubeConfig *rest.Config
+
+	// K8sClusterConfigLoader 根据集群 ID 加载已注册集群的连接配置，由集群管理服务注入
+	K8sClusterConfigLoader func(clusterID uint32) (*rest.Config, error)
+
+	// K8sClientsEvicted 集群客户端被清除后的回调，用于停止依赖旧客户端的 informer，由状态检查器注入
+	K8sClientsEvicted func(clusterID uint32)
+
+	k8sClientCache sync.Map // key: clusterID, value: *K8sClients
 )
 
+// K8sClients 单个集群的客户端集合
+type K8sClients struct {
+	ClusterID uint32
+	Client    *kubernetes.Clientset
+	Config    *rest.Config
+	Dynamic   dynamic.Interface
+	Mapper    *restmapper.DeferredDiscoveryRESTMapper
+}
+
+// NewK8sClients 根据连接配置创建集群的客户端集合
+func NewK8sClients(clusterID uint32, config *rest.Config) (*K8sClients, error) {
+	// 创建客户端集
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %v", err)
+	}
+
+	// 创建 dynamic 客户端，用于 apply 任意类型的资源
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+
+	return &K8sClients{
+		ClusterID: clusterID,
+		Client:    clientSet,
+		Config:    config,
+		Dynamic:   dynamicClient,
+		// 通过 discovery 解析 GVK -> GVR，带内存缓存
+		Mapper: restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientSet.Discovery())),
+	}, nil
+}
+
+// ResourceInterface 通过 RESTMapper 解析对象的 GVK，返回对应的 dynamic 资源接口；未声明 namespace 的对象使用 defaultNamespace
+func (c *K8sClients) ResourceInterface(obj *unstructured.Unstructured, defaultNamespace string) (dynamic.ResourceInterface, *meta.RESTMapping, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := c.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		// CRD 等新注册的类型可能不在缓存中，重置后重试一次
+		c.Mapper.Reset()
+		mapping, err = c.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, nil, fmt.Errorf("无法识别的资源类型 %s: %w", gvk.String(), err)
+		}
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		// 集群级资源不需要 namespace
+		obj.SetNamespace("")
+		return c.Dynamic.Resource(mapping.Resource), mapping, nil
+	}
+
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(defaultNamespace)
+	}
+	return c.Dynamic.Resource(mapping.Resource).Namespace(obj.GetNamespace()), mapping, nil
+}
+
+// Kubeconfig 使用集群地址和给定的 token 生成 kubeconfig，供 helm 等命令行工具连接集群
+func (c *K8sClients) Kubeconfig(token string, namespace string) ([]byte, error) {
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = c.Config.Host
+	cluster.TLSServerName = c.Config.TLSClientConfig.ServerName
+	cluster.InsecureSkipTLSVerify = c.Config.TLSClientConfig.Insecure
+	cluster.CertificateAuthorityData = c.Config.TLSClientConfig.CAData
+	if len(cluster.CertificateAuthorityData) == 0 {
+		cluster.CertificateAuthority = c.Config.TLSClientConfig.CAFile
+	}
+
+	authInfo := clientcmdapi.NewAuthInfo()
+	authInfo.Token = token
+
+	context := clientcmdapi.NewContext()
+	context.Cluster = "cluster"
+	context.AuthInfo = "user"
+	context.Namespace = namespace
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["cluster"] = cluster
+	kubeconfig.AuthInfos["user"] = authInfo
+	kubeconfig.Contexts["default"] = context
+	kubeconfig.CurrentContext = "default"
+	return clientcmd.Write(*kubeconfig)
+}
+
+// GetK8sClients 获取集群的客户端集合，已注册集群的客户端按集群 ID 缓存
+func GetK8sClients(clusterID uint32) (*K8sClients, error) {
+	if clients, ok := k8sClientCache.Load(clusterID); ok {
+		return clients.(*K8sClients), nil
+	}
+
+	if K8sClusterConfigLoader == nil {
+		return nil, fmt.Errorf("集群 %d 不存在", clusterID)
+	}
+	config, err := K8sClusterConfigLoader(clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("加载集群 %d 配置失败: %v", clusterID, err)
+	}
+
+	clients, err := NewK8sClients(clusterID, config)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := k8sClientCache.LoadOrStore(clusterID, clients)
+	return actual.(*K8sClients), nil
+}
+
+// EvictK8sClients 集群凭证变更或删除后清除缓存的客户端
+func EvictK8sClients(clusterID uint32) {
+	if clusterID == DefaultClusterID {
+		return
+	}
+	k8sClientCache.Delete(clusterID)
+	if K8sClientsEvicted != nil {
+		K8sClientsEvicted(clusterID)
+	}
+}
+
 func InitK8s() {
 	// 定义 kubeconfig 文件的路径
 	kubeconfigPath := filepath.Join(
@@ -30,12 +166,12 @@ func InitK8s() {
 	// 打印 API 服务器的 URL
 	logrus.Infof("K8S API Server URL: %s\n", config.Host)
 
-	// 创建客户端集
-	clientSet, err := kubernetes.NewForConfig(config)
+	clients, err := NewK8sClients(DefaultClusterID, config)
 	if err != nil {
-		panic(fmt.Errorf("failed to create clientset: %v", err))
+		panic(err)
 	}
 
-	KubeClient = clientSet
-	KubeConfig = config
+	KubeClient = clients.Client
+	KubeConfig = clients.Config
+	k8sClientCache.Store(DefaultClusterID, clients)
 }
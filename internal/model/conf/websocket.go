@@ -3,8 +3,7 @@ package conf
 import (
 	"fmt"
 	"net/http"
-
-	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"sync"
 
 	"github.com/ZZGADA/easy-deploy/internal/config"
 	"github.com/gorilla/websocket"
@@ -20,11 +19,12 @@ type WebSocketServer struct {
 	Port            int
 	Path            string
 	PathK8s         string
+	PathK8sExec     string
 	ReadBufferSize  int
 	WriteBufferSize int
 	Upgrader        *websocket.Upgrader
 	Connections     map[uint]*websocket.Conn // key: userID
-	OssClient       map[uint]*oss.Bucket
+	writeLocks      sync.Map                 // key: *websocket.Conn, value: *sync.Mutex
 }
 
 // InitWebSocketServer 初始化 WebSocket 服务器
@@ -33,10 +33,10 @@ func InitWebSocketServer() {
 		Port:            config.GlobalConfig.WebSocket.Port,
 		Path:            config.GlobalConfig.WebSocket.Path,
 		PathK8s:         config.GlobalConfig.WebSocket.PathK8s,
+		PathK8sExec:     config.GlobalConfig.WebSocket.PathK8sExec,
 		ReadBufferSize:  config.GlobalConfig.WebSocket.ReadBufferSize,
 		WriteBufferSize: config.GlobalConfig.WebSocket.WriteBufferSize,
 		Connections:     make(map[uint]*websocket.Conn),
-		OssClient:       make(map[uint]*oss.Bucket),
 	}
 
 	WSUpgrader = &websocket.Upgrader{
@@ -59,3 +59,16 @@ func GetWSServer() *WebSocketServer {
 func (s *WebSocketServer) GetWSAddress() string {
 	return fmt.Sprintf(":%d", s.Port)
 }
+
+// WriteJSON 向连接写入 JSON 消息；流式推送和命令响应可能同时写同一个连接，需要加锁
+func (s *WebSocketServer) WriteJSON(conn *websocket.Conn, v interface{}) error {
+	lock, _ := s.writeLocks.LoadOrStore(conn, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+	return conn.WriteJSON(v)
+}
+
+// ReleaseConn 连接关闭后释放写锁
+func (s *WebSocketServer) ReleaseConn(conn *websocket.Conn) {
+	s.writeLocks.Delete(conn)
+}
@@ -17,6 +17,15 @@ type Team struct {
 	CreatedAt       *time.Time `gorm:"column:created_at;type:datetime;not null;" json:"created_at"`
 	UpdatedAt       *time.Time `gorm:"column:updated_at;type:datetime;not null;" json:"updated_at"`
 	DeletedAt       *time.Time `gorm:"column:deleted_at;type:datetime;default:NULL;" json:"deleted_at"`
+
+	// 团队 namespace 的配额设置，为空表示不限制
+	K8sCPUQuota          string `gorm:"column:k8s_cpu_quota;type:varchar(50);" json:"k8s_cpu_quota"`
+	K8sMemoryQuota       string `gorm:"column:k8s_memory_quota;type:varchar(50);" json:"k8s_memory_quota"`
+	K8sPodQuota          string `gorm:"column:k8s_pod_quota;type:varchar(50);" json:"k8s_pod_quota"`
+	K8sDefaultCPULimit   string `gorm:"column:k8s_default_cpu_limit;type:varchar(50);" json:"k8s_default_cpu_limit"`
+	K8sDefaultMemLimit   string `gorm:"column:k8s_default_mem_limit;type:varchar(50);" json:"k8s_default_mem_limit"`
+	K8sDefaultCPURequest string `gorm:"column:k8s_default_cpu_request;type:varchar(50);" json:"k8s_default_cpu_request"`
+	K8sDefaultMemRequest string `gorm:"column:k8s_default_mem_request;type:varchar(50);" json:"k8s_default_mem_request"`
 }
 
 // TableName 指定表名
@@ -0,0 +1,65 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TeamK8sNamespace 团队在集群中管理的 namespace
+type TeamK8sNamespace struct {
+	Id        uint32         `gorm:"column:id;type:int UNSIGNED;primaryKey;not null;" json:"id"`
+	TeamID    uint32         `gorm:"column:team_id;not null" json:"team_id"`
+	ClusterID uint32         `gorm:"column:cluster_id;not null" json:"cluster_id"`
+	Namespace string         `gorm:"column:namespace;type:varchar(63);not null" json:"namespace"`
+	CreatorID uint32         `gorm:"column:creator_id;not null" json:"creator_id"`
+	CreatedAt *time.Time     `gorm:"column:created_at;type:datetime;not null;" json:"created_at"`
+	UpdatedAt *time.Time     `gorm:"column:updated_at;type:datetime;not null;" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;type:datetime;default:NULL;" json:"deleted_at"`
+}
+
+func (TeamK8sNamespace) TableName() string {
+	return "team_k8s_namespace"
+}
+
+func NewTeamK8sNamespaceDao(db *gorm.DB) *TeamK8sNamespaceDao {
+	return &TeamK8sNamespaceDao{db: db}
+}
+
+type TeamK8sNamespaceDao struct {
+	db *gorm.DB
+}
+
+// Create 记录团队 namespace
+func (d *TeamK8sNamespaceDao) Create(namespace *TeamK8sNamespace) error {
+	return d.db.Create(namespace).Error
+}
+
+// Delete 删除团队 namespace 记录（软删除）
+func (d *TeamK8sNamespaceDao) Delete(id uint32) error {
+	return d.db.Delete(&TeamK8sNamespace{}, id).Error
+}
+
+// QueryById 根据 ID 查询
+func (d *TeamK8sNamespaceDao) QueryById(id uint32) (*TeamK8sNamespace, error) {
+	var namespace TeamK8sNamespace
+	err := d.db.Where("id = ? and deleted_at IS NULL", id).First(&namespace).Error
+	if err != nil {
+		return nil, err
+	}
+	return &namespace, nil
+}
+
+// QueryByTeamID 查询团队的全部 namespace
+func (d *TeamK8sNamespaceDao) QueryByTeamID(teamID uint32) ([]TeamK8sNamespace, error) {
+	var namespaces []TeamK8sNamespace
+	err := d.db.Where("team_id = ? and deleted_at IS NULL", teamID).Order("id DESC").Find(&namespaces).Error
+	return namespaces, err
+}
+
+// QueryByClusterAndNamespace 查询集群中某个 namespace 的归属
+func (d *TeamK8sNamespaceDao) QueryByClusterAndNamespace(clusterID uint32, namespace string) ([]TeamK8sNamespace, error) {
+	var namespaces []TeamK8sNamespace
+	err := d.db.Where("cluster_id = ? and namespace = ? and deleted_at IS NULL", clusterID, namespace).Find(&namespaces).Error
+	return namespaces, err
+}
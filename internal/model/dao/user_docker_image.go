@@ -52,3 +52,13 @@ func (d *UserDockerImageDao) GetByRepositoryID(ctx context.Context, repositoryID
 		Find(&images).Error
 	return images, err
 }
+
+// GetByID 根据 ID 查询记录
+func (d *UserDockerImageDao) GetByID(ctx context.Context, id uint32) (*UserDockerImage, error) {
+	var image UserDockerImage
+	err := d.db.WithContext(ctx).Where("id = ? and deleted_at IS NULL", id).First(&image).Error
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserK8sCluster 用户/团队注册的 K8s 集群
+type UserK8sCluster struct {
+	Id          uint32         `gorm:"column:id;type:int UNSIGNED;primaryKey;not null;" json:"id"`
+	UserID      uint32         `gorm:"column:user_id;not null" json:"user_id"`
+	TeamID      uint32         `gorm:"column:team_id" json:"team_id"`
+	Name        string         `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	ApiServer   string         `gorm:"column:api_server;type:varchar(255)" json:"api_server"`
+	AuthType    string         `gorm:"column:auth_type;type:varchar(50);not null" json:"auth_type"` // kubeconfig / token
+	Credential  string         `gorm:"column:credential;type:text;not null" json:"-"`               // 加密后的 kubeconfig 或 token
+	CAData      string         `gorm:"column:ca_data;type:text" json:"-"`                           // 加密后的 CA 证书，token 方式使用
+	Insecure    bool           `gorm:"column:insecure;not null;default:false" json:"insecure"`      // 跳过 API Server 证书校验，需要注册时显式开启
+	Status      int            `gorm:"column:status;not null" json:"status"`                        // 健康状态
+	Version     string         `gorm:"column:version;type:varchar(50)" json:"version"`
+	Message     string         `gorm:"column:message;type:varchar(500)" json:"message"`
+	LastCheckAt *time.Time     `gorm:"column:last_check_at;type:datetime" json:"last_check_at"`
+	CreatedAt   *time.Time     `gorm:"column:created_at;type:datetime;not null;" json:"created_at"`
+	UpdatedAt   *time.Time     `gorm:"column:updated_at;type:datetime;not null;" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at;type:datetime;default:NULL;" json:"deleted_at"`
+}
+
+func (UserK8sCluster) TableName() string {
+	return "user_k8s_cluster"
+}
+
+func NewUserK8sClusterDao(db *gorm.DB) *UserK8sClusterDao {
+	return &UserK8sClusterDao{db: db}
+}
+
+type UserK8sClusterDao struct {
+	db *gorm.DB
+}
+
+// Create 注册集群
+func (d *UserK8sClusterDao) Create(cluster *UserK8sCluster) error {
+	return d.db.Create(cluster).Error
+}
+
+// Delete 删除集群（软删除）
+func (d *UserK8sClusterDao) Delete(id uint32) error {
+	return d.db.Delete(&UserK8sCluster{}, id).Error
+}
+
+// UpdateHealth 更新集群健康状态
+func (d *UserK8sClusterDao) UpdateHealth(id uint32, status int, version string, message string) error {
+	return d.db.Model(&UserK8sCluster{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":        status,
+		"version":       version,
+		"message":       message,
+		"last_check_at": time.Now(),
+	}).Error
+}
+
+// QueryById 根据 ID 查询集群
+func (d *UserK8sClusterDao) QueryById(id uint32) (*UserK8sCluster, error) {
+	var cluster UserK8sCluster
+	err := d.db.Where("id = ? and deleted_at IS NULL", id).First(&cluster).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cluster, nil
+}
+
+// QueryByUserOrTeam 查询用户自己注册的以及所在团队的集群
+func (d *UserK8sClusterDao) QueryByUserOrTeam(userID uint32, teamID uint32) ([]UserK8sCluster, error) {
+	var clusters []UserK8sCluster
+	query := d.db.Where("deleted_at IS NULL")
+	if teamID != 0 {
+		query = query.Where("user_id = ? or team_id = ?", userID, teamID)
+	} else {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Order("id DESC").Find(&clusters).Error
+	return clusters, err
+}
@@ -15,6 +15,7 @@ type UserK8sResource struct {
 	FileName         string         `gorm:"column:file_name;not null" json:"file_name"`
 	IsUpdate         bool           `gorm:"column:is_update;not null" json:"is_update"`
 	FatherResourceId uint32         `gorm:"column:father_resource_id" json:"father_resource_id"`
+	ClusterID        uint32         `gorm:"column:cluster_id" json:"cluster_id"` // 目标集群，0 为默认集群
 	CreatedAt        *time.Time     `gorm:"column:created_at;type:datetime;not null;" json:"created_at"`
 	UpdatedAt        *time.Time     `gorm:"column:updated_at;type:datetime;not null;" json:"updated_at"`
 	DeletedAt        gorm.DeletedAt `gorm:"column:deleted_at;type:datetime;default:NULL;" json:"deleted_at"`
@@ -64,6 +65,27 @@ func (d *UserK8sResourceDao) QueryById(id uint32) (UserK8sResource, error) {
 	return resources, err
 }
 
+// QueryVersionChain 从指定版本开始沿 FatherResourceId 向上查询历史版本，结果按从新到旧排列
+func (d *UserK8sResourceDao) QueryVersionChain(id uint32) ([]UserK8sResource, error) {
+	var chain []UserK8sResource
+	visited := make(map[uint32]bool)
+	for id != 0 && !visited[id] {
+		visited[id] = true
+		var resource UserK8sResource
+		err := d.db.Where("id = ? and deleted_at IS NULL", id).First(&resource).Error
+		if err != nil {
+			if len(chain) > 0 && err == gorm.ErrRecordNotFound {
+				// 历史版本已被删除，链路到此为止
+				break
+			}
+			return nil, err
+		}
+		chain = append(chain, resource)
+		id = resource.FatherResourceId
+	}
+	return chain, nil
+}
+
 // QueryByRepositoryAndType 根据仓库ID和资源类型查询配置列表
 func (d *UserK8sResourceDao) QueryByRepositoryAndType(repositoryID string, resourceType string) ([]UserK8sResource, error) {
 	var resources []UserK8sResource
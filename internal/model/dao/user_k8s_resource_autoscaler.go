@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserK8sResourceAutoscaler Deployment 资源的自动扩缩容策略，每个资源版本一条记录，apply 时同步为 autoscaling/v2 HPA
+type UserK8sResourceAutoscaler struct {
+	Id                            uint32         `gorm:"column:id;type:int UNSIGNED;primaryKey;not null;" json:"id"`
+	K8sResourceID                 uint32         `gorm:"column:k8s_resource_id;not null" json:"k8s_resource_id"`
+	MinReplicas                   int32          `gorm:"column:min_replicas;not null" json:"min_replicas"`
+	MaxReplicas                   int32          `gorm:"column:max_replicas;not null" json:"max_replicas"`
+	TargetCPUUtilization          int32          `gorm:"column:target_cpu_utilization;not null;default:0" json:"target_cpu_utilization"`               // CPU 平均使用率目标（百分比），0 表示不按 CPU 扩缩
+	TargetMemoryUtilization       int32          `gorm:"column:target_memory_utilization;not null;default:0" json:"target_memory_utilization"`         // 内存平均使用率目标（百分比），0 表示不按内存扩缩
+	ScaleUpStabilizationSeconds   *int32         `gorm:"column:scale_up_stabilization_seconds;default:NULL" json:"scale_up_stabilization_seconds"`     // 扩容稳定窗口，为空时使用 k8s 默认值
+	ScaleDownStabilizationSeconds *int32         `gorm:"column:scale_down_stabilization_seconds;default:NULL" json:"scale_down_stabilization_seconds"` // 缩容稳定窗口，为空时使用 k8s 默认值
+	UserID                        uint32         `gorm:"column:user_id;not null" json:"user_id"`
+	CreatedAt                     *time.Time     `gorm:"column:created_at;type:datetime;not null;" json:"created_at"`
+	UpdatedAt                     *time.Time     `gorm:"column:updated_at;type:datetime;not null;" json:"updated_at"`
+	DeletedAt                     gorm.DeletedAt `gorm:"column:deleted_at;type:datetime;default:NULL;" json:"deleted_at"`
+}
+
+func (UserK8sResourceAutoscaler) TableName() string {
+	return "user_k8s_resource_autoscaler"
+}
+
+func NewUserK8sResourceAutoscalerDao(db *gorm.DB) *UserK8sResourceAutoscalerDao {
+	return &UserK8sResourceAutoscalerDao{db: db}
+}
+
+type UserK8sResourceAutoscalerDao struct {
+	db *gorm.DB
+}
+
+// Save 保存资源的扩缩容策略，已存在时覆盖
+func (d *UserK8sResourceAutoscalerDao) Save(autoscaler *UserK8sResourceAutoscaler) error {
+	existing, err := d.QueryByResourceID(autoscaler.K8sResourceID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if existing != nil {
+		return d.db.Model(&UserK8sResourceAutoscaler{}).Where("id = ?", existing.Id).Updates(map[string]interface{}{
+			"min_replicas":                     autoscaler.MinReplicas,
+			"max_replicas":                     autoscaler.MaxReplicas,
+			"target_cpu_utilization":           autoscaler.TargetCPUUtilization,
+			"target_memory_utilization":        autoscaler.TargetMemoryUtilization,
+			"scale_up_stabilization_seconds":   autoscaler.ScaleUpStabilizationSeconds,
+			"scale_down_stabilization_seconds": autoscaler.ScaleDownStabilizationSeconds,
+			"user_id":                          autoscaler.UserID,
+		}).Error
+	}
+	return d.db.Create(autoscaler).Error
+}
+
+// DeleteByResourceID 删除资源的扩缩容策略（软删除）
+func (d *UserK8sResourceAutoscalerDao) DeleteByResourceID(k8sResourceID uint32) error {
+	return d.db.Where("k8s_resource_id = ?", k8sResourceID).Delete(&UserK8sResourceAutoscaler{}).Error
+}
+
+// QueryByResourceID 查询资源的扩缩容策略
+func (d *UserK8sResourceAutoscalerDao) QueryByResourceID(k8sResourceID uint32) (*UserK8sResourceAutoscaler, error) {
+	var autoscaler UserK8sResourceAutoscaler
+	err := d.db.Where("k8s_resource_id = ? and deleted_at IS NULL", k8sResourceID).First(&autoscaler).Error
+	if err != nil {
+		return nil, err
+	}
+	return &autoscaler, nil
+}
+
+// CopyToResourceTx 资源更新出新版本时，把旧版本的扩缩容策略复制到新版本
+func (d *UserK8sResourceAutoscalerDao) CopyToResourceTx(tx *gorm.DB, fromResourceID uint32, toResourceID uint32) error {
+	var autoscalers []UserK8sResourceAutoscaler
+	if err := tx.Where("k8s_resource_id = ? and deleted_at IS NULL", fromResourceID).Find(&autoscalers).Error; err != nil {
+		return err
+	}
+	for _, autoscaler := range autoscalers {
+		copied := &UserK8sResourceAutoscaler{
+			K8sResourceID:                 toResourceID,
+			MinReplicas:                   autoscaler.MinReplicas,
+			MaxReplicas:                   autoscaler.MaxReplicas,
+			TargetCPUUtilization:          autoscaler.TargetCPUUtilization,
+			TargetMemoryUtilization:       autoscaler.TargetMemoryUtilization,
+			ScaleUpStabilizationSeconds:   autoscaler.ScaleUpStabilizationSeconds,
+			ScaleDownStabilizationSeconds: autoscaler.ScaleDownStabilizationSeconds,
+			UserID:                        autoscaler.UserID,
+		}
+		if err := tx.Create(copied).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserK8sResourceDrift 线上对象与资源当前部署版本的差异，同一资源同一时间只有一条未解决的记录
+type UserK8sResourceDrift struct {
+	Id               uint64     `gorm:"column:id;type:bigint UNSIGNED;primaryKey;not null;" json:"id"`
+	K8sResourceID    uint32     `gorm:"column:k8s_resource_id;not null;index:idx_resource_drift" json:"k8s_resource_id"`
+	TargetResourceID uint32     `gorm:"column:target_resource_id;not null" json:"target_resource_id"` // 比较的版本，回滚后为回滚的目标版本
+	ClusterID        uint32     `gorm:"column:cluster_id;not null" json:"cluster_id"`
+	Namespace        string     `gorm:"column:namespace;type:varchar(255);not null" json:"namespace"`
+	MetadataName     string     `gorm:"column:metadata_name;type:varchar(255);not null" json:"metadata_name"`
+	Objects          string     `gorm:"column:objects;type:mediumtext" json:"objects"`                   // 存在差异的对象及字段级差异的 JSON
+	Fingerprint      string     `gorm:"column:fingerprint;type:varchar(64);not null" json:"fingerprint"` // objects 的 sha256，用于判断差异是否变化
+	FirstDetected    *time.Time `gorm:"column:first_detected;type:datetime;not null" json:"first_detected"`
+	LastDetected     *time.Time `gorm:"column:last_detected;type:datetime;not null" json:"last_detected"`
+	ResolvedAt       *time.Time `gorm:"column:resolved_at;type:datetime" json:"resolved_at"`
+	Resolution       string     `gorm:"column:resolution;type:varchar(20)" json:"resolution"` // reconciled：平台重新 apply；converged：线上对象已恢复一致
+}
+
+func (UserK8sResourceDrift) TableName() string {
+	return "user_k8s_resource_drift"
+}
+
+func NewUserK8sResourceDriftDao(db *gorm.DB) *UserK8sResourceDriftDao {
+	return &UserK8sResourceDriftDao{db: db}
+}
+
+type UserK8sResourceDriftDao struct {
+	db *gorm.DB
+}
+
+// QueryOpen 查询资源未解决的差异，没有时返回 nil
+func (d *UserK8sResourceDriftDao) QueryOpen(k8sResourceID uint32) (*UserK8sResourceDrift, error) {
+	var drift UserK8sResourceDrift
+	err := d.db.Where("k8s_resource_id = ? and resolved_at IS NULL", k8sResourceID).Order("id desc").First(&drift).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &drift, nil
+}
+
+// Create 记录新发现的差异
+func (d *UserK8sResourceDriftDao) Create(drift *UserK8sResourceDrift) error {
+	return d.db.Create(drift).Error
+}
+
+// UpdateDetected 更新未解决差异的内容和最后发现时间
+func (d *UserK8sResourceDriftDao) UpdateDetected(id uint64, objects string, fingerprint string, lastDetected *time.Time) error {
+	return d.db.Model(&UserK8sResourceDrift{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"objects":       objects,
+		"fingerprint":   fingerprint,
+		"last_detected": lastDetected,
+	}).Error
+}
+
+// Resolve 将资源未解决的差异标记为已解决
+func (d *UserK8sResourceDriftDao) Resolve(k8sResourceID uint32, resolution string, resolvedAt *time.Time) error {
+	return d.db.Model(&UserK8sResourceDrift{}).Where("k8s_resource_id = ? and resolved_at IS NULL", k8sResourceID).Updates(map[string]interface{}{
+		"resolved_at": resolvedAt,
+		"resolution":  resolution,
+	}).Error
+}
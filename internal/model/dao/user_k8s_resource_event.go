@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserK8sResourceEvent 平台管理资源的 Warning 事件，同一个 k8s Event 重复出现时只更新次数和最后出现时间
+type UserK8sResourceEvent struct {
+	Id            uint64     `gorm:"column:id;type:bigint UNSIGNED;primaryKey;not null;" json:"id"`
+	K8sResourceID uint32     `gorm:"column:k8s_resource_id;not null;index:idx_resource_event,priority:1" json:"k8s_resource_id"`
+	ClusterID     uint32     `gorm:"column:cluster_id;not null" json:"cluster_id"`
+	Namespace     string     `gorm:"column:namespace;type:varchar(255);not null" json:"namespace"`
+	EventUID      string     `gorm:"column:event_uid;type:varchar(64);not null;index:idx_resource_event,priority:2" json:"event_uid"`
+	InvolvedKind  string     `gorm:"column:involved_kind;type:varchar(63);not null" json:"involved_kind"` // Deployment / ReplicaSet / Pod 等
+	InvolvedName  string     `gorm:"column:involved_name;type:varchar(255);not null" json:"involved_name"`
+	Reason        string     `gorm:"column:reason;type:varchar(128);not null" json:"reason"` // FailedScheduling、BackOff、Unhealthy 等
+	Message       string     `gorm:"column:message;type:text" json:"message"`
+	Count         int32      `gorm:"column:count;not null;default:1" json:"count"`
+	FirstSeen     *time.Time `gorm:"column:first_seen;type:datetime;not null" json:"first_seen"`
+	LastSeen      *time.Time `gorm:"column:last_seen;type:datetime;not null" json:"last_seen"`
+}
+
+func (UserK8sResourceEvent) TableName() string {
+	return "user_k8s_resource_event"
+}
+
+func NewUserK8sResourceEventDao(db *gorm.DB) *UserK8sResourceEventDao {
+	return &UserK8sResourceEventDao{db: db}
+}
+
+type UserK8sResourceEventDao struct {
+	db *gorm.DB
+}
+
+// Save 保存事件，同一资源下已存在相同 event_uid 时更新次数、消息和最后出现时间
+func (d *UserK8sResourceEventDao) Save(event *UserK8sResourceEvent) error {
+	var existing UserK8sResourceEvent
+	err := d.db.Where("k8s_resource_id = ? and event_uid = ?", event.K8sResourceID, event.EventUID).First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return d.db.Create(event).Error
+	}
+	if err != nil {
+		return err
+	}
+	return d.db.Model(&UserK8sResourceEvent{}).Where("id = ?", existing.Id).Updates(map[string]interface{}{
+		"reason":    event.Reason,
+		"message":   event.Message,
+		"count":     event.Count,
+		"last_seen": event.LastSeen,
+	}).Error
+}
+
+// QueryByResourceID 查询资源最近的 Warning 事件，按最后出现时间倒序
+func (d *UserK8sResourceEventDao) QueryByResourceID(k8sResourceID uint32, limit int) ([]UserK8sResourceEvent, error) {
+	var events []UserK8sResourceEvent
+	err := d.db.Where("k8s_resource_id = ?", k8sResourceID).Order("last_seen desc").Limit(limit).Find(&events).Error
+	return events, err
+}
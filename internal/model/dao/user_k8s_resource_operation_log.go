@@ -8,18 +8,26 @@ import (
 
 // UserK8sResourceOperationLog 用户 K8s 资源操作日志
 type UserK8sResourceOperationLog struct {
-	ID             uint           `gorm:"primaryKey;column:id" json:"id"`
-	K8sResourceID  uint           `gorm:"not null;column:k8s_resource_id" json:"k8s_resource_id"`
-	UserID         uint           `gorm:"not null;column:user_id" json:"user_id"`
-	Namespace      string         `gorm:"size:255;not null;column:namespace" json:"namespace"`
-	MetadataName   string         `gorm:"size:255;not null;column:metadata_name" json:"metadata_name"`
-	MetadataLabels string         `gorm:"type:text;column:metadata_labels" json:"metadata_labels"`
-	OperationType  string         `gorm:"size:50;not null;column:operation_type" json:"operation_type"`
-	Status         int            `gorm:"not null;column:status" json:"status"`
-	Command        string         `gorm:"size:500;not null;column:command" json:"command"`
-	CreatedAt      *time.Time     `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt      *time.Time     `gorm:"column:updated_at" json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index;column:deleted_at" json:"deleted_at"`
+	ID               uint           `gorm:"primaryKey;column:id" json:"id"`
+	K8sResourceID    uint           `gorm:"not null;column:k8s_resource_id" json:"k8s_resource_id"`
+	TargetResourceID uint           `gorm:"column:target_resource_id" json:"target_resource_id"` // 回滚时重新 apply 的历史版本 ID
+	ReleaseRevision  int            `gorm:"column:release_revision" json:"release_revision"`     // helm 资源操作后的 release revision
+	Overlay          string         `gorm:"size:63;column:overlay" json:"overlay"`               // kustomize 资源 apply 时使用的 overlay，空为 base
+	Environment      string         `gorm:"size:63;column:environment" json:"environment"`       // 模板 apply 时使用的变量集
+	DockerImageID    uint32         `gorm:"column:docker_image_id" json:"docker_image_id"`       // 模板 apply 时替换 {{ .Image }} 的镜像
+	UserID           uint           `gorm:"not null;column:user_id" json:"user_id"`
+	Namespace        string         `gorm:"size:255;not null;column:namespace" json:"namespace"`
+	MetadataName     string         `gorm:"size:255;not null;column:metadata_name" json:"metadata_name"`
+	MetadataLabels   string         `gorm:"type:text;column:metadata_labels" json:"metadata_labels"`
+	OperationType    string         `gorm:"size:50;not null;column:operation_type" json:"operation_type"`
+	Status           int            `gorm:"not null;column:status" json:"status"`
+	ReplicasBefore   *int32         `gorm:"column:replicas_before" json:"replicas_before"`       // scale / rollout 操作前的副本数
+	ReplicasAfter    *int32         `gorm:"column:replicas_after" json:"replicas_after"`         // scale / rollout 操作后的副本数
+	RolloutStatus    string         `gorm:"size:20;column:rollout_status" json:"rollout_status"` // deployment rollout 的状态：跟踪中为 progressing，结束后为 succeeded / failed / timeout
+	Command          string         `gorm:"size:500;not null;column:command" json:"command"`
+	CreatedAt        *time.Time     `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt        *time.Time     `gorm:"column:updated_at" json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index;column:deleted_at" json:"deleted_at"`
 }
 
 func (UserK8sResourceOperationLog) TableName() string {
@@ -41,6 +49,14 @@ func (d *UserK8sResourceOperationLogDao) Create(log *UserK8sResourceOperationLog
 	return d.db.Create(log).Error
 }
 
+// UpdateRolloutStatus rollout 跟踪结束后更新操作日志的资源状态和 rollout 状态
+func (d *UserK8sResourceOperationLogDao) UpdateRolloutStatus(id uint, status int, rolloutStatus string) error {
+	return d.db.Model(&UserK8sResourceOperationLog{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":         status,
+		"rollout_status": rolloutStatus,
+	}).Error
+}
+
 // QueryByK8sResourceIDPage 根据 K8s 资源 ID 查询操作日志 分页查询
 func (d *UserK8sResourceOperationLogDao) QueryByK8sResourceIDPage(k8sResourceID uint, page, pageSize int) ([]*UserK8sResourceOperationLog, int64, error) {
 	var logs []*UserK8sResourceOperationLog
@@ -81,8 +97,23 @@ func (d *UserK8sResourceOperationLogDao) QueryByUserID(userID uint) ([]*UserK8sR
 	return logs, err
 }
 
-func (d *UserK8sResourceOperationLogDao) QueryByNamespaceAndMetadataName(namespace string, metaName string) ([]*UserK8sResourceOperationLog, error) {
+// QueryLatestByOperationTypes 查询资源最近一条指定类型的操作日志，没有时返回 nil
+func (d *UserK8sResourceOperationLogDao) QueryLatestByOperationTypes(k8sResourceID uint, operationTypes []string) (*UserK8sResourceOperationLog, error) {
 	var logs []*UserK8sResourceOperationLog
-	err := d.db.Where("namespace = ? and metadata_name = ?", namespace, metaName).Order("id desc").Limit(1).Find(&logs).Error
-	return logs, err
+	err := d.db.Where("k8s_resource_id = ? and operation_type in ?", k8sResourceID, operationTypes).Order("id desc").Limit(1).Find(&logs).Error
+	if err != nil || len(logs) == 0 {
+		return nil, err
+	}
+	return logs[0], nil
+}
+
+// QueryLatestVersionApply 查询某个版本最近一次被 apply 的操作日志：版本作为当前版本部署，或被回滚、reconcile 重新 apply，没有时返回 nil
+func (d *UserK8sResourceOperationLogDao) QueryLatestVersionApply(versionID uint, operationTypes []string) (*UserK8sResourceOperationLog, error) {
+	var logs []*UserK8sResourceOperationLog
+	err := d.db.Where("operation_type in ? and ((k8s_resource_id = ? and target_resource_id in (0, ?)) or target_resource_id = ?)", operationTypes, versionID, versionID, versionID).
+		Order("id desc").Limit(1).Find(&logs).Error
+	if err != nil || len(logs) == 0 {
+		return nil, err
+	}
+	return logs[0], nil
 }
@@ -0,0 +1,52 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserK8sResourceUsage 平台管理资源的用量采样，只保留最近 24 小时
+type UserK8sResourceUsage struct {
+	Id            uint64     `gorm:"column:id;type:bigint UNSIGNED;primaryKey;not null;" json:"id"`
+	K8sResourceID uint32     `gorm:"column:k8s_resource_id;not null;index:idx_resource_sampled,priority:1" json:"k8s_resource_id"`
+	ClusterID     uint32     `gorm:"column:cluster_id;not null" json:"cluster_id"`
+	Namespace     string     `gorm:"column:namespace;type:varchar(255);not null" json:"namespace"`
+	MetadataName  string     `gorm:"column:metadata_name;type:varchar(255);not null" json:"metadata_name"`
+	PodCount      int        `gorm:"column:pod_count;not null" json:"pod_count"`
+	CPUMilli      int64      `gorm:"column:cpu_milli;not null" json:"cpu_milli"`       // 全部 Pod 的 CPU 用量之和，单位 millicore
+	MemoryBytes   int64      `gorm:"column:memory_bytes;not null" json:"memory_bytes"` // 全部 Pod 的内存用量之和
+	SampledAt     *time.Time `gorm:"column:sampled_at;type:datetime;not null;index:idx_resource_sampled,priority:2" json:"sampled_at"`
+}
+
+func (UserK8sResourceUsage) TableName() string {
+	return "user_k8s_resource_usage"
+}
+
+func NewUserK8sResourceUsageDao(db *gorm.DB) *UserK8sResourceUsageDao {
+	return &UserK8sResourceUsageDao{db: db}
+}
+
+type UserK8sResourceUsageDao struct {
+	db *gorm.DB
+}
+
+// CreateBatch 批量写入一轮采样结果
+func (d *UserK8sResourceUsageDao) CreateBatch(usages []*UserK8sResourceUsage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+	return d.db.Create(usages).Error
+}
+
+// QueryByResourcesSince 查询一组资源版本从 since 开始的采样，按时间正序
+func (d *UserK8sResourceUsageDao) QueryByResourcesSince(k8sResourceIDs []uint32, since time.Time) ([]UserK8sResourceUsage, error) {
+	var usages []UserK8sResourceUsage
+	err := d.db.Where("k8s_resource_id in ? and sampled_at >= ?", k8sResourceIDs, since).Order("sampled_at").Find(&usages).Error
+	return usages, err
+}
+
+// DeleteBefore 删除过期的采样
+func (d *UserK8sResourceUsageDao) DeleteBefore(before time.Time) error {
+	return d.db.Where("sampled_at < ?", before).Delete(&UserK8sResourceUsage{}).Error
+}
@@ -0,0 +1,98 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserK8sResourceVariable K8s 资源的模板变量集，每个环境一条记录
+type UserK8sResourceVariable struct {
+	Id            uint32         `gorm:"column:id;type:int UNSIGNED;primaryKey;not null;" json:"id"`
+	K8sResourceID uint32         `gorm:"column:k8s_resource_id;not null" json:"k8s_resource_id"`
+	Environment   string         `gorm:"column:environment;type:varchar(63);not null" json:"environment"`
+	Variables     string         `gorm:"column:variables;type:text" json:"variables"` // 变量的 JSON
+	UserID        uint32         `gorm:"column:user_id;not null" json:"user_id"`
+	CreatedAt     *time.Time     `gorm:"column:created_at;type:datetime;not null;" json:"created_at"`
+	UpdatedAt     *time.Time     `gorm:"column:updated_at;type:datetime;not null;" json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"column:deleted_at;type:datetime;default:NULL;" json:"deleted_at"`
+}
+
+func (UserK8sResourceVariable) TableName() string {
+	return "user_k8s_resource_variable"
+}
+
+func NewUserK8sResourceVariableDao(db *gorm.DB) *UserK8sResourceVariableDao {
+	return &UserK8sResourceVariableDao{db: db}
+}
+
+type UserK8sResourceVariableDao struct {
+	db *gorm.DB
+}
+
+// Save 保存某个环境的变量集，已存在时覆盖
+func (d *UserK8sResourceVariableDao) Save(variable *UserK8sResourceVariable) error {
+	existing, err := d.QueryByResourceAndEnvironment(variable.K8sResourceID, variable.Environment)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if existing != nil {
+		return d.db.Model(&UserK8sResourceVariable{}).Where("id = ?", existing.Id).Updates(map[string]interface{}{
+			"variables": variable.Variables,
+			"user_id":   variable.UserID,
+		}).Error
+	}
+	return d.db.Create(variable).Error
+}
+
+// Delete 删除变量集（软删除）
+func (d *UserK8sResourceVariableDao) Delete(id uint32) error {
+	return d.db.Delete(&UserK8sResourceVariable{}, id).Error
+}
+
+// QueryById 根据 ID 查询
+func (d *UserK8sResourceVariableDao) QueryById(id uint32) (*UserK8sResourceVariable, error) {
+	var variable UserK8sResourceVariable
+	err := d.db.Where("id = ? and deleted_at IS NULL", id).First(&variable).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variable, nil
+}
+
+// QueryByResourceID 查询资源的全部变量集
+func (d *UserK8sResourceVariableDao) QueryByResourceID(k8sResourceID uint32) ([]UserK8sResourceVariable, error) {
+	var variables []UserK8sResourceVariable
+	err := d.db.Where("k8s_resource_id = ? and deleted_at IS NULL", k8sResourceID).Order("environment").Find(&variables).Error
+	return variables, err
+}
+
+// QueryByResourceAndEnvironment 查询资源某个环境的变量集
+func (d *UserK8sResourceVariableDao) QueryByResourceAndEnvironment(k8sResourceID uint32, environment string) (*UserK8sResourceVariable, error) {
+	var variable UserK8sResourceVariable
+	err := d.db.Where("k8s_resource_id = ? and environment = ? and deleted_at IS NULL", k8sResourceID, environment).First(&variable).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variable, nil
+}
+
+// CopyToResourceTx 资源更新出新版本时，把旧版本的变量集复制到新版本
+func (d *UserK8sResourceVariableDao) CopyToResourceTx(tx *gorm.DB, fromResourceID uint32, toResourceID uint32) error {
+	var variables []UserK8sResourceVariable
+	if err := tx.Where("k8s_resource_id = ? and deleted_at IS NULL", fromResourceID).Find(&variables).Error; err != nil {
+		return err
+	}
+	for _, variable := range variables {
+		copied := &UserK8sResourceVariable{
+			K8sResourceID: toResourceID,
+			Environment:   variable.Environment,
+			Variables:     variable.Variables,
+			UserID:        variable.UserID,
+		}
+		if err := tx.Create(copied).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,501 @@
+package scheduled_tasks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/config"
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	networkinglisters "k8s.io/client-go/listers/networking/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	informerResync  = 10 * time.Minute       // informer 全量 resync 周期，兜底修正遗漏的事件
+	informerTimeout = 30 * time.Second       // 启动时等待缓存同步的最长时间
+	checkDebounce   = 500 * time.Millisecond // 合并短时间内的多个事件
+)
+
+// resourceInformer 通过 shared informer 监听单个集群中工作负载、Service、Ingress、Pod 的变化，以及 Warning 事件
+type resourceInformer struct {
+	clients           *conf.K8sClients
+	filtered          bool // 只缓存带有资源 ID 标签的对象
+	factory           informers.SharedInformerFactory
+	warningFactory    informers.SharedInformerFactory // 只 list / watch type=Warning 的 Event
+	deploymentLister  appslisters.DeploymentLister
+	statefulSetLister appslisters.StatefulSetLister
+	daemonSetLister   appslisters.DaemonSetLister
+	jobLister         batchlisters.JobLister
+	cronJobLister     batchlisters.CronJobLister
+	serviceLister     corelisters.ServiceLister
+	ingressLister     networkinglisters.IngressLister
+	podLister         corelisters.PodLister
+	synced            []cache.InformerSynced
+	onEvent           func(clusterID uint32, resourceID uint32, namespace string, name string)
+	onResync          func()
+	onWarning         func(clusterID uint32, event *v1.Event)
+	stopCh            chan struct{} // 关闭后停止 informer 的 list / watch
+}
+
+func newResourceInformer(clients *conf.K8sClients, onEvent func(clusterID uint32, resourceID uint32, namespace string, name string), onResync func(), onWarning func(clusterID uint32, event *v1.Event)) *resourceInformer {
+	// 未开启 legacy_name_attribution 时只 list / watch 带有资源 ID 标签的对象，不缓存集群中与平台无关的对象
+	var options []informers.SharedInformerOption
+	filtered := !config.GlobalConfig.K8s.LegacyNameAttribution
+	if filtered {
+		options = append(options, informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = define.K8sLabelResourceID
+		}))
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(clients.Client, informerResync, options...)
+	warningFactory := informers.NewSharedInformerFactoryWithOptions(clients.Client, informerResync,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = "type=" + v1.EventTypeWarning
+		}))
+	deployments := factory.Apps().V1().Deployments()
+	statefulSets := factory.Apps().V1().StatefulSets()
+	daemonSets := factory.Apps().V1().DaemonSets()
+	jobs := factory.Batch().V1().Jobs()
+	cronJobs := factory.Batch().V1().CronJobs()
+	services := factory.Core().V1().Services()
+	ingresses := factory.Networking().V1().Ingresses()
+	pods := factory.Core().V1().Pods()
+
+	r := &resourceInformer{
+		clients:           clients,
+		filtered:          filtered,
+		factory:           factory,
+		warningFactory:    warningFactory,
+		deploymentLister:  deployments.Lister(),
+		statefulSetLister: statefulSets.Lister(),
+		daemonSetLister:   daemonSets.Lister(),
+		jobLister:         jobs.Lister(),
+		cronJobLister:     cronJobs.Lister(),
+		serviceLister:     services.Lister(),
+		ingressLister:     ingresses.Lister(),
+		podLister:         pods.Lister(),
+		synced: []cache.InformerSynced{
+			deployments.Informer().HasSynced,
+			statefulSets.Informer().HasSynced,
+			daemonSets.Informer().HasSynced,
+			jobs.Informer().HasSynced,
+			cronJobs.Informer().HasSynced,
+			services.Informer().HasSynced,
+			ingresses.Informer().HasSynced,
+			pods.Informer().HasSynced,
+		},
+		onEvent:   onEvent,
+		onResync:  onResync,
+		onWarning: onWarning,
+		stopCh:    make(chan struct{}),
+	}
+
+	deployments.Informer().AddEventHandler(r.handler(objectKey))
+	statefulSets.Informer().AddEventHandler(r.handler(objectKey))
+	daemonSets.Informer().AddEventHandler(r.handler(objectKey))
+	cronJobs.Informer().AddEventHandler(r.handler(objectKey))
+	// CronJob 创建的 Job 的变化归到所属的 CronJob 上
+	jobs.Informer().AddEventHandler(r.handler(func(obj interface{}) (string, string) {
+		job, ok := obj.(*batchv1.Job)
+		if !ok {
+			return objectKey(obj)
+		}
+		return job.Namespace, jobControllerName(job)
+	}))
+	services.Informer().AddEventHandler(r.handler(objectKey))
+	ingresses.Informer().AddEventHandler(r.handler(objectKey))
+	// Pod 的变化归到其所属的 Deployment 上
+	pods.Informer().AddEventHandler(r.handler(func(obj interface{}) (string, string) {
+		pod, ok := obj.(*v1.Pod)
+		if !ok {
+			return objectKey(obj)
+		}
+		return pod.Namespace, podControllerName(pod)
+	}))
+	// Warning 事件不参与缓存同步判断，事件缓存未就绪不影响状态检查
+	warningFactory.Core().V1().Events().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    r.notifyWarning,
+		UpdateFunc: func(_, newObj interface{}) { r.notifyWarning(newObj) },
+	})
+	return r
+}
+
+func (r *resourceInformer) notifyWarning(obj interface{}) {
+	if event, ok := obj.(*v1.Event); ok {
+		r.onWarning(r.clients.ClusterID, event)
+	}
+}
+
+// handler 构造事件处理器，add / update / delete 触发对象所属资源的检查；resync 时对象没有变化，触发一次全量检查。
+// 对象（包括 Pod 和 CronJob 创建的 Job）带有平台写入的资源 ID 标签，keyFunc 给出的名称只用于兼容没有标签的对象
+func (r *resourceInformer) handler(keyFunc func(obj interface{}) (string, string)) cache.ResourceEventHandlerFuncs {
+	notify := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		var resourceID uint32
+		if accessor, ok := obj.(metav1.Object); ok {
+			resourceID, _ = k8s_manage.ResourceIDFromLabels(accessor.GetLabels())
+		}
+		namespace, name := keyFunc(obj)
+		if resourceID != 0 || name != "" {
+			r.onEvent(r.clients.ClusterID, resourceID, namespace, name)
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: notify,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldAccessor, oldOk := oldObj.(metav1.Object)
+			newAccessor, newOk := newObj.(metav1.Object)
+			if oldOk && newOk && oldAccessor.GetResourceVersion() == newAccessor.GetResourceVersion() {
+				r.onResync()
+				return
+			}
+			notify(newObj)
+		},
+		DeleteFunc: notify,
+	}
+}
+
+// start 启动 informer，不阻塞调用方；缓存同步完成前状态检查直接访问 API Server
+func (r *resourceInformer) start(onSynced func()) {
+	r.factory.Start(r.stopCh)
+	r.warningFactory.Start(r.stopCh)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), informerTimeout)
+		defer cancel()
+		go func() {
+			select {
+			case <-r.stopCh:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		if !cache.WaitForCacheSync(ctx.Done(), r.synced...) {
+			select {
+			case <-r.stopCh:
+			default:
+				logrus.Warnf("集群 %d 的 K8s informer 缓存同步失败，状态检查将直接访问 API Server", r.clients.ClusterID)
+			}
+			return
+		}
+		onSynced()
+	}()
+}
+
+// stop 停止 informer 并等待其 goroutine 退出
+func (r *resourceInformer) stop() {
+	close(r.stopCh)
+	r.factory.Shutdown()
+	r.warningFactory.Shutdown()
+}
+
+// cachedGet 缓存可用时从 lister 读取，否则直接访问 API Server；
+// informer 只缓存带有资源 ID 标签的对象时，缓存中不存在的对象（如 Ingress 引用的未打标签的 Service）也回退到 API Server
+func cachedGet[T any](r *resourceInformer, fromCache func() (T, error), fromServer func() (T, error)) (T, error) {
+	if r.hasSynced() {
+		obj, err := fromCache()
+		if !r.filtered || !k8serrors.IsNotFound(err) {
+			return obj, err
+		}
+	}
+	return fromServer()
+}
+
+// hasSynced informer 缓存是否可用
+func (r *resourceInformer) hasSynced() bool {
+	if r == nil {
+		return false
+	}
+	for _, synced := range r.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// getDeployment 优先从 informer 缓存读取 Deployment
+func (r *resourceInformer) getDeployment(namespace string, name string) (*appsv1.Deployment, error) {
+	return cachedGet(r, func() (*appsv1.Deployment, error) {
+		return r.deploymentLister.Deployments(namespace).Get(name)
+	}, func() (*appsv1.Deployment, error) {
+		return r.clients.Client.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	})
+}
+
+// getStatefulSet 优先从 informer 缓存读取 StatefulSet
+func (r *resourceInformer) getStatefulSet(namespace string, name string) (*appsv1.StatefulSet, error) {
+	return cachedGet(r, func() (*appsv1.StatefulSet, error) {
+		return r.statefulSetLister.StatefulSets(namespace).Get(name)
+	}, func() (*appsv1.StatefulSet, error) {
+		return r.clients.Client.AppsV1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	})
+}
+
+// getDaemonSet 优先从 informer 缓存读取 DaemonSet
+func (r *resourceInformer) getDaemonSet(namespace string, name string) (*appsv1.DaemonSet, error) {
+	return cachedGet(r, func() (*appsv1.DaemonSet, error) {
+		return r.daemonSetLister.DaemonSets(namespace).Get(name)
+	}, func() (*appsv1.DaemonSet, error) {
+		return r.clients.Client.AppsV1().DaemonSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	})
+}
+
+// getJob 优先从 informer 缓存读取 Job
+func (r *resourceInformer) getJob(namespace string, name string) (*batchv1.Job, error) {
+	return cachedGet(r, func() (*batchv1.Job, error) {
+		return r.jobLister.Jobs(namespace).Get(name)
+	}, func() (*batchv1.Job, error) {
+		return r.clients.Client.BatchV1().Jobs(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	})
+}
+
+// getCronJob 优先从 informer 缓存读取 CronJob
+func (r *resourceInformer) getCronJob(namespace string, name string) (*batchv1.CronJob, error) {
+	return cachedGet(r, func() (*batchv1.CronJob, error) {
+		return r.cronJobLister.CronJobs(namespace).Get(name)
+	}, func() (*batchv1.CronJob, error) {
+		return r.clients.Client.BatchV1().CronJobs(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	})
+}
+
+// getService 优先从 informer 缓存读取 Service
+func (r *resourceInformer) getService(namespace string, name string) (*v1.Service, error) {
+	return cachedGet(r, func() (*v1.Service, error) {
+		return r.serviceLister.Services(namespace).Get(name)
+	}, func() (*v1.Service, error) {
+		return r.clients.Client.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	})
+}
+
+// getIngress 优先从 informer 缓存读取 Ingress
+func (r *resourceInformer) getIngress(namespace string, name string) (*networkingv1.Ingress, error) {
+	return cachedGet(r, func() (*networkingv1.Ingress, error) {
+		return r.ingressLister.Ingresses(namespace).Get(name)
+	}, func() (*networkingv1.Ingress, error) {
+		return r.clients.Client.NetworkingV1().Ingresses(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	})
+}
+
+// missingIngressBackends Ingress 引用的后端 Service 中不存在的部分
+func (r *resourceInformer) missingIngressBackends(ingress *networkingv1.Ingress) []string {
+	var backends []*networkingv1.IngressBackend
+	if ingress.Spec.DefaultBackend != nil {
+		backends = append(backends, ingress.Spec.DefaultBackend)
+	}
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			backends = append(backends, &rule.HTTP.Paths[i].Backend)
+		}
+	}
+
+	var missing []string
+	for _, backend := range backends {
+		if backend.Service == nil {
+			continue
+		}
+		if _, err := r.getService(ingress.Namespace, backend.Service.Name); err != nil {
+			missing = append(missing, backend.Service.Name)
+		}
+	}
+	return missing
+}
+
+// hasCrashingPods Deployment 下是否有处于 CrashLoopBackOff 的容器
+func (r *resourceInformer) hasCrashingPods(deployment *appsv1.Deployment) bool {
+	if !r.hasSynced() || deployment.Spec.Selector == nil {
+		return false
+	}
+	pods, err := r.podLister.Pods(deployment.Namespace).List(labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels))
+	if err != nil {
+		return false
+	}
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// objectKey 获取对象的 namespace 和名称
+func objectKey(obj interface{}) (string, string) {
+	accessor, ok := obj.(metav1.Object)
+	if !ok {
+		return "", ""
+	}
+	return accessor.GetNamespace(), accessor.GetName()
+}
+
+// podControllerName 根据 OwnerReferences 推断 Pod 所属控制器名称，ReplicaSet 去掉 pod-template-hash 后即为 Deployment 名称
+func podControllerName(pod *v1.Pod) string {
+	if len(pod.OwnerReferences) == 0 {
+		return pod.Name
+	}
+	owner := pod.OwnerReferences[0]
+	if owner.Kind == "ReplicaSet" {
+		if hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok {
+			return strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+	return owner.Name
+}
+
+// jobControllerName CronJob 创建的 Job 返回 CronJob 名称，其他 Job 返回自身名称
+func jobControllerName(job *batchv1.Job) string {
+	if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == "CronJob" {
+		return owner.Name
+	}
+	return job.Name
+}
+
+// watchKey 资源在关注列表中的 key，只用于按名称兼容没有资源 ID 标签的对象
+func watchKey(clusterID uint32, namespace string, name string) string {
+	return fmt.Sprintf("%d/%s/%s", clusterID, namespace, name)
+}
+
+// namespaceKey 资源部署所在的集群和 namespace
+func namespaceKey(clusterID uint32, namespace string) string {
+	return fmt.Sprintf("%d/%s", clusterID, namespace)
+}
+
+// clusterInformer 获取集群的 informer，首次使用时创建并启动
+func (c *K8sResourceStatusChecker) clusterInformer(clusterID uint32) (*resourceInformer, error) {
+	c.informersMu.Lock()
+	defer c.informersMu.Unlock()
+
+	if informer, ok := c.informers[clusterID]; ok {
+		return informer, nil
+	}
+
+	clients, err := conf.GetK8sClients(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	informer := newResourceInformer(clients, c.onResourceEvent, c.triggerCheck, c.onWarningEvent)
+	informer.start(c.triggerCheck)
+	c.informers[clusterID] = informer
+	return informer, nil
+}
+
+// evictInformer 集群被删除或凭证变更后停止并移除其 informer，下次检查时使用新的客户端重新创建
+func (c *K8sResourceStatusChecker) evictInformer(clusterID uint32) {
+	c.informersMu.Lock()
+	informer, ok := c.informers[clusterID]
+	delete(c.informers, clusterID)
+	c.informersMu.Unlock()
+
+	if ok {
+		informer.stop()
+		logrus.Infof("集群 %d 的 K8s informer 已停止", clusterID)
+	}
+}
+
+// setWatched 更新平台管理中的资源列表
+func (c *K8sResourceStatusChecker) setWatched(watched map[string][]uint32, resourceIDs map[string]uint32, namespaces map[uint32]string) {
+	c.watchedMu.Lock()
+	defer c.watchedMu.Unlock()
+	c.watched = watched
+	c.resourceIDs = resourceIDs
+	c.namespaces = namespaces
+}
+
+// deployedIn 资源当前是否部署在该集群的 namespace 中，用于校验对象上的资源 ID 标签，避免其他团队伪造标签
+func (c *K8sResourceStatusChecker) deployedIn(resourceID uint32, clusterID uint32, namespace string) bool {
+	c.watchedMu.RLock()
+	defer c.watchedMu.RUnlock()
+	return c.namespaces[resourceID] == namespaceKey(clusterID, namespace)
+}
+
+// onResourceEvent informer 事件回调，只重新检查发生变化的平台管理资源。
+// 对象带有资源 ID 标签时直接检查该资源，检查本身按操作日志中的对象进行，标签不会影响其他资源的状态；
+// 没有标签的对象只在开启 legacy_name_attribution 时按 namespace 和名称匹配
+func (c *K8sResourceStatusChecker) onResourceEvent(clusterID uint32, resourceID uint32, namespace string, name string) {
+	if resourceID != 0 {
+		c.triggerResourceCheck([]uint32{resourceID})
+		return
+	}
+	if !config.GlobalConfig.K8s.LegacyNameAttribution {
+		return
+	}
+
+	c.watchedMu.RLock()
+	resourceIDs := c.watched[watchKey(clusterID, namespace, name)]
+	c.watchedMu.RUnlock()
+
+	if len(resourceIDs) > 0 {
+		c.triggerResourceCheck(resourceIDs)
+	}
+}
+
+// triggerCheck 请求一次全量检查，启动、缓存同步完成和 informer resync 时调用
+func (c *K8sResourceStatusChecker) triggerCheck() {
+	c.pendingMu.Lock()
+	c.pendingAll = true
+	c.pendingMu.Unlock()
+	c.signalCheck()
+}
+
+// triggerResourceCheck 请求重新检查指定的资源
+func (c *K8sResourceStatusChecker) triggerResourceCheck(resourceIDs []uint32) {
+	c.pendingMu.Lock()
+	for _, resourceID := range resourceIDs {
+		c.pending[resourceID] = true
+	}
+	c.pendingMu.Unlock()
+	c.signalCheck()
+}
+
+// signalCheck 唤醒检查循环，已有待执行的检查时直接合并
+func (c *K8sResourceStatusChecker) signalCheck() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// runTriggerLoop 处理检查请求，短时间内的多次请求只执行一次检查；有全量检查请求时不再单独检查资源
+func (c *K8sResourceStatusChecker) runTriggerLoop() {
+	for range c.trigger {
+		time.Sleep(checkDebounce)
+
+		c.pendingMu.Lock()
+		all, pending := c.pendingAll, c.pending
+		c.pendingAll, c.pending = false, make(map[uint32]bool)
+		c.pendingMu.Unlock()
+
+		if all {
+			c.checkResources()
+			continue
+		}
+		resourceIDs := make([]uint32, 0, len(pending))
+		for resourceID := range pending {
+			resourceIDs = append(resourceIDs, resourceID)
+		}
+		if len(resourceIDs) > 0 {
+			c.checkResourcesByID(resourceIDs)
+		}
+	}
+}
@@ -3,14 +3,18 @@ package scheduled_tasks
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/ZZGADA/easy-deploy/internal/define"
 	"github.com/ZZGADA/easy-deploy/internal/model/conf"
 	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
 	"github.com/sirupsen/logrus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"gorm.io/gorm"
 )
 
 // K8sResourceStatusChecker K8s 资源状态检查器
@@ -18,6 +22,29 @@ type K8sResourceStatusChecker struct {
 	userK8sResourceDao             *dao.UserK8sResourceDao
 	userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao
 	userDao                        *dao.UsersDao
+	userK8sResourceEventDao        *dao.UserK8sResourceEventDao
+
+	informersMu sync.Mutex
+	informers   map[uint32]*resourceInformer // key: clusterID
+	trigger     chan struct{}
+	pendingMu   sync.Mutex
+	pendingAll  bool            // 需要全量检查
+	pending     map[uint32]bool // 需要重新检查的资源
+	mu          sync.Mutex
+	running     map[uint32]K8sResourceInfo // 运行中的资源，单个资源检查后据此重新组装用户的运行中列表
+	watchedMu   sync.RWMutex
+	namespaces  map[uint32]string   // key: k8s_resource_id，value: clusterID/namespace，平台管理中的资源部署的位置
+	watched     map[string][]uint32 // key: clusterID/namespace/name，legacy_name_attribution 时按名称匹配没有标签的对象
+	resourceIDs map[string]uint32   // key: clusterID/resourceType/namespace/name，legacy_name_attribution 时按名称归属 Warning 事件
+}
+
+// resourceCheck 单个资源一次检查的结果，running 和 change 为空表示未运行、状态没有变化
+type resourceCheck struct {
+	namespaceKey string
+	watchKey     string
+	resourceKey  string
+	running      *K8sResourceInfo
+	change       *K8sResourceStatusChange
 }
 
 // K8sResourceInfo K8s资源信息结构
@@ -27,38 +54,63 @@ type K8sResourceInfo struct {
 	ResourceType string `json:"resource_type"`
 	Namespace    string `json:"namespace"`
 	UserID       uint   `json:"user_id"`
+	ClusterID    uint32 `json:"cluster_id"`
+}
+
+// K8sResourceStatusChange K8s资源状态变化
+type K8sResourceStatusChange struct {
+	ResourceID   uint   `json:"resource_id"`
+	ResourceName string `json:"resource_name"`
+	ResourceType string `json:"resource_type"`
+	Namespace    string `json:"namespace"`
+	OldStatus    int    `json:"old_status"`
+	Status       int    `json:"status"`
 }
 
 // NewK8sResourceStatusChecker 创建 K8s 资源状态检查器
-func NewK8sResourceStatusChecker(userK8sResourceDao *dao.UserK8sResourceDao, userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao, userDao *dao.UsersDao) *K8sResourceStatusChecker {
+func NewK8sResourceStatusChecker(userK8sResourceDao *dao.UserK8sResourceDao, userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao, userDao *dao.UsersDao, userK8sResourceEventDao *dao.UserK8sResourceEventDao) *K8sResourceStatusChecker {
 	return &K8sResourceStatusChecker{
 		userK8sResourceDao:             userK8sResourceDao,
 		userK8sResourceOperationLogDao: userK8sResourceOperationLogDao,
 		userDao:                        userDao,
+		userK8sResourceEventDao:        userK8sResourceEventDao,
+		informers:                      make(map[uint32]*resourceInformer),
+		trigger:                        make(chan struct{}, 1),
+		pending:                        make(map[uint32]bool),
+		running:                        make(map[uint32]K8sResourceInfo),
+		namespaces:                     make(map[uint32]string),
+		watched:                        make(map[string][]uint32),
+		resourceIDs:                    make(map[string]uint32),
 	}
 }
 
 var k8sResourceStatusChecker *K8sResourceStatusChecker
 
 func Init() {
-	k8sResourceStatusChecker = NewK8sResourceStatusChecker(dao.NewUserK8sResourceDao(conf.DB), dao.NewUserK8sResourceOperationLogDao(conf.DB), dao.NewUsersDao(conf.DB))
+	k8sResourceStatusChecker = NewK8sResourceStatusChecker(dao.NewUserK8sResourceDao(conf.DB), dao.NewUserK8sResourceOperationLogDao(conf.DB), dao.NewUsersDao(conf.DB), dao.NewUserK8sResourceEventDao(conf.DB))
 	k8sResourceStatusChecker.start()
+
+	NewK8sResourceUsageSampler(dao.NewUserK8sResourceDao(conf.DB), dao.NewUserK8sResourceOperationLogDao(conf.DB), dao.NewUserK8sResourceUsageDao(conf.DB), k8sResourceStatusChecker).start()
 }
 
-// Start 启动定时任务
+// Start 启动默认集群的 informer，资源变化时触发状态检查；已注册集群的 informer 在首次检查时创建
 func (c *K8sResourceStatusChecker) start() {
-	// 每分钟执行一次
-	ticker := time.NewTicker(1 * time.Minute)
-	go func() {
-		for range ticker.C {
-			c.checkResources()
-		}
-	}()
+	conf.K8sClientsEvicted = c.evictInformer
+	if _, err := c.clusterInformer(conf.DefaultClusterID); err != nil {
+		logrus.Errorf("启动默认集群 informer 失败: %v", err)
+	}
+
+	go c.runTriggerLoop()
+	c.triggerCheck()
 	logrus.Info("K8s 资源状态检查器已启动")
 }
 
-// checkResources 检查所有用户自定义资源的状态
+// checkResources 检查所有用户自定义资源的状态，只有状态变化时才记录操作日志并推送变化
 func (c *K8sResourceStatusChecker) checkResources() {
+	// informer 事件和 apply 之后的主动检查可能同时触发，串行执行
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// 获取所有 K8s 资源
 	resources, err := c.userK8sResourceDao.QueryAll()
 	if err != nil {
@@ -66,150 +118,372 @@ func (c *K8sResourceStatusChecker) checkResources() {
 		return
 	}
 
-	// 用于存储运行中的资源信息，按用户ID分组
-	userResourcesMap := make(map[uint][]K8sResourceInfo)
+	// 拥有资源的用户，没有运行中资源时也要写入空列表，覆盖 Redis 中已经过时的列表
+	owners := make(map[uint]bool)
+	// 本次状态发生变化的资源，按用户ID分组
+	userChangesMap := make(map[uint][]K8sResourceStatusChange)
+	// 需要关注 informer 事件的资源
+	namespaces := make(map[uint32]string)
+	watched := make(map[string][]uint32)
+	resourceIDs := make(map[string]uint32)
+	running := make(map[uint32]K8sResourceInfo)
 
 	for _, resource := range resources {
-		// 查询最新的操作日志，获取资源信息
-		logs, err := c.userK8sResourceOperationLogDao.QueryByK8sResourceIDFirst(uint(resource.Id))
-		if err != nil {
-			logrus.Infof("查询资源 %d erros : %v", resource.Id, err)
+		owners[uint(resource.UserID)] = true
+		check := c.checkResource(resource)
+		if check == nil {
 			continue
 		}
+		namespaces[resource.Id] = check.namespaceKey
+		watched[check.watchKey] = append(watched[check.watchKey], resource.Id)
+		resourceIDs[check.resourceKey] = resource.Id
 
-		if len(logs) == 0 {
-			logrus.Infof("查询资源 %d 的操作日志为空: %v，无需check", resource.Id, err)
-			continue
+		userID := uint(resource.UserID)
+		if check.running != nil {
+			running[resource.Id] = *check.running
+		}
+		if check.change != nil {
+			userChangesMap[userID] = append(userChangesMap[userID], *check.change)
 		}
+	}
 
-		logrus.Infof("查询资源 %d 的操作日志为: %v", resource.Id, logs[0])
+	c.setWatched(watched, resourceIDs, namespaces)
+	c.running = running
+	c.pushStatusChanges(userChangesMap)
 
-		// 获取最新的操作日志
-		latestLog := logs[0]
-		if latestLog.OperationType == "delete" {
+	// 将每个团队的运行中资源存入Redis并推送给对应的WebSocket客户端，同一团队只推送一次
+	pushed := make(map[uint]bool)
+	for userID := range owners {
+		if pushed[userID] {
 			continue
 		}
+		for _, memberID := range c.pushRunningResources(userID) {
+			pushed[memberID] = true
+		}
+	}
+}
 
-		namespace := latestLog.Namespace
-		metadataName := latestLog.MetadataName
-
-		// 检查资源状态
-		var status int
-		var command string
-
-		if resource.ResourceType == "deployment" {
-			// 检查部署状态
-			deployment, err := conf.KubeClient.AppsV1().Deployments(namespace).Get(context.TODO(), metadataName, metav1.GetOptions{})
-			if err != nil {
-				// 资源不存在，状态为停止
-				status = define.K8sResourceStatusStop
-				command = fmt.Sprintf("kubectl get deployment %s -n %s", metadataName, namespace)
-			} else {
-				// 检查部署状态
-				if deployment.Status.AvailableReplicas == *deployment.Spec.Replicas {
-					status = define.K8sResourceStatusRun // 运行正常
-					// 如果资源正在运行，添加到用户资源列表
-					userID := uint(resource.UserID)
-					resourceInfo := K8sResourceInfo{
-						ResourceID:   uint(resource.Id),
-						ResourceName: metadataName,
-						ResourceType: resource.ResourceType,
-						Namespace:    namespace,
-						UserID:       userID,
-					}
-					userResourcesMap[userID] = append(userResourcesMap[userID], resourceInfo)
-				} else if deployment.Status.UpdatedReplicas < *deployment.Spec.Replicas {
-					status = define.K8sResourceStatusRestart // 容器重启
-				} else {
-					status = define.K8sResourceStatusStop // 运行停止
-				}
-				command = fmt.Sprintf("kubectl get deployment %s -n %s", metadataName, namespace)
-			}
-		} else if resource.ResourceType == "service" {
-			// 检查服务状态
-			_, err := conf.KubeClient.CoreV1().Services(namespace).Get(context.TODO(), metadataName, metav1.GetOptions{})
-			if err != nil {
-				// 服务不存在，状态为停止
-				status = define.K8sResourceStatusStop
-				command = fmt.Sprintf("kubectl get service %s -n %s", metadataName, namespace)
-			} else {
-				// 服务存在，状态为正常
-				status = define.K8sResourceStatusRun
-				command = fmt.Sprintf("kubectl get service %s -n %s", metadataName, namespace)
-				// 如果资源正在运行，添加到用户资源列表
-				userID := uint(resource.UserID)
-				resourceInfo := K8sResourceInfo{
-					ResourceID:   uint(resource.Id),
-					ResourceName: metadataName,
-					ResourceType: resource.ResourceType,
-					Namespace:    namespace,
-					UserID:       userID,
-				}
-				userResourcesMap[userID] = append(userResourcesMap[userID], resourceInfo)
-			}
+// checkResourcesByID 只重新检查 informer 事件涉及的资源，运行中列表有变化时才重新推送资源所属用户的列表
+func (c *K8sResourceStatusChecker) checkResourcesByID(resourceIDs []uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	userChangesMap := make(map[uint][]K8sResourceStatusChange)
+	affectedUsers := make(map[uint]bool)
+	for _, resourceID := range resourceIDs {
+		var check *resourceCheck
+		resource, err := c.userK8sResourceDao.QueryById(resourceID)
+		if err == nil {
+			check = c.checkResource(&resource)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Errorf("查询资源 %d 失败: %v", resourceID, err)
+			continue
+		}
+
+		c.watchedMu.Lock()
+		if check != nil {
+			c.namespaces[resourceID] = check.namespaceKey
 		} else {
-			// 不支持的资源类型
+			delete(c.namespaces, resourceID)
+		}
+		c.watchedMu.Unlock()
+
+		// 运行中列表只在资源进入、离开或信息变化时重新推送
+		previous, wasRunning := c.running[resourceID]
+		delete(c.running, resourceID)
+		var current *K8sResourceInfo
+		if check != nil {
+			current = check.running
+		}
+		if current != nil {
+			c.running[resourceID] = *current
+		}
+		if wasRunning && (current == nil || previous != *current) {
+			affectedUsers[previous.UserID] = true
+		}
+		if current != nil && (!wasRunning || previous != *current) {
+			affectedUsers[current.UserID] = true
+		}
+
+		if check != nil && check.change != nil {
+			userID := uint(resource.UserID)
+			userChangesMap[userID] = append(userChangesMap[userID], *check.change)
+		}
+	}
+
+	c.pushStatusChanges(userChangesMap)
+	pushed := make(map[uint]bool)
+	for userID := range affectedUsers {
+		if pushed[userID] {
 			continue
 		}
+		for _, memberID := range c.pushRunningResources(userID) {
+			pushed[memberID] = true
+		}
+	}
+}
+
+// checkResource 检查单个资源的状态，状态变化时记录操作日志；资源未部署或已删除时返回 nil
+func (c *K8sResourceStatusChecker) checkResource(resource *dao.UserK8sResource) *resourceCheck {
+	// 查询最新的操作日志，获取资源信息
+	logs, err := c.userK8sResourceOperationLogDao.QueryByK8sResourceIDFirst(uint(resource.Id))
+	if err != nil {
+		logrus.Infof("查询资源 %d erros : %v", resource.Id, err)
+		return nil
+	}
+
+	if len(logs) == 0 {
+		return nil
+	}
 
-		// 记录操作日志
-		operationLog := &dao.UserK8sResourceOperationLog{
-			K8sResourceID:  uint(resource.Id),
-			UserID:         uint(resource.UserID),
-			Namespace:      namespace,
-			MetadataName:   metadataName,
-			MetadataLabels: latestLog.MetadataLabels,
-			OperationType:  "check",
-			Status:         status,
-			Command:        command,
+	// 获取最新的操作日志
+	latestLog := logs[0]
+	if latestLog.OperationType == "delete" {
+		return nil
+	}
+
+	namespace := latestLog.Namespace
+	metadataName := latestLog.MetadataName
+	check := &resourceCheck{
+		namespaceKey: namespaceKey(resource.ClusterID, namespace),
+		watchKey:     watchKey(resource.ClusterID, namespace, metadataName),
+		resourceKey:  resourceKey(resource.ClusterID, resource.ResourceType, namespace, metadataName),
+	}
+
+	// 检查资源状态
+	status, command, ok := c.resourceStatus(resource.ClusterID, resource.ResourceType, namespace, metadataName)
+	if !ok {
+		// 不支持的资源类型或集群不可用
+		return check
+	}
+
+	userID := uint(resource.UserID)
+	if status == define.K8sResourceStatusRun {
+		// 如果资源正在运行，添加到用户资源列表
+		check.running = &K8sResourceInfo{
+			ResourceID:   uint(resource.Id),
+			ResourceName: metadataName,
+			ResourceType: resource.ResourceType,
+			Namespace:    namespace,
+			UserID:       userID,
+			ClusterID:    resource.ClusterID,
 		}
+	}
 
-		// 保存操作日志
-		if err := c.userK8sResourceOperationLogDao.Create(operationLog); err != nil {
-			logrus.Errorf("保存资源 %d 的操作日志失败: %v", resource.Id, err)
+	// 状态没有变化时不记录日志，避免操作日志表无限增长
+	if status == latestLog.Status {
+		return check
+	}
+
+	// 记录操作日志
+	// 沿用最新日志中实际部署的版本信息，回滚后的状态变化仍指向回滚的目标版本
+	operationLog := &dao.UserK8sResourceOperationLog{
+		K8sResourceID:    uint(resource.Id),
+		TargetResourceID: latestLog.TargetResourceID,
+		UserID:           userID,
+		Namespace:        namespace,
+		MetadataName:     metadataName,
+		MetadataLabels:   latestLog.MetadataLabels,
+		OperationType:    "check",
+		Status:           status,
+		Command:          command,
+		Overlay:          latestLog.Overlay,
+		Environment:      latestLog.Environment,
+		DockerImageID:    latestLog.DockerImageID,
+	}
+
+	// 保存操作日志
+	if err := c.userK8sResourceOperationLogDao.Create(operationLog); err != nil {
+		logrus.Errorf("保存资源 %d 的操作日志失败: %v", resource.Id, err)
+	}
+
+	check.change = &K8sResourceStatusChange{
+		ResourceID:   uint(resource.Id),
+		ResourceName: metadataName,
+		ResourceType: resource.ResourceType,
+		Namespace:    namespace,
+		OldStatus:    latestLog.Status,
+		Status:       status,
+	}
+	return check
+}
+
+// pushStatusChanges 将状态变化推送给资源所属用户的团队成员
+func (c *K8sResourceStatusChecker) pushStatusChanges(userChangesMap map[uint][]K8sResourceStatusChange) {
+	for userID, changes := range userChangesMap {
+		users, err := c.teamMembers(userID)
+		if err != nil {
+			logrus.Errorf("获取用户 %d 的团队成员失败: %v", userID, err)
+			continue
 		}
+		c.pushStatusChangeToUsers(users, changes)
 	}
+}
 
-	// 将每个用户的资源信息存入Redis并推送给对应的WebSocket客户端
-	for userID, userResources := range userResourcesMap {
-		if len(userResources) > 0 {
-			// 将用户的资源信息序列化为JSON
-			userResourcesJSON, err := json.Marshal(userResources)
-			if err != nil {
-				logrus.Errorf("序列化用户 %d 的运行中资源信息失败: %v", userID, err)
-				continue
-			}
+// pushRunningResources 将用户所在团队全部成员的运行中资源存入每个成员的 Redis 列表并推送，没有运行中资源时写入空列表；
+// 返回已推送的团队成员
+func (c *K8sResourceStatusChecker) pushRunningResources(userID uint) []uint {
+	// 获取用户所在团队信息
+	users, err := c.teamMembers(userID)
+	if err != nil {
+		logrus.Errorf("获取用户 %d 的团队成员失败: %v", userID, err)
+		return nil
+	}
+	memberIDs := make([]uint, 0, len(users))
+	members := make(map[uint]bool, len(users))
+	for _, user := range users {
+		memberIDs = append(memberIDs, uint(user.Id))
+		members[uint(user.Id)] = true
+	}
 
-			// 获取用户所在团队信息
-			userCreator, err2 := c.userDao.GetUserByID(uint32(userID))
-			if err2 != nil {
-				break
-			}
+	userResources := make([]K8sResourceInfo, 0)
+	for _, info := range c.running {
+		if members[info.UserID] {
+			userResources = append(userResources, info)
+		}
+	}
+	sort.Slice(userResources, func(i, j int) bool { return userResources[i].ResourceID < userResources[j].ResourceID })
 
-			users, err2 := c.userDao.GetUsersByTeamID(userCreator.TeamID)
-			if err2 != nil {
-				break
-			}
+	// 将用户的资源信息序列化为JSON
+	userResourcesJSON, err := json.Marshal(userResources)
+	if err != nil {
+		logrus.Errorf("序列化用户 %d 的运行中资源信息失败: %v", userID, err)
+		return memberIDs
+	}
+
+	// 将消息推送出去
+	c.pushResourceInfoToRedisAndUser(users, userResourcesJSON, userResources)
+	return memberIDs
+}
+
+// resourceStatus 根据资源类型计算资源当前状态，不支持的资源类型或集群不可用时返回 false
+func (c *K8sResourceStatusChecker) resourceStatus(clusterID uint32, resourceType string, namespace string, name string) (int, string, bool) {
+	informer, err := c.clusterInformer(clusterID)
+	if err != nil {
+		logrus.Errorf("获取集群 %d 的 informer 失败: %v", clusterID, err)
+		return 0, "", false
+	}
 
-			// 将消息推送出去
-			c.pushResourceInfoToRedisAndUser(users, userResourcesJSON, userResources)
+	switch resourceType {
+	case "deployment":
+		command := fmt.Sprintf("kubectl get deployment %s -n %s", name, namespace)
+		deployment, err := informer.getDeployment(namespace, name)
+		if err != nil {
+			// 资源不存在，状态为停止
+			return define.K8sResourceStatusStop, command, true
+		}
+
+		// 检查部署状态
+		if deployment.Status.AvailableReplicas == *deployment.Spec.Replicas {
+			// 副本都可用，但仍有容器在反复重启时视为重启中
+			if informer.hasCrashingPods(deployment) {
+				return define.K8sResourceStatusRestart, command, true
+			}
+			return define.K8sResourceStatusRun, command, true // 运行正常
+		} else if deployment.Status.UpdatedReplicas < *deployment.Spec.Replicas {
+			return define.K8sResourceStatusRestart, command, true // 容器重启
+		}
+		return define.K8sResourceStatusStop, command, true // 运行停止
+	case "service":
+		command := fmt.Sprintf("kubectl get service %s -n %s", name, namespace)
+		if _, err := informer.getService(namespace, name); err != nil {
+			// 服务不存在，状态为停止
+			return define.K8sResourceStatusStop, command, true
+		}
+		// 服务存在，状态为正常
+		return define.K8sResourceStatusRun, command, true
+	case "statefulset":
+		command := fmt.Sprintf("kubectl get statefulset %s -n %s", name, namespace)
+		statefulSet, err := informer.getStatefulSet(namespace, name)
+		if err != nil {
+			return define.K8sResourceStatusStop, command, true
 		}
+		status, _ := k8s_manage.StatefulSetHealth(statefulSet)
+		return status, command, true
+	case "daemonset":
+		command := fmt.Sprintf("kubectl get daemonset %s -n %s", name, namespace)
+		daemonSet, err := informer.getDaemonSet(namespace, name)
+		if err != nil {
+			return define.K8sResourceStatusStop, command, true
+		}
+		status, _ := k8s_manage.DaemonSetHealth(daemonSet)
+		return status, command, true
+	case "job":
+		command := fmt.Sprintf("kubectl get job %s -n %s", name, namespace)
+		job, err := informer.getJob(namespace, name)
+		if err != nil {
+			return define.K8sResourceStatusStop, command, true
+		}
+		status, _ := k8s_manage.JobHealth(job)
+		return status, command, true
+	case "cronjob":
+		command := fmt.Sprintf("kubectl get cronjob %s -n %s", name, namespace)
+		cronJob, err := informer.getCronJob(namespace, name)
+		if err != nil {
+			return define.K8sResourceStatusStop, command, true
+		}
+		status, _ := k8s_manage.CronJobHealth(cronJob)
+		return status, command, true
+	case "ingress":
+		command := fmt.Sprintf("kubectl get ingress %s -n %s", name, namespace)
+		ingress, err := informer.getIngress(namespace, name)
+		if err != nil {
+			// Ingress 不存在，状态为停止
+			return define.K8sResourceStatusStop, command, true
+		}
+		// 后端 Service 缺失时流量无法转发，视为异常
+		if len(informer.missingIngressBackends(ingress)) > 0 {
+			return define.K8sResourceStatusRestart, command, true
+		}
+		return define.K8sResourceStatusRun, command, true
+	default:
+		return 0, "", false
 	}
 }
 
-// pushResourceInfoToRedisAndUser 将k8s资源推送给用户
-func (c *K8sResourceStatusChecker) pushResourceInfoToRedisAndUser(users []*dao.Users, userResourcesJSON []byte, userResources []K8sResourceInfo) {
-	// 使用Redis存储用户的资源信息，键名格式为 k8s:running_resources:{user_id}
+// teamMembers 获取用户所在团队的全部成员，没有团队时只返回用户本人
+func (c *K8sResourceStatusChecker) teamMembers(userID uint) ([]*dao.Users, error) {
+	userCreator, err := c.userDao.GetUserByID(uint32(userID))
+	if err != nil {
+		return nil, err
+	}
+	if userCreator.TeamID == 0 {
+		return []*dao.Users{userCreator}, nil
+	}
+	return c.userDao.GetUsersByTeamID(userCreator.TeamID)
+}
+
+// pushStatusChangeToUsers 将资源状态变化推送给团队成员
+func (c *K8sResourceStatusChecker) pushStatusChangeToUsers(users []*dao.Users, changes []K8sResourceStatusChange) {
 	for _, user := range users {
-		// 检测团队成员是否连接
-		_, exists := conf.WSServer.Connections[uint(user.Id)]
+		conn, exists := conf.WSServer.Connections[uint(user.Id)]
 		if !exists {
-			logrus.Infof("用户 %d 没有活动的WebSocket连接", user.Id)
 			continue
 		}
 
+		response := map[string]interface{}{
+			"success": true,
+			"message": "resource_status_changed",
+			"data": map[string]interface{}{
+				"type":      "resource_status_changed",
+				"changes":   changes,
+				"timestamp": time.Now().Unix(),
+			},
+		}
+		if err := conf.WSServer.WriteJSON(conn, response); err != nil {
+			logrus.Errorf("向用户 %d 推送资源状态变化失败: %v", user.Id, err)
+		}
+	}
+}
+
+// pushResourceInfoToRedisAndUser 将k8s资源推送给用户
+func (c *K8sResourceStatusChecker) pushResourceInfoToRedisAndUser(users []*dao.Users, userResourcesJSON []byte, userResources []K8sResourceInfo) {
+	// 使用Redis存储用户的资源信息，键名格式为 k8s:running_resources:{user_id}
+	// informer resync 时会重新写入，过期时间取两个 resync 周期
+	// 没有连接的成员也写入，重新连接后 get / describe 不会读到过时的列表
+	for _, user := range users {
 		redisKey := fmt.Sprintf(define.K8sRunningResources, user.Id)
-		err := conf.RedisClient.Set(context.Background(), redisKey, userResourcesJSON, 2*time.Minute).Err()
+		err := conf.RedisClient.Set(context.Background(), redisKey, userResourcesJSON, 2*informerResync).Err()
 		if err != nil {
 			logrus.Errorf("将用户 %d 的运行中资源信息存入Redis失败: %v", user.Id, err)
 			continue
@@ -243,7 +517,7 @@ func (c *K8sResourceStatusChecker) pushResourceInfoToUser(user *dao.Users, resou
 	}
 
 	// 发送消息
-	err := conn.WriteJSON(response)
+	err := conf.WSServer.WriteJSON(conn, response)
 	if err != nil {
 		logrus.Errorf("向用户 %d 推送资源状态消息失败: %v", user.Id, err)
 	} else {
@@ -253,6 +527,8 @@ func (c *K8sResourceStatusChecker) pushResourceInfoToUser(user *dao.Users, resou
 
 // PushRunningResource quick start one method
 func PushRunningResource() {
-	k8sResourceStatusChecker = NewK8sResourceStatusChecker(dao.NewUserK8sResourceDao(conf.DB), dao.NewUserK8sResourceOperationLogDao(conf.DB), dao.NewUsersDao(conf.DB))
+	if k8sResourceStatusChecker == nil {
+		Init()
+	}
 	k8sResourceStatusChecker.checkResources()
 }
@@ -0,0 +1,124 @@
+package scheduled_tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// k8sResourceUsageSampleInterval 用量采样间隔
+const k8sResourceUsageSampleInterval = time.Minute
+
+// K8sResourceUsageSampler 定时从 metrics.k8s.io 采集平台管理的工作负载用量并写入 MySQL
+type K8sResourceUsageSampler struct {
+	userK8sResourceDao             *dao.UserK8sResourceDao
+	userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao
+	userK8sResourceUsageDao        *dao.UserK8sResourceUsageDao
+	statusChecker                  *K8sResourceStatusChecker
+}
+
+// NewK8sResourceUsageSampler 创建用量采样器，工作负载的 selector 从状态检查器的 informer 中读取
+func NewK8sResourceUsageSampler(userK8sResourceDao *dao.UserK8sResourceDao, userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao, userK8sResourceUsageDao *dao.UserK8sResourceUsageDao, statusChecker *K8sResourceStatusChecker) *K8sResourceUsageSampler {
+	return &K8sResourceUsageSampler{
+		userK8sResourceDao:             userK8sResourceDao,
+		userK8sResourceOperationLogDao: userK8sResourceOperationLogDao,
+		userK8sResourceUsageDao:        userK8sResourceUsageDao,
+		statusChecker:                  statusChecker,
+	}
+}
+
+func (s *K8sResourceUsageSampler) start() {
+	go func() {
+		ticker := time.NewTicker(k8sResourceUsageSampleInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.sample()
+		}
+	}()
+	logrus.Info("K8s 资源用量采样器已启动")
+}
+
+// sample 采集一轮用量，并清理超过保留时间的采样
+func (s *K8sResourceUsageSampler) sample() {
+	resources, err := s.userK8sResourceDao.QueryAll()
+	if err != nil {
+		logrus.Errorf("查询 K8s 资源失败: %v", err)
+		return
+	}
+
+	ctx := context.TODO()
+	now := time.Now()
+	var usages []*dao.UserK8sResourceUsage
+	for _, resource := range resources {
+		// 只采集当前版本的工作负载
+		if resource.IsUpdate || resource.ResourceType != "deployment" {
+			continue
+		}
+
+		logs, err := s.userK8sResourceOperationLogDao.QueryByK8sResourceIDFirst(uint(resource.Id))
+		if err != nil || len(logs) == 0 || logs[0].OperationType == "delete" {
+			continue
+		}
+		namespace, name := logs[0].Namespace, logs[0].MetadataName
+
+		usage, err := s.workloadUsage(ctx, resource.ClusterID, namespace, name)
+		if err != nil {
+			logrus.Debugf("采集资源 %d 用量失败: %v", resource.Id, err)
+			continue
+		}
+		usage.K8sResourceID = resource.Id
+		usage.SampledAt = &now
+		usages = append(usages, usage)
+	}
+
+	if err := s.userK8sResourceUsageDao.CreateBatch(usages); err != nil {
+		logrus.Errorf("保存资源用量失败: %v", err)
+	}
+	if err := s.userK8sResourceUsageDao.DeleteBefore(now.Add(-define.K8sResourceUsageRetention)); err != nil {
+		logrus.Errorf("清理过期的资源用量失败: %v", err)
+	}
+}
+
+// workloadUsage 按 Deployment 的 selector 汇总其全部 Pod 的用量
+func (s *K8sResourceUsageSampler) workloadUsage(ctx context.Context, clusterID uint32, namespace string, name string) (*dao.UserK8sResourceUsage, error) {
+	informer, err := s.statusChecker.clusterInformer(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	deployment, err := informer.getDeployment(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := conf.GetK8sClients(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	podMetrics, err := k8s_manage.ListPodMetrics(ctx, clients, namespace, selector.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var total k8s_manage.ResourceUsage
+	for _, pod := range podMetrics {
+		total.Add(pod.Usage)
+	}
+	return &dao.UserK8sResourceUsage{
+		ClusterID:    clusterID,
+		Namespace:    namespace,
+		MetadataName: name,
+		PodCount:     len(podMetrics),
+		CPUMilli:     total.CPU.MilliValue(),
+		MemoryBytes:  total.Memory.Value(),
+	}, nil
+}
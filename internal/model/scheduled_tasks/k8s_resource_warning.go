@@ -0,0 +1,199 @@
+package scheduled_tasks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ZZGADA/easy-deploy/internal/config"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// resourceKey 平台管理资源的 key，资源类型参与区分，避免同名的 Deployment 和 Service 混淆；只用于 legacy_name_attribution
+func resourceKey(clusterID uint32, resourceType string, namespace string, name string) string {
+	return fmt.Sprintf("%d/%s/%s/%s", clusterID, resourceType, namespace, name)
+}
+
+// eventOwner 事件可能归属的平台资源
+type eventOwner struct {
+	resourceType string
+	name         string
+}
+
+// onWarningEvent Warning 事件回调，事件归属于平台管理的资源时按 k8s_resource_id 保存。
+// 归属以涉及对象上平台写入的资源 ID 标签为准，且资源必须部署在事件所在的 namespace；
+// 对象没有标签（已被删除或部署早于标签）时，只在开启 legacy_name_attribution 时按 owner 和名称推断
+func (c *K8sResourceStatusChecker) onWarningEvent(clusterID uint32, event *v1.Event) {
+	c.informersMu.Lock()
+	informer := c.informers[clusterID]
+	c.informersMu.Unlock()
+
+	resourceID, labeled := informer.labeledResourceID(event)
+	found := labeled && c.deployedIn(resourceID, clusterID, event.Namespace)
+	if !labeled && config.GlobalConfig.K8s.LegacyNameAttribution {
+		resourceID, found = c.legacyEventOwner(informer, clusterID, event)
+	}
+	if !found {
+		return
+	}
+
+	firstSeen, lastSeen := k8s_manage.EventFirstSeen(event), k8s_manage.EventLastSeen(event)
+	count := event.Count
+	if count == 0 {
+		count = 1
+	}
+	record := &dao.UserK8sResourceEvent{
+		K8sResourceID: resourceID,
+		ClusterID:     clusterID,
+		Namespace:     event.Namespace,
+		EventUID:      string(event.UID),
+		InvolvedKind:  event.InvolvedObject.Kind,
+		InvolvedName:  event.InvolvedObject.Name,
+		Reason:        event.Reason,
+		Message:       event.Message,
+		Count:         count,
+		FirstSeen:     &firstSeen,
+		LastSeen:      &lastSeen,
+	}
+	if err := c.userK8sResourceEventDao.Save(record); err != nil {
+		logrus.Errorf("保存资源 %d 的 Warning 事件失败: %v", resourceID, err)
+	}
+}
+
+// legacyEventOwner 按事件涉及对象的 owner 和名称推断所属的平台资源，兼容没有资源 ID 标签的对象
+func (c *K8sResourceStatusChecker) legacyEventOwner(informer *resourceInformer, clusterID uint32, event *v1.Event) (uint32, bool) {
+	c.watchedMu.RLock()
+	defer c.watchedMu.RUnlock()
+	for _, owner := range eventOwners(informer, event) {
+		if owner.name == "" {
+			continue
+		}
+		if resourceID, found := c.resourceIDs[resourceKey(clusterID, owner.resourceType, event.Namespace, owner.name)]; found {
+			return resourceID, true
+		}
+	}
+	return 0, false
+}
+
+// eventOwners 事件涉及对象可能归属的平台资源：ReplicaSet、Job 和 Pod 的事件归到所属的工作负载，
+// 对象已被删除无法查询 owner 时按命名规则推断全部可能的工作负载
+func eventOwners(informer *resourceInformer, event *v1.Event) []eventOwner {
+	involved := event.InvolvedObject
+
+	switch involved.Kind {
+	case "ReplicaSet":
+		// Deployment 创建的 ReplicaSet 命名为 <deployment>-<pod-template-hash>
+		return []eventOwner{{"deployment", trimNameSuffix(involved.Name, 1)}}
+	case "Job":
+		if informer.hasSynced() {
+			if job, err := informer.jobLister.Jobs(event.Namespace).Get(involved.Name); err == nil {
+				return []eventOwner{jobOwner(job)}
+			}
+		}
+		return []eventOwner{{"job", involved.Name}, {"cronjob", trimNameSuffix(involved.Name, 1)}}
+	case "Pod":
+		if informer.hasSynced() {
+			if pod, err := informer.podLister.Pods(event.Namespace).Get(involved.Name); err == nil {
+				return []eventOwner{informer.podOwner(pod)}
+			}
+		}
+		// Deployment 的 Pod 命名为 <deployment>-<pod-template-hash>-<suffix>，其他工作负载为 <name>-<suffix>
+		return []eventOwner{
+			{"deployment", trimNameSuffix(involved.Name, 2)},
+			{"statefulset", trimNameSuffix(involved.Name, 1)},
+			{"daemonset", trimNameSuffix(involved.Name, 1)},
+			{"job", trimNameSuffix(involved.Name, 1)},
+			{"cronjob", trimNameSuffix(involved.Name, 2)},
+		}
+	}
+	for resourceType, gvk := range k8s_manage.ResourceKinds {
+		if gvk.Kind == involved.Kind {
+			return []eventOwner{{resourceType, involved.Name}}
+		}
+	}
+	return nil
+}
+
+// labeledResourceID 从 informer 缓存中查询事件涉及的对象，读取平台写入的资源 ID 标签；
+// ReplicaSet 不在缓存中，直接查询 API Server，其标签复制自 Deployment 的 Pod 模板
+func (r *resourceInformer) labeledResourceID(event *v1.Event) (uint32, bool) {
+	if !r.hasSynced() {
+		return 0, false
+	}
+	namespace, name := event.Namespace, event.InvolvedObject.Name
+	var object metav1.Object
+	var err error
+	switch event.InvolvedObject.Kind {
+	case "Pod":
+		object, err = r.podLister.Pods(namespace).Get(name)
+	case "Job":
+		object, err = r.jobLister.Jobs(namespace).Get(name)
+	case "ReplicaSet":
+		object, err = r.clients.Client.AppsV1().ReplicaSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	case "Deployment":
+		object, err = r.deploymentLister.Deployments(namespace).Get(name)
+	case "StatefulSet":
+		object, err = r.statefulSetLister.StatefulSets(namespace).Get(name)
+	case "DaemonSet":
+		object, err = r.daemonSetLister.DaemonSets(namespace).Get(name)
+	case "CronJob":
+		object, err = r.cronJobLister.CronJobs(namespace).Get(name)
+	case "Service":
+		object, err = r.serviceLister.Services(namespace).Get(name)
+	case "Ingress":
+		object, err = r.ingressLister.Ingresses(namespace).Get(name)
+	default:
+		return 0, false
+	}
+	if err != nil {
+		return 0, false
+	}
+	return k8s_manage.ResourceIDFromLabels(object.GetLabels())
+}
+
+// podOwner Pod 所属的工作负载
+func (r *resourceInformer) podOwner(pod *v1.Pod) eventOwner {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return eventOwner{}
+	}
+	switch owner.Kind {
+	case "ReplicaSet":
+		return eventOwner{"deployment", podControllerName(pod)}
+	case "StatefulSet":
+		return eventOwner{"statefulset", owner.Name}
+	case "DaemonSet":
+		return eventOwner{"daemonset", owner.Name}
+	case "Job":
+		if job, err := r.jobLister.Jobs(pod.Namespace).Get(owner.Name); err == nil {
+			return jobOwner(job)
+		}
+		return eventOwner{"job", owner.Name}
+	}
+	return eventOwner{}
+}
+
+// jobOwner CronJob 创建的 Job 归到 CronJob，其他 Job 归到自身
+func jobOwner(job *batchv1.Job) eventOwner {
+	if name := jobControllerName(job); name != job.Name {
+		return eventOwner{"cronjob", name}
+	}
+	return eventOwner{"job", job.Name}
+}
+
+// trimNameSuffix 去掉名称末尾 n 段以 - 分隔的后缀，段数不足时返回空
+func trimNameSuffix(name string, n int) string {
+	for i := 0; i < n; i++ {
+		index := strings.LastIndex(name, "-")
+		if index <= 0 {
+			return ""
+		}
+		name = name[:index]
+	}
+	return name
+}
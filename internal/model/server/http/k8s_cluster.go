@@ -0,0 +1,112 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gin-gonic/gin"
+)
+
+// K8sClusterHandler K8s 集群注册管理
+type K8sClusterHandler struct {
+	k8sClusterService *k8s_manage.K8sClusterService
+}
+
+func NewK8sClusterHandler(k8sClusterService *k8s_manage.K8sClusterService) *K8sClusterHandler {
+	return &K8sClusterHandler{
+		k8sClusterService: k8sClusterService,
+	}
+}
+
+type SaveClusterRequest struct {
+	Name        string `json:"name" binding:"required"`
+	AuthType    string `json:"auth_type" binding:"required"`  // kubeconfig / token
+	ApiServer   string `json:"api_server"`                    // token 方式必填
+	Credential  string `json:"credential" binding:"required"` // kubeconfig 内容或 service account token
+	CAData      string `json:"ca_data"`                       // token 方式的 CA 证书，不填时需要开启 insecure
+	Insecure    bool   `json:"insecure"`                      // 跳过 API Server 证书校验，只在没有 CA 证书时使用
+	ShareToTeam bool   `json:"share_to_team"`
+}
+
+type ClusterIDRequest struct {
+	ID uint32 `json:"id" binding:"required"`
+}
+
+// SaveCluster 注册集群
+func (h *K8sClusterHandler) SaveCluster(c *gin.Context) {
+	var req SaveClusterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	cluster, err := h.k8sClusterService.SaveCluster(userID, req.Name, req.AuthType, req.ApiServer, req.Credential, req.CAData, req.Insecure, req.ShareToTeam)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "success",
+		"data":    cluster})
+}
+
+// QueryClusters 查询用户可用的集群列表
+func (h *K8sClusterHandler) QueryClusters(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	clusters, err := h.k8sClusterService.QueryClusters(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "success",
+		"data":    clusters})
+}
+
+// DeleteCluster 删除集群
+func (h *K8sClusterHandler) DeleteCluster(c *gin.Context) {
+	var req ClusterIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.k8sClusterService.DeleteCluster(userID, req.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// CheckCluster 检查集群健康状态
+func (h *K8sClusterHandler) CheckCluster(c *gin.Context) {
+	var req ClusterIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.k8sClusterService.CanAccess(userID, req.ID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	cluster, err := h.k8sClusterService.CheckHealth(req.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "success",
+		"data":    cluster})
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gin-gonic/gin"
+)
+
+// K8sNamespaceHandler 团队 namespace 管理
+type K8sNamespaceHandler struct {
+	k8sNamespaceService *k8s_manage.K8sNamespaceService
+}
+
+func NewK8sNamespaceHandler(k8sNamespaceService *k8s_manage.K8sNamespaceService) *K8sNamespaceHandler {
+	return &K8sNamespaceHandler{
+		k8sNamespaceService: k8sNamespaceService,
+	}
+}
+
+type SaveNamespaceRequest struct {
+	Namespace string `json:"namespace" binding:"required"`
+	ClusterID uint32 `json:"cluster_id"` // 不填为默认集群
+}
+
+type NamespaceIDRequest struct {
+	ID uint32 `json:"id" binding:"required"`
+}
+
+// SaveNamespace 创建团队 namespace
+func (h *K8sNamespaceHandler) SaveNamespace(c *gin.Context) {
+	var req SaveNamespaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	namespace, err := h.k8sNamespaceService.CreateNamespace(userID, req.ClusterID, req.Namespace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "success",
+		"data":    namespace})
+}
+
+// QueryNamespaces 查询团队 namespace 列表
+func (h *K8sNamespaceHandler) QueryNamespaces(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	namespaces, err := h.k8sNamespaceService.QueryNamespaces(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "success",
+		"data":    namespaces})
+}
+
+// DeleteNamespace 删除团队 namespace
+func (h *K8sNamespaceHandler) DeleteNamespace(c *gin.Context) {
+	var req NamespaceIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.k8sNamespaceService.DeleteNamespace(userID, req.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// SyncNamespace 重新下发 namespace 的 RBAC 与配额
+func (h *K8sNamespaceHandler) SyncNamespace(c *gin.Context) {
+	var req NamespaceIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.k8sNamespaceService.SyncNamespace(userID, req.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// UpdateSettings 更新团队 namespace 的配额设置
+func (h *K8sNamespaceHandler) UpdateSettings(c *gin.Context) {
+	var req k8s_manage.K8sNamespaceSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	team, err := h.k8sNamespaceService.UpdateSettings(userID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "success",
+		"data":    team})
+}
@@ -2,7 +2,9 @@ package http
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
 	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
 	"github.com/gin-gonic/gin"
 )
@@ -22,6 +24,7 @@ type SaveResourceRequest struct {
 	ResourceType string `json:"resource_type" binding:"required"`
 	OssURL       string `json:"oss_url" binding:"required"`
 	FileName     string `json:"file_name" binding:"required"`
+	ClusterID    uint32 `json:"cluster_id"` // 目标集群，不填为默认集群
 }
 type UpdateResourceRequest struct {
 	Id           int    `json:"id" binding:"required"`
@@ -29,6 +32,38 @@ type UpdateResourceRequest struct {
 	ResourceType string `json:"resource_type" binding:"required"`
 	OssURL       string `json:"oss_url" binding:"required"`
 	FileName     string `json:"file_name" binding:"required"`
+	ClusterID    uint32 `json:"cluster_id"`
+}
+
+type ValidateResourceRequest struct {
+	ResourceType  string `json:"resource_type" binding:"required"`
+	OssURL        string `json:"oss_url" binding:"required"`
+	ClusterID     uint32 `json:"cluster_id"`
+	K8sResourceID uint32 `json:"k8s_resource_id"` // 更新已有资源时使用其变量集渲染模板
+}
+
+type SaveResourceVariablesRequest struct {
+	K8sResourceID uint32                 `json:"k8s_resource_id" binding:"required"`
+	Environment   string                 `json:"environment"` // 不填为 default
+	Variables     map[string]interface{} `json:"variables" binding:"required"`
+}
+
+type DeleteResourceVariablesRequest struct {
+	ID uint32 `json:"id" binding:"required"`
+}
+
+type SaveResourceAutoscalerRequest struct {
+	K8sResourceID                 uint32 `json:"k8s_resource_id" binding:"required"`
+	MinReplicas                   int32  `json:"min_replicas" binding:"required"`
+	MaxReplicas                   int32  `json:"max_replicas" binding:"required"`
+	TargetCPUUtilization          int32  `json:"target_cpu_utilization"`           // 百分比，不填不按 CPU 扩缩
+	TargetMemoryUtilization       int32  `json:"target_memory_utilization"`        // 百分比，不填不按内存扩缩
+	ScaleUpStabilizationSeconds   *int32 `json:"scale_up_stabilization_seconds"`   // 不填使用 k8s 默认值
+	ScaleDownStabilizationSeconds *int32 `json:"scale_down_stabilization_seconds"` // 不填使用 k8s 默认值
+}
+
+type DeleteResourceAutoscalerRequest struct {
+	K8sResourceID uint32 `json:"k8s_resource_id" binding:"required"`
 }
 
 type DeleteResourceRequest struct {
@@ -50,12 +85,50 @@ func (h *K8sResourceHandler) SaveResource(c *gin.Context) {
 	}
 
 	userID := c.GetUint("user_id")
-	if err := h.k8sResourceService.SaveResource(userID, req.RepositoryID, req.ResourceType, req.OssURL, req.FileName); err != nil {
+
+	// 校验 YAML，存在 error 级别的问题时不保存
+	validation, err := h.k8sResourceService.ValidateManifest(userID, req.ClusterID, req.ResourceType, req.OssURL, 0)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if !validation.Valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "manifest validation failed", "data": validation})
+		return
+	}
+
+	if err := h.k8sResourceService.SaveResource(userID, req.RepositoryID, req.ResourceType, req.OssURL, req.FileName, req.ClusterID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "success"})
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": validation})
+}
+
+// ValidateResource 只校验 YAML，不保存
+func (h *K8sResourceHandler) ValidateResource(c *gin.Context) {
+	var req ValidateResourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.k8sResourceService.ValidateResourceType(req.ResourceType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid resource type"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	validation, err := h.k8sResourceService.ValidateManifest(userID, req.ClusterID, req.ResourceType, req.OssURL, req.K8sResourceID)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "success",
+		"data":    validation})
 }
 
 // DeleteResource 删除 K8s 资源配置
@@ -88,12 +161,23 @@ func (h *K8sResourceHandler) UpdateResource(c *gin.Context) {
 	}
 
 	userID := c.GetUint("user_id")
-	if err := h.k8sResourceService.UpdateResource(userID, uint32(req.Id), req.RepositoryID, req.ResourceType, req.OssURL, req.FileName); err != nil {
+
+	validation, err := h.k8sResourceService.ValidateManifest(userID, req.ClusterID, req.ResourceType, req.OssURL, uint32(req.Id))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if !validation.Valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "manifest validation failed", "data": validation})
+		return
+	}
+
+	if err := h.k8sResourceService.UpdateResource(userID, uint32(req.Id), req.RepositoryID, req.ResourceType, req.OssURL, req.FileName, req.ClusterID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "success"})
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": validation})
 
 }
 
@@ -118,3 +202,148 @@ func (h *K8sResourceHandler) QueryResources(c *gin.Context) {
 		"message": "success",
 		"data":    resources})
 }
+
+// QueryResourceVersions 查询 K8s 资源配置的版本历史
+func (h *K8sResourceHandler) QueryResourceVersions(c *gin.Context) {
+	k8sResourceIDStr := c.Query("k8s_resource_id")
+	if k8sResourceIDStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "k8s_resource_id is required"})
+		return
+	}
+
+	k8sResourceID, err := strconv.ParseUint(k8sResourceIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid k8s_resource_id"})
+		return
+	}
+
+	versions, err := h.k8sResourceService.QueryVersionHistory(uint32(k8sResourceID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "success",
+		"data":    versions})
+}
+
+// SaveResourceVariables 保存资源某个环境的模板变量集
+func (h *K8sResourceHandler) SaveResourceVariables(c *gin.Context) {
+	var req SaveResourceVariablesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.k8sResourceService.SaveVariables(userID, req.K8sResourceID, req.Environment, req.Variables); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// QueryResourceVariables 查询资源的全部模板变量集
+func (h *K8sResourceHandler) QueryResourceVariables(c *gin.Context) {
+	k8sResourceID, err := strconv.ParseUint(c.Query("k8s_resource_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid k8s_resource_id"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	variables, err := h.k8sResourceService.QueryVariables(userID, uint32(k8sResourceID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "success",
+		"data":    variables})
+}
+
+// DeleteResourceVariables 删除模板变量集
+func (h *K8sResourceHandler) DeleteResourceVariables(c *gin.Context) {
+	var req DeleteResourceVariablesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.k8sResourceService.DeleteVariables(userID, req.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// SaveResourceAutoscaler 保存 Deployment 资源的扩缩容策略
+func (h *K8sResourceHandler) SaveResourceAutoscaler(c *gin.Context) {
+	var req SaveResourceAutoscalerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	autoscaler := &dao.UserK8sResourceAutoscaler{
+		K8sResourceID:                 req.K8sResourceID,
+		MinReplicas:                   req.MinReplicas,
+		MaxReplicas:                   req.MaxReplicas,
+		TargetCPUUtilization:          req.TargetCPUUtilization,
+		TargetMemoryUtilization:       req.TargetMemoryUtilization,
+		ScaleUpStabilizationSeconds:   req.ScaleUpStabilizationSeconds,
+		ScaleDownStabilizationSeconds: req.ScaleDownStabilizationSeconds,
+	}
+	if err := h.k8sResourceService.SaveAutoscaler(userID, autoscaler); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// QueryResourceAutoscaler 查询资源的扩缩容策略，未配置时 data 为空
+func (h *K8sResourceHandler) QueryResourceAutoscaler(c *gin.Context) {
+	k8sResourceID, err := strconv.ParseUint(c.Query("k8s_resource_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid k8s_resource_id"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	autoscaler, err := h.k8sResourceService.QueryResourceAutoscaler(userID, uint32(k8sResourceID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "success",
+		"data":    autoscaler})
+}
+
+// DeleteResourceAutoscaler 删除资源的扩缩容策略
+func (h *K8sResourceHandler) DeleteResourceAutoscaler(c *gin.Context) {
+	var req DeleteResourceAutoscalerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.k8sResourceService.DeleteAutoscaler(userID, req.K8sResourceID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
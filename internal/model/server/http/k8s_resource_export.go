@@ -0,0 +1,51 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gin-gonic/gin"
+)
+
+// K8sResourceExportHandler K8s 资源导出处理程序
+type K8sResourceExportHandler struct {
+	k8sResourceExportService *k8s_manage.K8sResourceExportService
+}
+
+// NewK8sResourceExportHandler 创建 K8s 资源导出处理程序
+func NewK8sResourceExportHandler(k8sResourceExportService *k8s_manage.K8sResourceExportService) *K8sResourceExportHandler {
+	return &K8sResourceExportHandler{
+		k8sResourceExportService: k8sResourceExportService,
+	}
+}
+
+// ExportResources 下载仓库下所有资源的导出文件，format 为 yaml（默认）或 tar.gz，live=true 时包含去掉运行时字段的线上对象
+func (h *K8sResourceExportHandler) ExportResources(c *gin.Context) {
+	repositoryID := c.Query("repository_id")
+	if repositoryID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "repository_id is required"})
+		return
+	}
+
+	includeLive := false
+	if liveStr := c.Query("live"); liveStr != "" {
+		live, err := strconv.ParseBool(liveStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid live"})
+			return
+		}
+		includeLive = live
+	}
+
+	userID := c.GetUint("user_id")
+	bundle, err := h.k8sResourceExportService.ExportRepository(c.Request.Context(), userID, repositoryID, c.Query("format"), includeLive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", bundle.FileName))
+	c.Data(http.StatusOK, bundle.ContentType, bundle.Content)
+}
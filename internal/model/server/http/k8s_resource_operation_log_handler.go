@@ -3,7 +3,9 @@ package http
 import (
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/ZZGADA/easy-deploy/internal/define"
 	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
 	"github.com/gin-gonic/gin"
 )
@@ -67,3 +69,57 @@ func (h *K8sResourceOperationLogHandler) QueryOperationLogs(c *gin.Context) {
 		"page_size": pageSize,
 	})
 }
+
+// QueryResourceUsage 查询 K8s 资源最近 hours 小时的用量采样，最多 24 小时
+func (h *K8sResourceOperationLogHandler) QueryResourceUsage(c *gin.Context) {
+	k8sResourceID, err := strconv.ParseUint(c.Query("k8s_resource_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid k8s_resource_id"})
+		return
+	}
+
+	retention := int(define.K8sResourceUsageRetention / time.Hour)
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", strconv.Itoa(retention)))
+	if err != nil || hours < 1 || hours > retention {
+		hours = retention
+	}
+
+	usages, err := h.k8sResourceOperationLogService.QueryUsage(uint32(k8sResourceID), time.Now().Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"usages":  usages,
+		"hours":   hours,
+	})
+}
+
+// QueryResourceEvents 查询 K8s 资源最近的 Warning 事件，默认 50 条，最多 200 条
+func (h *K8sResourceOperationLogHandler) QueryResourceEvents(c *gin.Context) {
+	k8sResourceID, err := strconv.ParseUint(c.Query("k8s_resource_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid k8s_resource_id"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	events, err := h.k8sResourceOperationLogService.QueryWarningEvents(uint32(k8sResourceID), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"events":  events,
+	})
+}
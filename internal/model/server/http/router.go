@@ -25,16 +25,32 @@ func SetupRouter(r *gin.Engine) {
 
 	r.Use(cors.New(config))
 
+	ossService := oss_manage.NewOssService(dao.NewUserOssDao(conf.DB))
+	k8sClusterService := k8s_manage.NewK8sClusterService(dao.NewUserK8sClusterDao(conf.DB), dao.NewUsersDao(conf.DB))
+	k8sNamespaceService := k8s_manage.NewK8sNamespaceService(dao.NewTeamK8sNamespaceDao(conf.DB), dao.NewTeamDao(conf.DB), dao.NewUsersDao(conf.DB), k8sClusterService)
+	k8sResourceService := k8s_manage.NewK8sResourceService(dao.NewUserK8sResourceDao(conf.DB), dao.NewUserK8sResourceVariableDao(conf.DB), dao.NewUserK8sResourceAutoscalerDao(conf.DB), dao.NewUserK8sResourceOperationLogDao(conf.DB), dao.NewUserDockerImageDao(conf.DB),
+		k8sClusterService, k8sNamespaceService, ossService)
+
 	// 注册 WebSocket 路由
 
+	socketService := websocket2.NewSocketService(
+		dao.NewUserDockerfileDao(conf.DB),
+		dao.NewUserDockerDao(conf.DB),
+		dao.NewUserGithubDao(conf.DB),
+		dao.NewUserK8sResourceDao(conf.DB),
+		dao.NewUserOssDao(conf.DB),
+		dao.NewUserK8sResourceOperationLogDao(conf.DB),
+		dao.NewUserK8sResourceDriftDao(conf.DB),
+		dao.NewUsersDao(conf.DB),
+		k8sClusterService,
+		k8sNamespaceService,
+		k8sResourceService,
+		ossService)
+	// 定时比较平台部署的资源与线上对象
+	socketService.StartDriftDetector()
+
 	websocketHandler := websocket.NewSocketDockerHandler(
-		websocket2.NewSocketService(
-			dao.NewUserDockerfileDao(conf.DB),
-			dao.NewUserDockerDao(conf.DB),
-			dao.NewUserGithubDao(conf.DB),
-			dao.NewUserK8sResourceDao(conf.DB),
-			dao.NewUserOssDao(conf.DB),
-			dao.NewUserK8sResourceOperationLogDao(conf.DB)),
+		socketService,
 		docker_manage.NewDockerImageService(
 			dao.NewUserDockerImageDao(conf.DB), dao.NewUsersDao(conf.DB)),
 		user_manage.NewDockerAccountService(
@@ -42,6 +58,7 @@ func SetupRouter(r *gin.Engine) {
 
 	r.GET(conf.WSServer.Path, middleware.WsAuthMiddleware(), websocketHandler.HandleWebSocketDockerBuild)
 	r.GET(conf.WSServer.PathK8s, middleware.WsAuthMiddleware(), websocketHandler.HandleWebSocketK8s)
+	r.GET(conf.WSServer.PathK8sExec, middleware.WsAuthMiddleware(), websocketHandler.HandleWebSocketK8sExec)
 
 	// 注册路由
 	// 登陆注册路由组
@@ -103,19 +120,46 @@ func SetupRouter(r *gin.Engine) {
 	}
 
 	// k8s 资源管理
-	k8sResourceHandler := NewK8sResourceHandler(k8s_manage.NewK8sResourceService(dao.NewUserK8sResourceDao(conf.DB)))
-	k8sResourceOperationLogHandler := NewK8sResourceOperationLogHandler(k8s_manage.NewK8sResourceOperationLogService(dao.NewUserK8sResourceOperationLogDao(conf.DB)))
+	k8sResourceHandler := NewK8sResourceHandler(k8sResourceService)
+	k8sClusterHandler := NewK8sClusterHandler(k8sClusterService)
+	k8sNamespaceHandler := NewK8sNamespaceHandler(k8sNamespaceService)
+	k8sResourceOperationLogHandler := NewK8sResourceOperationLogHandler(k8s_manage.NewK8sResourceOperationLogService(dao.NewUserK8sResourceDao(conf.DB), dao.NewUserK8sResourceOperationLogDao(conf.DB), dao.NewUserK8sResourceUsageDao(conf.DB), dao.NewUserK8sResourceEventDao(conf.DB)))
+	k8sResourceExportHandler := NewK8sResourceExportHandler(k8s_manage.NewK8sResourceExportService(dao.NewUserK8sResourceDao(conf.DB), dao.NewUserK8sResourceOperationLogDao(conf.DB), k8sResourceService))
 	k8s := r.Group("/api/user/k8s", middleware.CustomAuthMiddleware())
 	{
 		k8s.POST("/resource/save", k8sResourceHandler.SaveResource)
 		k8s.POST("/resource/update", k8sResourceHandler.UpdateResource)
+		k8s.POST("/resource/validate", k8sResourceHandler.ValidateResource)
 		k8s.GET("/resource/query", k8sResourceHandler.QueryResources)
 		k8s.POST("/resource/delete", k8sResourceHandler.DeleteResource)
+		k8s.GET("/resource/version/query", k8sResourceHandler.QueryResourceVersions)
+		k8s.POST("/resource/variables/save", k8sResourceHandler.SaveResourceVariables)
+		k8s.GET("/resource/variables/query", k8sResourceHandler.QueryResourceVariables)
+		k8s.POST("/resource/variables/delete", k8sResourceHandler.DeleteResourceVariables)
+		k8s.POST("/resource/autoscaler/save", k8sResourceHandler.SaveResourceAutoscaler)
+		k8s.GET("/resource/autoscaler/query", k8sResourceHandler.QueryResourceAutoscaler)
+		k8s.POST("/resource/autoscaler/delete", k8sResourceHandler.DeleteResourceAutoscaler)
 		k8s.GET("/resource/operation/log/query", k8sResourceOperationLogHandler.QueryOperationLogs)
+		k8s.GET("/resource/usage/query", k8sResourceOperationLogHandler.QueryResourceUsage)
+		k8s.GET("/resource/events/query", k8sResourceOperationLogHandler.QueryResourceEvents)
+		k8s.GET("/resource/export", k8sResourceExportHandler.ExportResources) // 导出仓库下的资源，用于灾备和迁移集群
+
+		// 集群注册管理
+		k8s.POST("/cluster/save", k8sClusterHandler.SaveCluster)
+		k8s.GET("/cluster/query", k8sClusterHandler.QueryClusters)
+		k8s.POST("/cluster/delete", k8sClusterHandler.DeleteCluster)
+		k8s.POST("/cluster/check", k8sClusterHandler.CheckCluster)
+
+		// 团队 namespace 管理
+		k8s.POST("/namespace/save", k8sNamespaceHandler.SaveNamespace)
+		k8s.GET("/namespace/query", k8sNamespaceHandler.QueryNamespaces)
+		k8s.POST("/namespace/delete", k8sNamespaceHandler.DeleteNamespace)
+		k8s.POST("/namespace/sync", k8sNamespaceHandler.SyncNamespace)
+		k8s.POST("/namespace/settings/update", k8sNamespaceHandler.UpdateSettings)
 	}
 
 	// OSS 访问信息管理
-	ossHandler := NewOssHandler(oss_manage.NewOssService(dao.NewUserOssDao(conf.DB)))
+	ossHandler := NewOssHandler(ossService)
 	oss := r.Group("/api/user/oss", middleware.CustomAuthMiddleware())
 	{
 		oss.POST("/access/save", ossHandler.SaveOssAccess)
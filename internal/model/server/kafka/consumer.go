@@ -10,12 +10,11 @@ import (
 
 	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
-	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/ZZGADA/easy-deploy/internal/config"
 	"github.com/ZZGADA/easy-deploy/internal/model/conf"
 	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
 	"github.com/ZZGADA/easy-deploy/internal/utils"
 )
 
@@ -154,7 +153,7 @@ func processMessage(logMsg LogMessage) {
 	}
 
 	// 获取Pod创建者信息
-	userID, err := getPodCreator(logMsg.Kubernetes.PodName, logMsg.Kubernetes.NamespaceName)
+	userID, err := getPodCreator(logMsg.Kubernetes.PodName, logMsg.Kubernetes.NamespaceName, logMsg.Kubernetes.Labels)
 	if err != nil {
 		logrus.Errorf("获取Pod创建者失败: %v", err)
 		return
@@ -208,26 +207,19 @@ func processMessage(logMsg LogMessage) {
 	alertChannel <- alertMsgCreator
 }
 
-// getPodCreator 获取Pod创建者信息
-func getPodCreator(podName, namespace string) (uint, error) {
-	// 1. 获取Pod的元数据名称
-	pod, err := conf.KubeClient.CoreV1().Pods(namespace).Get(context.Background(), podName, metav1.GetOptions{})
-	if err != nil {
-		return 0, fmt.Errorf("获取Pod信息失败: %v", err)
+// getPodCreator 获取Pod创建者信息。日志来自多个集群且不携带集群信息，无法回查 Pod，
+// 只依据 fluentd 随日志采集的 Pod 标签：平台部署时在 Pod 模板上写入了资源 ID 标签
+func getPodCreator(podName, namespace string, podLabels map[string]string) (uint, error) {
+	// 1. 从日志携带的 Pod 标签中读取资源 ID
+	resourceID, ok := k8s_manage.ResourceIDFromLabels(podLabels)
+	if !ok {
+		return 0, fmt.Errorf("Pod %s/%s 不属于平台管理的资源", namespace, podName)
 	}
 
-	logrus.Infof("kafka消费者, getPodCreator, 获取Pod信息成功, pod: %s", pod.ObjectMeta.Name)
-
-	// 2. 获取Pod所属的控制器名称
-	controllerName := getControllerName(pod)
-	if controllerName == "" {
-		return 0, fmt.Errorf("无法确定Pod所属的控制器")
-	}
+	logrus.Infof("kafka消费者, getPodCreator, Pod所属资源: %d", resourceID)
 
-	logrus.Infof("kafka消费者, getPodCreator, Pod所属控制器: %s", controllerName)
-
-	// 3. 从操作日志中查找创建者
-	logs, err := operationLogDao.QueryByNamespaceAndMetadataName(namespace, controllerName)
+	// 2. 资源最新的操作日志记录了当前版本的部署者
+	logs, err := operationLogDao.QueryByK8sResourceIDFirst(uint(resourceID))
 	if err != nil {
 		return 0, fmt.Errorf("查询操作日志失败: %v", err)
 	}
@@ -235,51 +227,11 @@ func getPodCreator(podName, namespace string) (uint, error) {
 	if len(logs) == 0 {
 		return 0, fmt.Errorf("未找到Pod创建者信息")
 	}
+	// 资源必须部署在日志所在的 namespace，避免其他团队伪造标签
+	if logs[0].Namespace != namespace {
+		return 0, fmt.Errorf("资源 %d 未部署在 namespace %s", resourceID, namespace)
+	}
 
 	// 返回创建者ID
 	return logs[0].UserID, nil
 }
-
-// getControllerName 获取Pod所属的控制器名称
-func getControllerName(pod *corev1.Pod) string {
-	// 检查Pod的OwnerReferences
-	if len(pod.OwnerReferences) > 0 {
-		owner := pod.OwnerReferences[0]
-
-		// 根据控制器类型返回不同的名称
-		switch owner.Kind {
-		case "ReplicaSet":
-			// 如果是ReplicaSet，需要进一步查找其所属的Deployment
-			rs, err := conf.KubeClient.AppsV1().ReplicaSets(pod.Namespace).Get(context.Background(), owner.Name, metav1.GetOptions{})
-			if err != nil {
-				logrus.Errorf("获取ReplicaSet失败: %v", err)
-				return owner.Name
-			}
-
-			// 查找ReplicaSet所属的Deployment
-			if len(rs.OwnerReferences) > 0 && rs.OwnerReferences[0].Kind == "Deployment" {
-				return rs.OwnerReferences[0].Name
-			}
-			return owner.Name
-
-		case "StatefulSet":
-			return owner.Name
-
-		case "DaemonSet":
-			return owner.Name
-
-		case "Job":
-			return owner.Name
-
-		case "CronJob":
-			return owner.Name
-
-		default:
-			// 对于其他类型的控制器，直接返回名称
-			return owner.Name
-		}
-	}
-
-	// 如果没有OwnerReferences，则使用Pod的名称
-	return pod.Name
-}
@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/gin-gonic/gin"
+)
+
+// HandleWebSocketK8sExec 处理容器交互式终端的 WebSocket 连接
+func (s *SocketDockerHandler) HandleWebSocketK8sExec(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	namespace := c.DefaultQuery("namespace", "default")
+	podName := c.Query("pod_name")
+	container := c.Query("container")
+	command := c.DefaultQuery("command", "/bin/sh")
+	if podName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pod_name is required"})
+		return
+	}
+	clusterID, err := strconv.ParseUint(c.DefaultQuery("cluster_id", "0"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cluster_id"})
+		return
+	}
+
+	// 升级连接前先校验权限
+	pod, clients, err := s.socketService.CheckPodAccess(userID, uint32(clusterID), namespace, podName)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 将HTTP连接升级为WebSocket连接
+	conn, err := conf.WSUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v\n", err)
+		return
+	}
+
+	// 清理连接
+	defer func() {
+		conn.Close()
+		conf.WSServer.ReleaseConn(conn)
+	}()
+
+	s.socketService.HandlePodExec(conn, clients, pod, container, strings.Fields(command))
+}
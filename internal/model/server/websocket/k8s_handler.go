@@ -27,22 +27,23 @@ func (s *SocketDockerHandler) HandleWebSocketK8s(c *gin.Context) {
 		return
 	}
 
-	ossClient, err := s.socketService.GetOssClient(userID)
-	if err != nil {
+	// 连接时只检查 OSS 配置，读取文件时再按用户配置创建客户端
+	if _, err := s.socketService.GetOssClient(userID); err != nil {
 		logrus.Warnf("get ossClient error: %v\n", err)
 		websocket2.SendError(conn, err.Error())
 	}
 
 	// 存储连接
 	conf.WSServer.Connections[userID] = conn
-	conf.WSServer.OssClient[userID] = ossClient
 
 	// 清理连接
 	defer func() {
+		// 停止该用户仍在推送的日志流
+		s.socketService.StopStreams(userID)
 		websocket2.SendSuccess(conn, "ws close", "ws close success")
 		conn.Close()
+		conf.WSServer.ReleaseConn(conn)
 		delete(conf.WSServer.Connections, userID)
-		delete(conf.WSServer.OssClient, userID)
 	}()
 
 	// 处理消息
@@ -79,6 +80,13 @@ func (s *SocketDockerHandler) HandleWebSocketK8s(c *gin.Context) {
 		case "connected":
 			logrus.Info("")
 			s.socketService.HandleKubeCommand(conn, wsMsg.Command, wsMsg.Data, userID)
+		case "stop":
+			// 停止日志等流式推送
+			s.socketService.StopStreams(userID)
+			websocket2.SendSuccess(conn, "stream stopped", websocket2.K8sCommandResponse{
+				Command: wsMsg.Command,
+				Result:  "stream stopped",
+			})
 		case "close":
 			break
 		default:
@@ -0,0 +1,255 @@
+package k8s_manage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"gorm.io/gorm"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	autoscalingv2apply "k8s.io/client-go/applyconfigurations/autoscaling/v2"
+)
+
+const (
+	// AutoscalerManagedByLabel 平台创建的 HPA 带有该标签，删除时只清理平台自己创建的 HPA
+	AutoscalerManagedByLabel = "app.kubernetes.io/managed-by"
+	// maxStabilizationSeconds k8s 允许的稳定窗口上限
+	maxStabilizationSeconds = 3600
+)
+
+// SaveAutoscaler 保存 Deployment 资源的扩缩容策略，资源已部署时立即同步到集群，否则在下一次 apply 时同步
+func (s *K8sResourceService) SaveAutoscaler(userID uint, autoscaler *dao.UserK8sResourceAutoscaler) error {
+	if err := s.checkResourceAccess(userID, autoscaler.K8sResourceID); err != nil {
+		return err
+	}
+	resource, err := s.userK8sResourceDao.QueryById(autoscaler.K8sResourceID)
+	if err != nil {
+		return fmt.Errorf("查询资源失败: %v", err)
+	}
+	if resource.ResourceType != "deployment" {
+		return fmt.Errorf("只有 deployment 类型的资源可以配置自动扩缩容")
+	}
+	if err := ValidateAutoscaler(autoscaler); err != nil {
+		return err
+	}
+
+	autoscaler.UserID = uint32(userID)
+	if err := s.userK8sResourceAutoscalerDao.Save(autoscaler); err != nil {
+		return err
+	}
+	return s.syncDeployedAutoscaler(&resource)
+}
+
+// QueryResourceAutoscaler 查询用户本人或团队成员资源的扩缩容策略，未配置时返回 nil
+func (s *K8sResourceService) QueryResourceAutoscaler(userID uint, k8sResourceID uint32) (*dao.UserK8sResourceAutoscaler, error) {
+	if err := s.checkResourceAccess(userID, k8sResourceID); err != nil {
+		return nil, err
+	}
+	return s.QueryAutoscaler(k8sResourceID)
+}
+
+// QueryAutoscaler 查询资源的扩缩容策略，未配置时返回 nil
+func (s *K8sResourceService) QueryAutoscaler(k8sResourceID uint32) (*dao.UserK8sResourceAutoscaler, error) {
+	autoscaler, err := s.userK8sResourceAutoscalerDao.QueryByResourceID(k8sResourceID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return autoscaler, nil
+}
+
+// DeleteAutoscaler 删除资源的扩缩容策略，资源已部署时立即删除平台创建的 HPA
+func (s *K8sResourceService) DeleteAutoscaler(userID uint, k8sResourceID uint32) error {
+	if err := s.checkResourceAccess(userID, k8sResourceID); err != nil {
+		return err
+	}
+	resource, err := s.userK8sResourceDao.QueryById(k8sResourceID)
+	if err != nil {
+		return fmt.Errorf("查询资源失败: %v", err)
+	}
+	if err := s.userK8sResourceAutoscalerDao.DeleteByResourceID(k8sResourceID); err != nil {
+		return err
+	}
+	return s.syncDeployedAutoscaler(&resource)
+}
+
+// syncDeployedAutoscaler 资源当前已部署时把扩缩容策略同步到集群，未部署或已关闭时等下一次 apply 再同步
+func (s *K8sResourceService) syncDeployedAutoscaler(resource *dao.UserK8sResource) error {
+	logs, err := s.userK8sResourceOperationLogDao.QueryByK8sResourceIDFirst(uint(resource.Id))
+	if err != nil {
+		return fmt.Errorf("查询资源操作日志失败: %v", err)
+	}
+	if len(logs) == 0 || logs[0].OperationType == "delete" {
+		return nil
+	}
+
+	clients, err := conf.GetK8sClients(resource.ClusterID)
+	if err != nil {
+		return fmt.Errorf("策略已保存，连接集群失败: %v", err)
+	}
+	if _, err := s.SyncAutoscaler(context.TODO(), clients, resource.Id, logs[0].Namespace, logs[0].MetadataName); err != nil {
+		return fmt.Errorf("策略已保存，同步 HPA 失败: %v", err)
+	}
+	return nil
+}
+
+// SyncAutoscaler 按资源的扩缩容策略创建或更新同名 HPA，未配置策略时删除平台创建的 HPA，返回 kubectl 风格的结果
+func (s *K8sResourceService) SyncAutoscaler(ctx context.Context, clients *conf.K8sClients, k8sResourceID uint32, namespace string, deploymentName string) (string, error) {
+	autoscaler, err := s.QueryAutoscaler(k8sResourceID)
+	if err != nil {
+		return "", fmt.Errorf("查询扩缩容策略失败: %v", err)
+	}
+
+	if autoscaler == nil {
+		deleted, err := DeleteManagedAutoscaler(ctx, clients, namespace, deploymentName)
+		if err != nil {
+			return "", err
+		}
+		if deleted {
+			return fmt.Sprintf("horizontalpodautoscaler.autoscaling/%s deleted", deploymentName), nil
+		}
+		return "未配置扩缩容策略", nil
+	}
+
+	hpa := BuildAutoscalerApply(autoscaler, namespace, deploymentName)
+	_, err = clients.Client.AutoscalingV2().HorizontalPodAutoscalers(namespace).Apply(ctx, hpa, metav1.ApplyOptions{
+		FieldManager: define.K8sFieldManager,
+		Force:        true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("horizontalpodautoscaler.autoscaling/%s apply 失败: %v", deploymentName, err)
+	}
+	return fmt.Sprintf("horizontalpodautoscaler.autoscaling/%s configured (min %d, max %d)",
+		deploymentName, autoscaler.MinReplicas, autoscaler.MaxReplicas), nil
+}
+
+// DeleteManagedAutoscaler 删除平台创建的同名 HPA，团队手写的 HPA 不受影响
+func DeleteManagedAutoscaler(ctx context.Context, clients *conf.K8sClients, namespace string, deploymentName string) (bool, error) {
+	hpas := clients.Client.AutoscalingV2().HorizontalPodAutoscalers(namespace)
+	hpa, err := hpas.Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("查询 HPA %s 失败: %v", deploymentName, err)
+	}
+	if hpa.Labels[AutoscalerManagedByLabel] != define.K8sFieldManager {
+		return false, nil
+	}
+	if err := hpas.Delete(ctx, deploymentName, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		return false, fmt.Errorf("删除 HPA %s 失败: %v", deploymentName, err)
+	}
+	return true, nil
+}
+
+// KeepLiveReplicas 资源配置了扩缩容策略时，把 Deployment 的 spec.replicas 设置为线上当前的副本数。
+// 不能直接去掉该字段：easy-deploy 是 spec.replicas 唯一的 apply 管理者时，server-side apply 会删除该字段，
+// Deployment 回到默认的 1 个副本；带上线上的值既不会覆盖 HPA 调整后的副本数，也不会让字段失去管理者。
+// Deployment 还不存在时保留清单中的副本数，由 HPA 接管后续调整
+func (s *K8sResourceService) KeepLiveReplicas(ctx context.Context, clients *conf.K8sClients, k8sResourceID uint32, namespace string, primary *unstructured.Unstructured) error {
+	if primary == nil || primary.GetKind() != "Deployment" {
+		return nil
+	}
+	autoscaler, err := s.QueryAutoscaler(k8sResourceID)
+	if err != nil {
+		return fmt.Errorf("查询扩缩容策略失败: %v", err)
+	}
+	if autoscaler == nil {
+		return nil
+	}
+
+	if primary.GetNamespace() != "" {
+		namespace = primary.GetNamespace()
+	}
+	live, err := clients.Client.AppsV1().Deployments(namespace).Get(ctx, primary.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("查询 Deployment %s 失败: %v", primary.GetName(), err)
+	}
+	if live.Spec.Replicas == nil {
+		return nil
+	}
+	return unstructured.SetNestedField(primary.Object, int64(*live.Spec.Replicas), "spec", "replicas")
+}
+
+// ValidateAutoscaler 校验扩缩容策略
+func ValidateAutoscaler(autoscaler *dao.UserK8sResourceAutoscaler) error {
+	if autoscaler.MinReplicas < 1 {
+		return fmt.Errorf("min_replicas 不能小于 1")
+	}
+	if autoscaler.MaxReplicas < autoscaler.MinReplicas {
+		return fmt.Errorf("max_replicas 不能小于 min_replicas")
+	}
+	if autoscaler.TargetCPUUtilization == 0 && autoscaler.TargetMemoryUtilization == 0 {
+		return fmt.Errorf("至少需要设置 CPU 或内存的使用率目标")
+	}
+	if autoscaler.TargetCPUUtilization < 0 || autoscaler.TargetMemoryUtilization < 0 {
+		return fmt.Errorf("使用率目标不能为负数")
+	}
+	for name, seconds := range map[string]*int32{
+		"scale_up_stabilization_seconds":   autoscaler.ScaleUpStabilizationSeconds,
+		"scale_down_stabilization_seconds": autoscaler.ScaleDownStabilizationSeconds,
+	} {
+		if seconds != nil && (*seconds < 0 || *seconds > maxStabilizationSeconds) {
+			return fmt.Errorf("%s 必须在 0 到 %d 之间", name, maxStabilizationSeconds)
+		}
+	}
+	return nil
+}
+
+// BuildAutoscalerApply 根据扩缩容策略生成 HPA 的 server-side apply 配置，HPA 与 Deployment 同名
+func BuildAutoscalerApply(autoscaler *dao.UserK8sResourceAutoscaler, namespace string, deploymentName string) *autoscalingv2apply.HorizontalPodAutoscalerApplyConfiguration {
+	spec := autoscalingv2apply.HorizontalPodAutoscalerSpec().
+		WithScaleTargetRef(autoscalingv2apply.CrossVersionObjectReference().
+			WithAPIVersion("apps/v1").
+			WithKind("Deployment").
+			WithName(deploymentName)).
+		WithMinReplicas(autoscaler.MinReplicas).
+		WithMaxReplicas(autoscaler.MaxReplicas)
+
+	// 按固定顺序生成 metrics，避免重复 apply 时列表顺序变化
+	targets := []struct {
+		name        v1.ResourceName
+		utilization int32
+	}{
+		{v1.ResourceCPU, autoscaler.TargetCPUUtilization},
+		{v1.ResourceMemory, autoscaler.TargetMemoryUtilization},
+	}
+	for _, target := range targets {
+		if target.utilization == 0 {
+			continue
+		}
+		spec.WithMetrics(autoscalingv2apply.MetricSpec().
+			WithType(autoscalingv2.ResourceMetricSourceType).
+			WithResource(autoscalingv2apply.ResourceMetricSource().
+				WithName(target.name).
+				WithTarget(autoscalingv2apply.MetricTarget().
+					WithType(autoscalingv2.UtilizationMetricType).
+					WithAverageUtilization(target.utilization))))
+	}
+
+	if autoscaler.ScaleUpStabilizationSeconds != nil || autoscaler.ScaleDownStabilizationSeconds != nil {
+		behavior := autoscalingv2apply.HorizontalPodAutoscalerBehavior()
+		if autoscaler.ScaleUpStabilizationSeconds != nil {
+			behavior.WithScaleUp(autoscalingv2apply.HPAScalingRules().WithStabilizationWindowSeconds(*autoscaler.ScaleUpStabilizationSeconds))
+		}
+		if autoscaler.ScaleDownStabilizationSeconds != nil {
+			behavior.WithScaleDown(autoscalingv2apply.HPAScalingRules().WithStabilizationWindowSeconds(*autoscaler.ScaleDownStabilizationSeconds))
+		}
+		spec.WithBehavior(behavior)
+	}
+
+	return autoscalingv2apply.HorizontalPodAutoscaler(deploymentName, namespace).
+		WithLabels(map[string]string{AutoscalerManagedByLabel: define.K8sFieldManager}).
+		WithSpec(spec)
+}
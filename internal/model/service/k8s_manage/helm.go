@@ -0,0 +1,139 @@
+package k8s_manage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/config"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/version"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// HelmRelease helm 类型资源保存在 OSS 中的 release 描述
+//
+//	release: redis
+//	namespace: team-a
+//	chart: https://bucket.oss-cn-hangzhou.aliyuncs.com/charts/redis-17.0.0.tgz  # OSS 上的 chart 包
+//	# 或者 chart: redis + repo: https://charts.bitnami.com/bitnami            # chart 仓库中的 chart
+//	version: 17.0.0
+//	values:
+//	  replica:
+//	    replicaCount: 2
+//
+// chart 只能是 OSS 上的 chart 包，或者 chart 名称加上 k8s.helm_repositories 中配置的仓库。
+// helm 使用 namespace 中 team-deployer 的权限安装，ClusterRole、ClusterRoleBinding、IngressClass、
+// CRD 等集群级对象以及 namespace 内的 Role / RoleBinding 都无法创建，chart 需要在 values 中关闭，
+// 例如 ingress-nginx 的 rbac.create=false、controller.ingressClassResource.enabled=false，redis 的 rbac.create=false
+type HelmRelease struct {
+	Release   string                 `json:"release"`
+	Namespace string                 `json:"namespace"`
+	Chart     string                 `json:"chart"`
+	Repo      string                 `json:"repo,omitempty"`    // chart 仓库地址，目录下需要有 index.yaml
+	Version   string                 `json:"version,omitempty"` // chart 版本，不填为仓库中的最新版本
+	Values    map[string]interface{} `json:"values,omitempty"`
+}
+
+// HelmMinVersion helm 类型的资源依赖服务器 PATH 中的 helm 命令行，install / upgrade / history 使用 -o json 输出
+const HelmMinVersion = "v3.8.0"
+
+// CheckHelmBinary 检查 helm 命令行是否已安装且不低于 HelmMinVersion，启动时调用
+func CheckHelmBinary() error {
+	path, err := exec.LookPath("helm")
+	if err != nil {
+		return fmt.Errorf("未找到 helm 命令行，helm 类型的资源无法部署，请安装 helm %s 及以上版本", HelmMinVersion)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, "version", "--template", "{{ .Version }}").Output()
+	if err != nil {
+		return fmt.Errorf("执行 %s version 失败: %v", path, err)
+	}
+	current, err := version.ParseSemantic(strings.TrimSpace(string(output)))
+	if err != nil {
+		return fmt.Errorf("无法识别 helm 版本 %q: %v", strings.TrimSpace(string(output)), err)
+	}
+	if !current.AtLeast(version.MustParseSemantic(HelmMinVersion)) {
+		return fmt.Errorf("helm 版本 %s 低于最低要求 %s", current, HelmMinVersion)
+	}
+	return nil
+}
+
+// helmReleaseNameMaxLength helm 对 release 名称的长度限制
+const helmReleaseNameMaxLength = 53
+
+// helmChartNamePattern 仓库中的 chart 名称，不允许带仓库别名或路径
+var helmChartNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// ParseHelmRelease 解析并校验 helm release 描述
+func ParseHelmRelease(content []byte) (*HelmRelease, error) {
+	var release HelmRelease
+	if err := yamlutil.UnmarshalStrict(content, &release); err != nil {
+		return nil, fmt.Errorf("解析 helm release 描述失败: %v", err)
+	}
+
+	if release.Release == "" {
+		return nil, fmt.Errorf("helm release 描述缺少 release")
+	}
+	if len(release.Release) > helmReleaseNameMaxLength {
+		return nil, fmt.Errorf("release 名称 %s 超过 %d 个字符", release.Release, helmReleaseNameMaxLength)
+	}
+	if errs := validation.IsDNS1123Subdomain(release.Release); len(errs) > 0 {
+		return nil, fmt.Errorf("release 名称 %s 不合法: %s", release.Release, strings.Join(errs, "; "))
+	}
+	if release.Chart == "" {
+		return nil, fmt.Errorf("helm release 描述缺少 chart")
+	}
+	// chart、repo、version 都会作为 helm 的参数，不能被解析成选项或者本地路径
+	if strings.HasPrefix(release.Chart, "-") || strings.HasPrefix(release.Chart, "/") || strings.Contains(release.Chart, "..") {
+		return nil, fmt.Errorf("chart %s 不合法", release.Chart)
+	}
+	if strings.HasPrefix(release.Version, "-") {
+		return nil, fmt.Errorf("chart 版本 %s 不合法", release.Version)
+	}
+	if release.IsOssChart() {
+		if release.Repo != "" {
+			return nil, fmt.Errorf("OSS 上的 chart 包不需要指定 repo")
+		}
+	} else {
+		if !helmChartNamePattern.MatchString(release.Chart) {
+			return nil, fmt.Errorf("chart %s 只能是 OSS 上的 .tgz 包，或者仓库中的 chart 名称", release.Chart)
+		}
+		if release.Repo == "" {
+			return nil, fmt.Errorf("chart %s 需要指定 repo，或者使用 OSS 上的 chart 包", release.Chart)
+		}
+		if !allowedHelmRepository(release.Repo) {
+			return nil, fmt.Errorf("chart 仓库 %s 不在允许的仓库列表中", release.Repo)
+		}
+	}
+	if release.Namespace == "" {
+		release.Namespace = "default"
+	}
+	return &release, nil
+}
+
+// IsOssChart chart 是否为保存在 OSS 上的 chart 包，需要用用户的 OSS 凭证下载
+func (r *HelmRelease) IsOssChart() bool {
+	u, err := url.Parse(r.Chart)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "https" && strings.HasSuffix(u.Hostname(), ".aliyuncs.com") && strings.HasSuffix(u.Path, ".tgz")
+}
+
+// allowedHelmRepository repo 是否在配置的 chart 仓库白名单中，忽略结尾的 /
+func allowedHelmRepository(repo string) bool {
+	repo = strings.TrimSuffix(repo, "/")
+	for _, allowed := range config.GlobalConfig.K8s.HelmRepositories {
+		if repo == strings.TrimSuffix(allowed, "/") {
+			return true
+		}
+	}
+	return false
+}
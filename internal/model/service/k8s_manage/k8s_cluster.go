@@ -0,0 +1,243 @@
+package k8s_manage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ZZGADA/easy-deploy/internal/config"
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/utils"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// K8sClusterService K8s 集群注册管理
+type K8sClusterService struct {
+	userK8sClusterDao *dao.UserK8sClusterDao
+	usersDao          *dao.UsersDao
+}
+
+func NewK8sClusterService(userK8sClusterDao *dao.UserK8sClusterDao, usersDao *dao.UsersDao) *K8sClusterService {
+	return &K8sClusterService{
+		userK8sClusterDao: userK8sClusterDao,
+		usersDao:          usersDao,
+	}
+}
+
+// SaveCluster 注册集群：凭证加密后入库，并立即做一次健康检查。只有 insecure 为 true 时才允许跳过 API Server 证书校验
+func (s *K8sClusterService) SaveCluster(userID uint, name string, authType string, apiServer string, credential string, caData string, insecure bool, shareToTeam bool) (*dao.UserK8sCluster, error) {
+	if authType != define.K8sClusterAuthKubeconfig && authType != define.K8sClusterAuthToken {
+		return nil, fmt.Errorf("不支持的认证方式: %s", authType)
+	}
+	if authType == define.K8sClusterAuthToken && apiServer == "" {
+		return nil, errors.New("token 认证方式需要填写 api_server")
+	}
+
+	// 入库前先确认凭证可以解析，记录实际是否跳过了证书校验
+	restConfig, err := buildRestConfig(authType, apiServer, credential, caData, insecure)
+	if err != nil {
+		return nil, err
+	}
+	insecure = restConfig.TLSClientConfig.Insecure
+
+	secretKey := config.GlobalConfig.K8s.ClusterSecretKey
+	encryptedCredential, err := utils.EncryptString(credential, secretKey)
+	if err != nil {
+		return nil, fmt.Errorf("加密集群凭证失败: %v", err)
+	}
+	encryptedCAData := ""
+	if caData != "" {
+		encryptedCAData, err = utils.EncryptString(caData, secretKey)
+		if err != nil {
+			return nil, fmt.Errorf("加密 CA 证书失败: %v", err)
+		}
+	}
+
+	cluster := &dao.UserK8sCluster{
+		UserID:     uint32(userID),
+		Name:       name,
+		ApiServer:  apiServer,
+		AuthType:   authType,
+		Credential: encryptedCredential,
+		CAData:     encryptedCAData,
+		Insecure:   insecure,
+		Status:     define.K8sClusterStatusUnknown,
+	}
+	if shareToTeam {
+		user, err := s.usersDao.GetUserByID(uint32(userID))
+		if err != nil {
+			return nil, err
+		}
+		cluster.TeamID = user.TeamID
+	}
+
+	if err := s.userK8sClusterDao.Create(cluster); err != nil {
+		return nil, err
+	}
+
+	s.CheckHealth(cluster.Id)
+	return s.userK8sClusterDao.QueryById(cluster.Id)
+}
+
+// QueryClusters 查询用户可用的集群
+func (s *K8sClusterService) QueryClusters(userID uint) ([]dao.UserK8sCluster, error) {
+	user, err := s.usersDao.GetUserByID(uint32(userID))
+	if err != nil {
+		return nil, err
+	}
+	return s.userK8sClusterDao.QueryByUserOrTeam(uint32(userID), user.TeamID)
+}
+
+// DeleteCluster 删除集群，只有注册者可以删除
+func (s *K8sClusterService) DeleteCluster(userID uint, clusterID uint32) error {
+	cluster, err := s.userK8sClusterDao.QueryById(clusterID)
+	if err != nil {
+		return err
+	}
+	if cluster.UserID != uint32(userID) {
+		return errors.New("只有集群注册者可以删除集群")
+	}
+
+	if err := s.userK8sClusterDao.Delete(clusterID); err != nil {
+		return err
+	}
+	conf.EvictK8sClients(clusterID)
+	return nil
+}
+
+// CanAccess 用户是否可以使用该集群：默认集群所有人可用，注册集群限注册者及其团队
+func (s *K8sClusterService) CanAccess(userID uint, clusterID uint32) error {
+	if clusterID == conf.DefaultClusterID {
+		return nil
+	}
+
+	cluster, err := s.userK8sClusterDao.QueryById(clusterID)
+	if err != nil {
+		return fmt.Errorf("集群 %d 不存在", clusterID)
+	}
+	if cluster.UserID == uint32(userID) {
+		return nil
+	}
+
+	user, err := s.usersDao.GetUserByID(uint32(userID))
+	if err != nil {
+		return err
+	}
+	if cluster.TeamID != 0 && cluster.TeamID == user.TeamID {
+		return nil
+	}
+	return fmt.Errorf("无权使用集群 %d", clusterID)
+}
+
+// CheckHealth 检查集群连通性并记录版本
+func (s *K8sClusterService) CheckHealth(clusterID uint32) (*dao.UserK8sCluster, error) {
+	// 强制重建客户端，凭证或网络变化后可以恢复
+	conf.EvictK8sClients(clusterID)
+	clients, err := conf.GetK8sClients(clusterID)
+	if err != nil {
+		_ = s.userK8sClusterDao.UpdateHealth(clusterID, define.K8sClusterStatusUnhealthy, "", err.Error())
+		return s.userK8sClusterDao.QueryById(clusterID)
+	}
+
+	version, err := clients.Client.Discovery().ServerVersion()
+	if err != nil {
+		conf.EvictK8sClients(clusterID)
+		_ = s.userK8sClusterDao.UpdateHealth(clusterID, define.K8sClusterStatusUnhealthy, "", err.Error())
+	} else {
+		message := ""
+		if cluster, err := s.userK8sClusterDao.QueryById(clusterID); err == nil && cluster.Insecure {
+			message = "已跳过 API Server 证书校验（insecure），建议补充 CA 证书"
+		}
+		_ = s.userK8sClusterDao.UpdateHealth(clusterID, define.K8sClusterStatusHealthy, version.GitVersion, message)
+	}
+	return s.userK8sClusterDao.QueryById(clusterID)
+}
+
+// LoadRestConfig 解密已注册集群的凭证并构建连接配置，注入给 conf.K8sClusterConfigLoader
+func (s *K8sClusterService) LoadRestConfig(clusterID uint32) (*rest.Config, error) {
+	cluster, err := s.userK8sClusterDao.QueryById(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	secretKey := config.GlobalConfig.K8s.ClusterSecretKey
+	credential, err := utils.DecryptString(cluster.Credential, secretKey)
+	if err != nil {
+		return nil, err
+	}
+	caData := ""
+	if cluster.CAData != "" {
+		caData, err = utils.DecryptString(cluster.CAData, secretKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buildRestConfig(cluster.AuthType, cluster.ApiServer, credential, caData, cluster.Insecure)
+}
+
+// buildRestConfig 根据认证方式构建连接配置
+func buildRestConfig(authType string, apiServer string, credential string, caData string, insecure bool) (*rest.Config, error) {
+	switch authType {
+	case define.K8sClusterAuthKubeconfig:
+		kubeconfig, err := clientcmd.Load([]byte(credential))
+		if err != nil {
+			return nil, fmt.Errorf("解析 kubeconfig 失败: %v", err)
+		}
+		if err := validateKubeconfig(kubeconfig, insecure); err != nil {
+			return nil, err
+		}
+		restConfig, err := clientcmd.NewDefaultClientConfig(*kubeconfig, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("解析 kubeconfig 失败: %v", err)
+		}
+		if apiServer != "" {
+			restConfig.Host = apiServer
+		}
+		return restConfig, nil
+	case define.K8sClusterAuthToken:
+		restConfig := &rest.Config{
+			Host:        apiServer,
+			BearerToken: credential,
+		}
+		if caData != "" {
+			restConfig.TLSClientConfig.CAData = []byte(caData)
+		} else if insecure {
+			restConfig.TLSClientConfig.Insecure = true
+		} else {
+			return nil, errors.New("token 认证方式需要填写 ca_data，确实无法提供 CA 证书时请显式开启 insecure")
+		}
+		return restConfig, nil
+	default:
+		return nil, fmt.Errorf("不支持的认证方式: %s", authType)
+	}
+}
+
+// validateKubeconfig 上传的 kubeconfig 只能使用内联的凭证和证书：exec、auth-provider 会在平台主机上执行命令，
+// tokenFile 和证书文件路径会读取平台主机上的文件
+func validateKubeconfig(kubeconfig *clientcmdapi.Config, insecure bool) error {
+	for name, authInfo := range kubeconfig.AuthInfos {
+		switch {
+		case authInfo.Exec != nil:
+			return fmt.Errorf("kubeconfig 用户 %s 使用了 exec，请改用 token 或内联的 client-certificate-data", name)
+		case authInfo.AuthProvider != nil:
+			return fmt.Errorf("kubeconfig 用户 %s 使用了 auth-provider，请改用 token 或内联的 client-certificate-data", name)
+		case authInfo.TokenFile != "":
+			return fmt.Errorf("kubeconfig 用户 %s 使用了 tokenFile，请改用 token", name)
+		case authInfo.ClientCertificate != "" || authInfo.ClientKey != "":
+			return fmt.Errorf("kubeconfig 用户 %s 使用了证书文件路径，请改用 client-certificate-data 和 client-key-data", name)
+		}
+	}
+	for name, cluster := range kubeconfig.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return fmt.Errorf("kubeconfig 集群 %s 使用了 CA 文件路径，请改用 certificate-authority-data", name)
+		}
+		if cluster.InsecureSkipTLSVerify && !insecure {
+			return fmt.Errorf("kubeconfig 集群 %s 跳过了证书校验，请改用 certificate-authority-data 或显式开启 insecure", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package k8s_manage
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// EventFirstSeen 事件首次出现的时间，events.k8s.io 写入的事件只有 eventTime
+func EventFirstSeen(event *v1.Event) time.Time {
+	if !event.FirstTimestamp.IsZero() {
+		return event.FirstTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// EventLastSeen 事件最后出现的时间，重复事件以 series 记录时取 lastObservedTime
+func EventLastSeen(event *v1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if event.Series != nil && !event.Series.LastObservedTime.IsZero() {
+		return event.Series.LastObservedTime.Time
+	}
+	return EventFirstSeen(event)
+}
@@ -1,33 +1,59 @@
 package k8s_manage
 
 import (
+	"fmt"
+
 	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/oss_manage"
 )
 
 type K8sResourceService struct {
-	userK8sResourceDao *dao.UserK8sResourceDao
+	userK8sResourceDao             *dao.UserK8sResourceDao
+	userK8sResourceVariableDao     *dao.UserK8sResourceVariableDao
+	userK8sResourceAutoscalerDao   *dao.UserK8sResourceAutoscalerDao
+	userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao
+	userDockerImageDao             *dao.UserDockerImageDao
+	k8sClusterService              *K8sClusterService
+	k8sNamespaceService            *K8sNamespaceService
+	ossService                     *oss_manage.OssService
 }
 
-func NewK8sResourceService(userK8sResourceDao *dao.UserK8sResourceDao) *K8sResourceService {
+func NewK8sResourceService(userK8sResourceDao *dao.UserK8sResourceDao, userK8sResourceVariableDao *dao.UserK8sResourceVariableDao, userK8sResourceAutoscalerDao *dao.UserK8sResourceAutoscalerDao, userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao, userDockerImageDao *dao.UserDockerImageDao, k8sClusterService *K8sClusterService, k8sNamespaceService *K8sNamespaceService, ossService *oss_manage.OssService) *K8sResourceService {
 	return &K8sResourceService{
-		userK8sResourceDao: userK8sResourceDao,
+		userK8sResourceDao:             userK8sResourceDao,
+		userK8sResourceVariableDao:     userK8sResourceVariableDao,
+		userK8sResourceAutoscalerDao:   userK8sResourceAutoscalerDao,
+		userK8sResourceOperationLogDao: userK8sResourceOperationLogDao,
+		userDockerImageDao:             userDockerImageDao,
+		k8sClusterService:              k8sClusterService,
+		k8sNamespaceService:            k8sNamespaceService,
+		ossService:                     ossService,
 	}
 }
 
 // SaveResource 保存 K8s 资源配置
-func (s *K8sResourceService) SaveResource(userID uint, repositoryID string, resourceType string, ossURL string, fileName string) error {
+func (s *K8sResourceService) SaveResource(userID uint, repositoryID string, resourceType string, ossURL string, fileName string, clusterID uint32) error {
+	if err := s.k8sClusterService.CanAccess(userID, clusterID); err != nil {
+		return err
+	}
+
 	resource := &dao.UserK8sResource{
 		UserID:       uint32(userID),
 		RepositoryID: repositoryID,
 		ResourceType: resourceType,
 		OssURL:       ossURL,
 		FileName:     fileName,
+		ClusterID:    clusterID,
 	}
 	return s.userK8sResourceDao.Create(resource)
 }
 
 // UpdateResource 保存 K8s 资源配置
-func (s *K8sResourceService) UpdateResource(userID uint, id uint32, repositoryID string, resourceType string, ossURL string, fileName string) error {
+func (s *K8sResourceService) UpdateResource(userID uint, id uint32, repositoryID string, resourceType string, ossURL string, fileName string, clusterID uint32) error {
+	if err := s.k8sClusterService.CanAccess(userID, clusterID); err != nil {
+		return err
+	}
+
 	resourceById, err := s.userK8sResourceDao.QueryById(id)
 	if err != nil {
 		return err
@@ -53,6 +79,7 @@ func (s *K8sResourceService) UpdateResource(userID uint, id uint32, repositoryID
 		OssURL:           ossURL,
 		FileName:         fileName,
 		FatherResourceId: resourceById.Id,
+		ClusterID:        clusterID,
 	}
 
 	err = s.userK8sResourceDao.CreateTx(tx, resource)
@@ -60,6 +87,19 @@ func (s *K8sResourceService) UpdateResource(userID uint, id uint32, repositoryID
 		tx.Rollback()
 		return err
 	}
+
+	// 新版本沿用旧版本的模板变量集
+	err = s.userK8sResourceVariableDao.CopyToResourceTx(tx, resourceById.Id, resource.Id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	// 新版本沿用旧版本的扩缩容策略
+	err = s.userK8sResourceAutoscalerDao.CopyToResourceTx(tx, resourceById.Id, resource.Id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		return err
@@ -84,12 +124,56 @@ func (s *K8sResourceService) QueryResources(repositoryID string, resourceType st
 
 }
 
+// checkResourceAccess 校验资源属于用户本人或用户所在团队的成员，变量集等可能包含凭证的配置只对他们可见
+func (s *K8sResourceService) checkResourceAccess(userID uint, k8sResourceID uint32) error {
+	resource, err := s.userK8sResourceDao.QueryById(k8sResourceID)
+	if err != nil {
+		return fmt.Errorf("查询资源失败: %v", err)
+	}
+	return checkResourceOwner(userID, &resource)
+}
+
+// CheckResourceOwner 校验已查询到的资源属于用户本人或用户所在团队的成员，供 websocket 命令在操作资源前校验
+func (s *K8sResourceService) CheckResourceOwner(userID uint, resource *dao.UserK8sResource) error {
+	return checkResourceOwner(userID, resource)
+}
+
+// checkResourceOwner 校验已查询到的资源属于用户本人或用户所在团队的成员
+func checkResourceOwner(userID uint, resource *dao.UserK8sResource) error {
+	if resource.UserID == uint32(userID) {
+		return nil
+	}
+
+	user, err := dao.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("获取用户信息失败: %v", err)
+	}
+	owner, err := dao.GetUserByID(uint(resource.UserID))
+	if err != nil {
+		return fmt.Errorf("获取资源创建者信息失败: %v", err)
+	}
+	if user.TeamID != 0 && user.TeamID == owner.TeamID {
+		return nil
+	}
+	return fmt.Errorf("无权操作资源 %d", resource.Id)
+}
+
+// QueryVersionHistory 查询 K8s 资源的版本历史，从新到旧排列
+func (s *K8sResourceService) QueryVersionHistory(id uint32) ([]dao.UserK8sResource, error) {
+	return s.userK8sResourceDao.QueryVersionChain(id)
+}
+
 // ValidateResourceType 验证资源类型是否有效
 func (s *K8sResourceService) ValidateResourceType(resourceType string) bool {
 	validTypes := map[string]bool{
-		"deployment": true,
-		"service":    true,
-		"ingress":    true,
+		"deployment":  true,
+		"service":     true,
+		"ingress":     true,
+		"statefulset": true,
+		"daemonset":   true,
+		"job":         true,
+		"cronjob":     true,
+		"helm":        true,
 	}
 	return validTypes[resourceType]
 }
@@ -0,0 +1,121 @@
+package k8s_manage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// metrics-server 提供的 metrics.k8s.io 资源，通过 dynamic 客户端读取
+var (
+	podMetricsGVR  = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+	nodeMetricsGVR = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "nodes"}
+)
+
+// ResourceUsage CPU 和内存用量
+type ResourceUsage struct {
+	CPU    resource.Quantity `json:"cpu"`
+	Memory resource.Quantity `json:"memory"`
+}
+
+// Add 累加用量
+func (u *ResourceUsage) Add(other ResourceUsage) {
+	u.CPU.Add(other.CPU)
+	u.Memory.Add(other.Memory)
+}
+
+// PodMetrics 单个 Pod 的用量，为全部容器之和
+type PodMetrics struct {
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace"`
+	Timestamp time.Time     `json:"timestamp"`
+	Usage     ResourceUsage `json:"usage"`
+}
+
+// NodeMetrics 单个节点的用量
+type NodeMetrics struct {
+	Name      string        `json:"name"`
+	Timestamp time.Time     `json:"timestamp"`
+	Usage     ResourceUsage `json:"usage"`
+}
+
+// ListPodMetrics 查询 Pod 的用量，namespace 为空时查询全部 namespace
+func ListPodMetrics(ctx context.Context, clients *conf.K8sClients, namespace string, labelSelector string) ([]PodMetrics, error) {
+	list, err := clients.Dynamic.Resource(podMetricsGVR).Namespace(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("查询 Pod 用量失败，请确认集群已安装 metrics-server: %v", err)
+	}
+
+	metrics := make([]PodMetrics, 0, len(list.Items))
+	for _, item := range list.Items {
+		podMetrics := PodMetrics{Name: item.GetName(), Namespace: item.GetNamespace(), Timestamp: metricsTimestamp(item)}
+		containers, _, _ := unstructured.NestedSlice(item.Object, "containers")
+		for _, container := range containers {
+			containerMap, ok := container.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			usage, err := parseUsage(containerMap)
+			if err != nil {
+				return nil, fmt.Errorf("解析 Pod %s 用量失败: %v", item.GetName(), err)
+			}
+			podMetrics.Usage.Add(usage)
+		}
+		metrics = append(metrics, podMetrics)
+	}
+	return metrics, nil
+}
+
+// ListNodeMetrics 查询节点的用量
+func ListNodeMetrics(ctx context.Context, clients *conf.K8sClients) ([]NodeMetrics, error) {
+	list, err := clients.Dynamic.Resource(nodeMetricsGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("查询节点用量失败，请确认集群已安装 metrics-server: %v", err)
+	}
+
+	metrics := make([]NodeMetrics, 0, len(list.Items))
+	for _, item := range list.Items {
+		usage, err := parseUsage(item.Object)
+		if err != nil {
+			return nil, fmt.Errorf("解析节点 %s 用量失败: %v", item.GetName(), err)
+		}
+		metrics = append(metrics, NodeMetrics{Name: item.GetName(), Timestamp: metricsTimestamp(item), Usage: usage})
+	}
+	return metrics, nil
+}
+
+// parseUsage 解析对象中的 usage.cpu 和 usage.memory
+func parseUsage(object map[string]interface{}) (ResourceUsage, error) {
+	var usage ResourceUsage
+	usageMap, _, _ := unstructured.NestedStringMap(object, "usage")
+	if cpu, ok := usageMap["cpu"]; ok {
+		quantity, err := resource.ParseQuantity(cpu)
+		if err != nil {
+			return usage, err
+		}
+		usage.CPU = quantity
+	}
+	if memory, ok := usageMap["memory"]; ok {
+		quantity, err := resource.ParseQuantity(memory)
+		if err != nil {
+			return usage, err
+		}
+		usage.Memory = quantity
+	}
+	return usage, nil
+}
+
+func metricsTimestamp(item unstructured.Unstructured) time.Time {
+	timestamp, _, _ := unstructured.NestedString(item.Object, "timestamp")
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return time.Now()
+	}
+	return parsed
+}
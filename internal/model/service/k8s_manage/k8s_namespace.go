@@ -0,0 +1,394 @@
+package k8s_manage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	rbacv1ac "k8s.io/client-go/applyconfigurations/rbac/v1"
+)
+
+// K8sNamespaceSettings 团队 namespace 的配额设置
+type K8sNamespaceSettings struct {
+	CPUQuota          string `json:"k8s_cpu_quota"`
+	MemoryQuota       string `json:"k8s_memory_quota"`
+	PodQuota          string `json:"k8s_pod_quota"`
+	DefaultCPULimit   string `json:"k8s_default_cpu_limit"`
+	DefaultMemLimit   string `json:"k8s_default_mem_limit"`
+	DefaultCPURequest string `json:"k8s_default_cpu_request"`
+	DefaultMemRequest string `json:"k8s_default_mem_request"`
+}
+
+// K8sNamespaceService 团队 namespace 管理：创建 namespace 并下发 RBAC、ResourceQuota、LimitRange
+type K8sNamespaceService struct {
+	teamK8sNamespaceDao *dao.TeamK8sNamespaceDao
+	teamDao             *dao.TeamDao
+	usersDao            *dao.UsersDao
+	k8sClusterService   *K8sClusterService
+}
+
+func NewK8sNamespaceService(teamK8sNamespaceDao *dao.TeamK8sNamespaceDao, teamDao *dao.TeamDao, usersDao *dao.UsersDao, k8sClusterService *K8sClusterService) *K8sNamespaceService {
+	return &K8sNamespaceService{
+		teamK8sNamespaceDao: teamK8sNamespaceDao,
+		teamDao:             teamDao,
+		usersDao:            usersDao,
+		k8sClusterService:   k8sClusterService,
+	}
+}
+
+// CreateNamespace 为用户所在团队创建受管 namespace，只有团队创建者可以操作
+func (s *K8sNamespaceService) CreateNamespace(userID uint, clusterID uint32, namespace string) (*dao.TeamK8sNamespace, error) {
+	team, err := s.creatorTeam(userID)
+	if err != nil {
+		return nil, err
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return nil, fmt.Errorf("namespace 名称不合法: %s", strings.Join(errs, "; "))
+	}
+	if namespace == "default" || strings.HasPrefix(namespace, "kube-") {
+		return nil, fmt.Errorf("namespace %s 为系统保留名称", namespace)
+	}
+	if err := s.k8sClusterService.CanAccess(userID, clusterID); err != nil {
+		return nil, err
+	}
+
+	owned, err := s.teamK8sNamespaceDao.QueryByClusterAndNamespace(clusterID, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(owned) > 0 {
+		return nil, fmt.Errorf("namespace %s 已被团队 %d 使用", namespace, owned[0].TeamID)
+	}
+
+	clients, err := conf.GetK8sClients(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 集群中已存在且不属于本团队的 namespace 不允许接管
+	live, err := clients.Client.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err == nil && live.Labels[define.K8sLabelTeamID] != strconv.Itoa(int(team.ID)) {
+		return nil, fmt.Errorf("namespace %s 已存在于集群中，且不属于当前团队", namespace)
+	} else if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("查询 namespace 失败: %v", err)
+	}
+
+	if err := provisionNamespace(clients, team, namespace); err != nil {
+		return nil, err
+	}
+
+	record := &dao.TeamK8sNamespace{
+		TeamID:    team.ID,
+		ClusterID: clusterID,
+		Namespace: namespace,
+		CreatorID: uint32(userID),
+	}
+	if err := s.teamK8sNamespaceDao.Create(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// QueryNamespaces 查询用户所在团队的 namespace
+func (s *K8sNamespaceService) QueryNamespaces(userID uint) ([]dao.TeamK8sNamespace, error) {
+	user, err := s.usersDao.GetUserByID(uint32(userID))
+	if err != nil {
+		return nil, err
+	}
+	if user.TeamID == 0 {
+		return []dao.TeamK8sNamespace{}, nil
+	}
+	return s.teamK8sNamespaceDao.QueryByTeamID(user.TeamID)
+}
+
+// DeleteNamespace 删除团队 namespace，集群中的 namespace 及其中的资源一并删除
+func (s *K8sNamespaceService) DeleteNamespace(userID uint, id uint32) error {
+	team, err := s.creatorTeam(userID)
+	if err != nil {
+		return err
+	}
+	record, err := s.teamK8sNamespaceDao.QueryById(id)
+	if err != nil {
+		return err
+	}
+	if record.TeamID != team.ID {
+		return errors.New("无权删除其他团队的 namespace")
+	}
+
+	clients, err := conf.GetK8sClients(record.ClusterID)
+	if err != nil {
+		return err
+	}
+	err = clients.Client.CoreV1().Namespaces().Delete(context.TODO(), record.Namespace, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("删除 namespace 失败: %v", err)
+	}
+	return s.teamK8sNamespaceDao.Delete(id)
+}
+
+// SyncNamespace 按团队当前设置重新下发 namespace 内的 RBAC 与配额
+func (s *K8sNamespaceService) SyncNamespace(userID uint, id uint32) error {
+	user, err := s.usersDao.GetUserByID(uint32(userID))
+	if err != nil {
+		return err
+	}
+	record, err := s.teamK8sNamespaceDao.QueryById(id)
+	if err != nil {
+		return err
+	}
+	if user.TeamID == 0 || record.TeamID != user.TeamID {
+		return errors.New("无权操作其他团队的 namespace")
+	}
+
+	team, err := s.teamDao.GetByID(context.TODO(), record.TeamID)
+	if err != nil {
+		return err
+	}
+	clients, err := conf.GetK8sClients(record.ClusterID)
+	if err != nil {
+		return err
+	}
+	return provisionNamespace(clients, team, record.Namespace)
+}
+
+// UpdateSettings 更新团队配额设置，并同步到团队的全部 namespace
+func (s *K8sNamespaceService) UpdateSettings(userID uint, settings K8sNamespaceSettings) (*dao.Team, error) {
+	team, err := s.creatorTeam(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	quantities := map[string]string{
+		"k8s_cpu_quota":           settings.CPUQuota,
+		"k8s_memory_quota":        settings.MemoryQuota,
+		"k8s_pod_quota":           settings.PodQuota,
+		"k8s_default_cpu_limit":   settings.DefaultCPULimit,
+		"k8s_default_mem_limit":   settings.DefaultMemLimit,
+		"k8s_default_cpu_request": settings.DefaultCPURequest,
+		"k8s_default_mem_request": settings.DefaultMemRequest,
+	}
+	for field, value := range quantities {
+		if value == "" {
+			continue
+		}
+		if _, err := resource.ParseQuantity(value); err != nil {
+			return nil, fmt.Errorf("%s 格式不正确: %s", field, value)
+		}
+	}
+
+	team.K8sCPUQuota = settings.CPUQuota
+	team.K8sMemoryQuota = settings.MemoryQuota
+	team.K8sPodQuota = settings.PodQuota
+	team.K8sDefaultCPULimit = settings.DefaultCPULimit
+	team.K8sDefaultMemLimit = settings.DefaultMemLimit
+	team.K8sDefaultCPURequest = settings.DefaultCPURequest
+	team.K8sDefaultMemRequest = settings.DefaultMemRequest
+	if err := s.teamDao.Update(context.TODO(), team); err != nil {
+		return nil, err
+	}
+
+	namespaces, err := s.teamK8sNamespaceDao.QueryByTeamID(team.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range namespaces {
+		clients, err := conf.GetK8sClients(record.ClusterID)
+		if err == nil {
+			err = provisionNamespace(clients, team, record.Namespace)
+		}
+		if err != nil {
+			logrus.Errorf("同步团队 %d 的 namespace %s 失败: %v", team.ID, record.Namespace, err)
+		}
+	}
+	return team, nil
+}
+
+// CheckNamespaceAccess 校验 namespace 是否为用户所在团队在该集群中的受管 namespace
+func (s *K8sNamespaceService) CheckNamespaceAccess(userID uint, clusterID uint32, namespace string) error {
+	user, err := s.usersDao.GetUserByID(uint32(userID))
+	if err != nil {
+		return err
+	}
+	if user.TeamID == 0 {
+		return fmt.Errorf("用户未加入任何团队，无法部署到 namespace %s", namespace)
+	}
+
+	owned, err := s.teamK8sNamespaceDao.QueryByClusterAndNamespace(clusterID, namespace)
+	if err != nil {
+		return err
+	}
+	for _, record := range owned {
+		if record.TeamID == user.TeamID {
+			return nil
+		}
+	}
+	return fmt.Errorf("namespace %s 不属于当前团队，请先在团队 namespace 管理中创建", namespace)
+}
+
+// TeamRole 用户在所在团队中的角色：creator / member，未加入团队时为 none
+func (s *K8sNamespaceService) TeamRole(userID uint) (string, error) {
+	user, err := s.usersDao.GetUserByID(uint32(userID))
+	if err != nil {
+		return "", err
+	}
+	if user.TeamID == 0 {
+		return define.TeamRoleNone, nil
+	}
+	team, err := s.teamDao.GetByID(context.TODO(), user.TeamID)
+	if err != nil {
+		return define.TeamRoleNone, nil
+	}
+	if team.CreatorID == uint32(userID) {
+		return define.TeamRoleCreator, nil
+	}
+	return define.TeamRoleMember, nil
+}
+
+// creatorTeam 获取用户作为创建者的团队
+func (s *K8sNamespaceService) creatorTeam(userID uint) (*dao.Team, error) {
+	user, err := s.usersDao.GetUserByID(uint32(userID))
+	if err != nil {
+		return nil, err
+	}
+	if user.TeamID == 0 {
+		return nil, errors.New("用户未加入任何团队")
+	}
+	team, err := s.teamDao.GetByID(context.TODO(), user.TeamID)
+	if err != nil {
+		return nil, errors.New("团队不存在")
+	}
+	if team.CreatorID != uint32(userID) {
+		return nil, errors.New("只有团队创建者可以管理团队 namespace")
+	}
+	return team, nil
+}
+
+// teamCoreResources 团队 Role 在 core 组中可以管理的资源，不包含 resourcequotas、limitranges 和 namespaces
+var teamCoreResources = []string{
+	"pods", "pods/log", "pods/exec", "pods/portforward", "pods/attach",
+	"services", "endpoints", "configmaps", "secrets", "persistentvolumeclaims",
+	"serviceaccounts", "replicationcontrollers", "events",
+}
+
+// provisionNamespace 使用 server-side apply 下发 namespace 及其 ServiceAccount、Role、RoleBinding、ResourceQuota、LimitRange，可重复执行
+func provisionNamespace(clients *conf.K8sClients, team *dao.Team, namespace string) error {
+	ctx := context.TODO()
+	opts := metav1.ApplyOptions{FieldManager: define.K8sFieldManager, Force: true}
+	labels := map[string]string{
+		define.K8sLabelTeamID:  strconv.Itoa(int(team.ID)),
+		define.K8sLabelManaged: "true",
+	}
+	name := define.K8sTeamServiceAccount
+
+	if _, err := clients.Client.CoreV1().Namespaces().Apply(ctx,
+		corev1ac.Namespace(namespace).WithLabels(labels), opts); err != nil {
+		return fmt.Errorf("创建 namespace 失败: %v", err)
+	}
+
+	if _, err := clients.Client.CoreV1().ServiceAccounts(namespace).Apply(ctx,
+		corev1ac.ServiceAccount(name, namespace).WithLabels(labels), opts); err != nil {
+		return fmt.Errorf("创建 ServiceAccount 失败: %v", err)
+	}
+
+	// 团队成员在自己的 namespace 内拥有常用资源的全部权限；core 组逐个列出，
+	// 配额和默认限制由平台下发，团队只能查看，不能自行调整或删除。
+	// PodDisruptionBudget 是常见 chart（redis、ingress-nginx 等）自带的 namespace 级对象，一并放开；
+	// ClusterRole、IngressClass、CRD 等集群级对象和 namespace 内的 RBAC 仍然不开放
+	role := rbacv1ac.Role(name, namespace).WithLabels(labels).WithRules(
+		rbacv1ac.PolicyRule().
+			WithAPIGroups("").
+			WithResources(teamCoreResources...).
+			WithVerbs("*"),
+		rbacv1ac.PolicyRule().
+			WithAPIGroups("").
+			WithResources("resourcequotas", "limitranges").
+			WithVerbs("get", "list", "watch"),
+		rbacv1ac.PolicyRule().
+			WithAPIGroups("apps", "batch", "autoscaling", "networking.k8s.io").
+			WithResources("*").
+			WithVerbs("*"),
+		rbacv1ac.PolicyRule().
+			WithAPIGroups("policy").
+			WithResources("poddisruptionbudgets").
+			WithVerbs("*"),
+	)
+	if _, err := clients.Client.RbacV1().Roles(namespace).Apply(ctx, role, opts); err != nil {
+		return fmt.Errorf("创建 Role 失败: %v", err)
+	}
+
+	roleBinding := rbacv1ac.RoleBinding(name, namespace).WithLabels(labels).
+		WithSubjects(rbacv1ac.Subject().WithKind("ServiceAccount").WithName(name).WithNamespace(namespace)).
+		WithRoleRef(rbacv1ac.RoleRef().WithAPIGroup("rbac.authorization.k8s.io").WithKind("Role").WithName(name))
+	if _, err := clients.Client.RbacV1().RoleBindings(namespace).Apply(ctx, roleBinding, opts); err != nil {
+		return fmt.Errorf("创建 RoleBinding 失败: %v", err)
+	}
+
+	// 配额为空时删除已有的 ResourceQuota
+	hard := resourceList(map[v1.ResourceName]string{
+		v1.ResourceLimitsCPU:    team.K8sCPUQuota,
+		v1.ResourceLimitsMemory: team.K8sMemoryQuota,
+		v1.ResourcePods:         team.K8sPodQuota,
+	})
+	if len(hard) > 0 {
+		quota := corev1ac.ResourceQuota(define.K8sTeamResourceQuota, namespace).WithLabels(labels).
+			WithSpec(corev1ac.ResourceQuotaSpec().WithHard(hard))
+		if _, err := clients.Client.CoreV1().ResourceQuotas(namespace).Apply(ctx, quota, opts); err != nil {
+			return fmt.Errorf("创建 ResourceQuota 失败: %v", err)
+		}
+	} else if err := clients.Client.CoreV1().ResourceQuotas(namespace).Delete(ctx, define.K8sTeamResourceQuota, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("删除 ResourceQuota 失败: %v", err)
+	}
+
+	// 未声明 resources 的容器使用团队的默认 limit / request
+	defaults := resourceList(map[v1.ResourceName]string{
+		v1.ResourceCPU:    team.K8sDefaultCPULimit,
+		v1.ResourceMemory: team.K8sDefaultMemLimit,
+	})
+	defaultRequests := resourceList(map[v1.ResourceName]string{
+		v1.ResourceCPU:    team.K8sDefaultCPURequest,
+		v1.ResourceMemory: team.K8sDefaultMemRequest,
+	})
+	if len(defaults) > 0 || len(defaultRequests) > 0 {
+		item := corev1ac.LimitRangeItem().WithType(v1.LimitTypeContainer)
+		if len(defaults) > 0 {
+			item = item.WithDefault(defaults)
+		}
+		if len(defaultRequests) > 0 {
+			item = item.WithDefaultRequest(defaultRequests)
+		}
+		limitRange := corev1ac.LimitRange(define.K8sTeamLimitRange, namespace).WithLabels(labels).
+			WithSpec(corev1ac.LimitRangeSpec().WithLimits(item))
+		if _, err := clients.Client.CoreV1().LimitRanges(namespace).Apply(ctx, limitRange, opts); err != nil {
+			return fmt.Errorf("创建 LimitRange 失败: %v", err)
+		}
+	} else if err := clients.Client.CoreV1().LimitRanges(namespace).Delete(ctx, define.K8sTeamLimitRange, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("删除 LimitRange 失败: %v", err)
+	}
+	return nil
+}
+
+// resourceList 将非空的设置值转换为 ResourceList，设置值在保存时已校验
+func resourceList(values map[v1.ResourceName]string) v1.ResourceList {
+	list := v1.ResourceList{}
+	for name, value := range values {
+		if value == "" {
+			continue
+		}
+		if quantity, err := resource.ParseQuantity(value); err == nil {
+			list[name] = quantity
+		}
+	}
+	return list
+}
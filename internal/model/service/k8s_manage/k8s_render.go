@@ -0,0 +1,170 @@
+package k8s_manage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// AppliedOperationTypes 会把资源某个版本完整 apply 到集群的操作，最近一次决定线上运行的版本和模板选项
+var AppliedOperationTypes = []string{"create", "update", "rollback", "reconcile"}
+
+// RenderOptions apply 时选择的模板变量：Environment 对应资源的环境变量集，DockerImageID 对应替换 {{ .Image }} 的镜像，
+// Overlay 对应 kustomization 压缩包中 overlays 下的目录
+type RenderOptions struct {
+	Environment   string
+	DockerImageID uint32
+	Overlay       string
+	Replicas      *int32 // 重新 apply 当前版本时保留平台 scale 之后的 Deployment 副本数
+}
+
+// String 记录到操作日志命令中的模板选项
+func (o RenderOptions) String() string {
+	var options []string
+	if o.Environment != "" {
+		options = append(options, fmt.Sprintf("--env=%s", o.Environment))
+	}
+	if o.DockerImageID != 0 {
+		options = append(options, fmt.Sprintf("--docker-image-id=%d", o.DockerImageID))
+	}
+	if o.Overlay != "" {
+		options = append(options, fmt.Sprintf("--overlay=%s", o.Overlay))
+	}
+	if o.Replicas != nil {
+		options = append(options, fmt.Sprintf("--replicas=%d", *o.Replicas))
+	}
+	return strings.Join(options, " ")
+}
+
+// DeployedState 资源当前部署的版本、apply 选项和部署者
+type DeployedState struct {
+	Resource *dao.UserK8sResource // 部署记录所在的版本，保存新版本但尚未 apply 时为之前部署的版本
+	Version  *dao.UserK8sResource // 线上运行的版本，回滚后为回滚的目标版本
+	Options  RenderOptions
+	UserID   uint
+}
+
+// RenderedResource 资源某个版本渲染的结果，helm 资源只有文件内容，没有对象
+type RenderedResource struct {
+	Source    []byte // OSS 中保存的原始文件
+	Content   []byte // 渲染后的文件内容
+	Objects   []*unstructured.Unstructured
+	Namespace string // 主对象的 namespace，为空时为 "default"
+}
+
+// DeployedState 沿版本链找到最近一个 apply 过的版本，按它最近一次 apply 类操作确定资源当前部署的内容：
+// 回滚后为回滚的目标版本，之后通过平台 scale 过的 Deployment 保留 scale 后的副本数；资源尚未部署时返回 nil
+func (s *K8sResourceService) DeployedState(resource *dao.UserK8sResource) (*DeployedState, error) {
+	versions, err := s.userK8sResourceDao.QueryVersionChain(resource.Id)
+	if err != nil {
+		return nil, fmt.Errorf("查询资源版本失败: %v", err)
+	}
+
+	var applied *dao.UserK8sResourceOperationLog
+	var deployed *dao.UserK8sResource
+	for i := range versions {
+		applied, err = s.userK8sResourceOperationLogDao.QueryLatestByOperationTypes(uint(versions[i].Id), AppliedOperationTypes)
+		if err != nil {
+			return nil, fmt.Errorf("查询操作日志失败: %v", err)
+		}
+		if applied != nil {
+			deployed = &versions[i]
+			break
+		}
+	}
+	if applied == nil {
+		return nil, nil
+	}
+
+	state := &DeployedState{
+		Resource: deployed,
+		Version:  deployed,
+		Options:  RenderOptions{Environment: applied.Environment, DockerImageID: applied.DockerImageID, Overlay: applied.Overlay},
+		UserID:   applied.UserID,
+	}
+	if applied.TargetResourceID != 0 && uint32(applied.TargetResourceID) != deployed.Id {
+		version, err := s.userK8sResourceDao.QueryById(uint32(applied.TargetResourceID))
+		if err != nil {
+			return nil, fmt.Errorf("查询版本 %d 失败: %v", applied.TargetResourceID, err)
+		}
+		state.Version = &version
+	}
+
+	scaled, err := s.userK8sResourceOperationLogDao.QueryLatestByOperationTypes(uint(deployed.Id), []string{"scale"})
+	if err != nil {
+		return nil, fmt.Errorf("查询操作日志失败: %v", err)
+	}
+	if scaled != nil && scaled.ID > applied.ID && scaled.ReplicasAfter != nil {
+		state.Options.Replicas = scaled.ReplicasAfter
+	}
+	return state, nil
+}
+
+// RenderResource 读取资源某个版本的文件并按 apply 的方式渲染：模板使用该版本的变量集和选择的镜像渲染，
+// kustomization 压缩包渲染选择的 overlay，拆分后写入 resourceID 和 userID 的归属标签，并设置保留的副本数。
+// resourceID 为对象归属的资源，回滚时与实际渲染的历史版本 version 不同；读取文件之后的步骤失败时仍返回原始文件
+func (s *K8sResourceService) RenderResource(userID uint, resourceID uint32, version *dao.UserK8sResource, options RenderOptions) (*RenderedResource, error) {
+	source, err := s.ossService.GetObject(userID, version.OssURL)
+	if err != nil {
+		return nil, err
+	}
+	rendered := &RenderedResource{Source: source, Content: source}
+
+	// kustomization 压缩包在内存中解压，渲染选择的 overlay
+	if IsKustomizeArchive(version.FileName) {
+		values, err := s.TemplateValues(userID, version.Id, options.Environment, options.DockerImageID)
+		if err != nil {
+			return rendered, err
+		}
+		layout, err := LoadKustomizeArchive(source, version.FileName, values)
+		if err != nil {
+			return rendered, err
+		}
+		if rendered.Content, err = layout.Render(options.Overlay); err != nil {
+			return rendered, err
+		}
+	} else if options.Overlay != "" {
+		return rendered, fmt.Errorf("资源 %s 不是 kustomization 压缩包，不能指定 overlay", version.FileName)
+	} else if IsTemplate(source) {
+		values, err := s.TemplateValues(userID, version.Id, options.Environment, options.DockerImageID)
+		if err != nil {
+			return rendered, err
+		}
+		if rendered.Content, err = RenderManifest(source, values); err != nil {
+			return rendered, err
+		}
+	}
+	if version.ResourceType == "helm" {
+		return rendered, nil
+	}
+
+	// 拆分多文档 YAML
+	objects, err := SplitManifest(rendered.Content)
+	if err != nil {
+		return rendered, fmt.Errorf("解析 YAML 文件失败: %v", err)
+	}
+
+	// 主对象的 namespace 作为默认 namespace，为空时使用 "default"
+	primary := PrimaryObject(objects, version.ResourceType)
+	rendered.Namespace = primary.GetNamespace()
+	if rendered.Namespace == "" {
+		rendered.Namespace = "default"
+	}
+
+	// 写入归属标签，Pod 等派生对象通过标签找到所属资源和部署者
+	user, err := dao.GetUserByID(userID)
+	if err != nil {
+		return rendered, fmt.Errorf("获取用户信息失败: %v", err)
+	}
+	StampOwnership(objects, Ownership{ResourceID: resourceID, UserID: userID, TeamID: user.TeamID})
+
+	if options.Replicas != nil && primary != nil && primary.GetKind() == "Deployment" {
+		if err := unstructured.SetNestedField(primary.Object, int64(*options.Replicas), "spec", "replicas"); err != nil {
+			return rendered, fmt.Errorf("设置副本数失败: %v", err)
+		}
+	}
+	rendered.Objects = objects
+	return rendered, nil
+}
@@ -0,0 +1,357 @@
+package k8s_manage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// 导出格式
+const (
+	ExportFormatYAML  = "yaml"
+	ExportFormatTarGz = "tar.gz"
+)
+
+// exportFileNamePattern 导出文件名中只保留的字符
+var exportFileNamePattern = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// jobGeneratedLabels Job 创建时由控制器写入 selector 和 Pod 模板的标签，迁移到其他集群时必须去掉
+var jobGeneratedLabels = []string{
+	"controller-uid",
+	"job-name",
+	"batch.kubernetes.io/controller-uid",
+	"batch.kubernetes.io/job-name",
+}
+
+type K8sResourceExportService struct {
+	userK8sResourceDao             *dao.UserK8sResourceDao
+	userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao
+	k8sResourceService             *K8sResourceService
+}
+
+func NewK8sResourceExportService(userK8sResourceDao *dao.UserK8sResourceDao, userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao, k8sResourceService *K8sResourceService) *K8sResourceExportService {
+	return &K8sResourceExportService{
+		userK8sResourceDao:             userK8sResourceDao,
+		userK8sResourceOperationLogDao: userK8sResourceOperationLogDao,
+		k8sResourceService:             k8sResourceService,
+	}
+}
+
+// ExportBundle 导出的文件
+type ExportBundle struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+// resourceExport 单个资源导出的内容，单个资源失败不影响其他资源，原因记录在 notes 中
+type resourceExport struct {
+	resource dao.UserK8sResource
+	version  *dao.UserK8sResource // 线上运行的版本，回滚后为回滚的目标版本
+	options  string               // 部署时的模板选项
+	source   []byte               // OSS 中保存的原始文件
+	manifest []*unstructured.Unstructured
+	live     []*unstructured.Unstructured
+	notes    []string
+}
+
+// ExportRepository 导出仓库下所有资源当前部署的内容，includeLive 时同时导出去掉运行时字段的线上对象。
+// yaml 格式为一个多文档 YAML，包含线上对象时只包含线上对象；tar.gz 格式按资源分别保存渲染后的 YAML、原始文件和线上对象
+func (s *K8sResourceExportService) ExportRepository(ctx context.Context, userID uint, repositoryID string, format string, includeLive bool) (*ExportBundle, error) {
+	if format == "" {
+		format = ExportFormatYAML
+	}
+	if format != ExportFormatYAML && format != ExportFormatTarGz {
+		return nil, fmt.Errorf("不支持的导出格式 %s，可选 %s、%s", format, ExportFormatYAML, ExportFormatTarGz)
+	}
+
+	resources, err := s.userK8sResourceDao.QueryByRepositoryALL(repositoryID)
+	if err != nil {
+		return nil, fmt.Errorf("查询资源失败: %v", err)
+	}
+	if len(resources) == 0 {
+		return nil, fmt.Errorf("仓库 %s 下没有资源", repositoryID)
+	}
+	// 导出内容包含原始文件和渲染后的变量，只有仓库资源的创建者或其团队成员可以导出
+	for i := range resources {
+		if err := checkResourceOwner(userID, &resources[i]); err != nil {
+			return nil, fmt.Errorf("无权导出仓库 %s: %v", repositoryID, err)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Id < resources[j].Id })
+
+	exports := make([]*resourceExport, 0, len(resources))
+	for _, resource := range resources {
+		export := s.exportResource(userID, resource)
+		if includeLive && export.manifest != nil {
+			s.exportLiveObjects(ctx, userID, export)
+		}
+		exports = append(exports, export)
+	}
+
+	name := "repository-" + exportFileName(repositoryID)
+	if format == ExportFormatTarGz {
+		content, err := exportTarGz(name, exports, includeLive)
+		if err != nil {
+			return nil, err
+		}
+		return &ExportBundle{FileName: name + ".tar.gz", ContentType: "application/gzip", Content: content}, nil
+	}
+
+	content, err := exportYAML(repositoryID, exports, includeLive)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportBundle{FileName: name + ".yaml", ContentType: "application/x-yaml", Content: content}, nil
+}
+
+// exportResource 按资源当前部署的版本和模板选项渲染资源，与部署时的渲染方式相同；尚未部署的资源使用默认变量集渲染。
+// 文件和变量集按导出者的 OSS 配置和权限读取，不使用部署者的凭证
+func (s *K8sResourceExportService) exportResource(userID uint, resource dao.UserK8sResource) *resourceExport {
+	export := &resourceExport{resource: resource, version: &resource}
+
+	state, err := s.k8sResourceService.DeployedState(&resource)
+	if err != nil {
+		export.notes = append(export.notes, err.Error())
+		return export
+	}
+	if state == nil {
+		export.notes = append(export.notes, "资源尚未部署，使用默认变量集渲染")
+		state = &DeployedState{Version: &resource}
+	} else {
+		export.options = state.Options.String()
+	}
+	export.version = state.Version
+
+	rendered, err := s.k8sResourceService.RenderResource(userID, resource.Id, state.Version, state.Options)
+	if rendered != nil {
+		export.source = rendered.Source
+	}
+	if err != nil {
+		export.notes = append(export.notes, err.Error())
+		return export
+	}
+	if resource.ResourceType == "helm" {
+		export.notes = append(export.notes, "helm 类型的资源只导出 release 描述，线上对象由 helm 管理")
+		return export
+	}
+
+	if namespace := PrimaryObject(rendered.Objects, resource.ResourceType).GetNamespace(); namespace != "" {
+		for _, obj := range rendered.Objects {
+			if obj.GetNamespace() == "" {
+				obj.SetNamespace(namespace)
+			}
+		}
+	}
+	export.manifest = rendered.Objects
+	return export
+}
+
+// exportLiveObjects 读取渲染结果中每个对象的线上状态，需要有集群和对象所在 namespace 的权限
+func (s *K8sResourceExportService) exportLiveObjects(ctx context.Context, userID uint, export *resourceExport) {
+	if err := s.k8sResourceService.k8sClusterService.CanAccess(userID, export.resource.ClusterID); err != nil {
+		export.notes = append(export.notes, fmt.Sprintf("无法导出线上对象: %v", err))
+		return
+	}
+	clients, err := conf.GetK8sClients(export.resource.ClusterID)
+	if err != nil {
+		export.notes = append(export.notes, fmt.Sprintf("无法导出线上对象: %v", err))
+		return
+	}
+
+	for _, obj := range export.manifest {
+		target := obj.DeepCopy()
+		ri, _, err := clients.ResourceInterface(target, "default")
+		if err != nil {
+			export.notes = append(export.notes, fmt.Sprintf("%s/%s: %v", obj.GetKind(), obj.GetName(), err))
+			continue
+		}
+		if target.GetNamespace() != "" {
+			if err := s.k8sResourceService.k8sNamespaceService.CheckNamespaceAccess(userID, export.resource.ClusterID, target.GetNamespace()); err != nil {
+				export.notes = append(export.notes, fmt.Sprintf("%s/%s: %v", obj.GetKind(), obj.GetName(), err))
+				continue
+			}
+		}
+
+		live, err := ri.Get(ctx, target.GetName(), metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			export.notes = append(export.notes, fmt.Sprintf("%s/%s 在集群中不存在", obj.GetKind(), obj.GetName()))
+			continue
+		}
+		if err != nil {
+			export.notes = append(export.notes, fmt.Sprintf("获取 %s/%s 失败: %v", obj.GetKind(), obj.GetName(), err))
+			continue
+		}
+		export.live = append(export.live, portableObject(live))
+	}
+}
+
+// portableObject 去掉线上对象的运行时字段，以及集群分配、在其他集群中 apply 会冲突的字段
+func portableObject(live *unstructured.Unstructured) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: StripServerFields(live.Object)}
+	switch obj.GetKind() {
+	case "Service":
+		// headless Service 的 clusterIP: None 是用户声明的
+		if clusterIP, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP"); clusterIP != "None" {
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+		}
+	case "Job":
+		if manual, _, _ := unstructured.NestedBool(obj.Object, "spec", "manualSelector"); !manual {
+			unstructured.RemoveNestedField(obj.Object, "spec", "selector")
+			labels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+			for _, key := range jobGeneratedLabels {
+				delete(labels, key)
+			}
+			_ = unstructured.SetNestedStringMap(obj.Object, labels, "spec", "template", "metadata", "labels")
+		}
+	}
+	return obj
+}
+
+// exportYAML 生成多文档 YAML，每个资源前用注释说明来源，无法导出的资源只保留注释
+func exportYAML(repositoryID string, exports []*resourceExport, includeLive bool) ([]byte, error) {
+	var buffer bytes.Buffer
+	content := "平台部署的版本"
+	if includeLive {
+		content = "线上对象（已去掉运行时字段）"
+	}
+	fmt.Fprintf(&buffer, "# 仓库 %s 的资源导出，共 %d 个资源，内容为%s\n", repositoryID, len(exports), content)
+	fmt.Fprintf(&buffer, "# 导出时间 %s\n", time.Now().Format(time.RFC3339))
+
+	for _, export := range exports {
+		buffer.WriteString("---\n")
+		buffer.WriteString(export.header("# "))
+		objects := export.manifest
+		if includeLive {
+			objects = export.live
+		}
+		document, err := marshalObjects(objects)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(document)
+	}
+	return buffer.Bytes(), nil
+}
+
+// exportTarGz 生成 tar.gz 压缩包，目录结构为：
+//
+//	repository-xxx/manifests/<id>-<文件名>.yaml  平台部署的版本渲染后的 YAML
+//	repository-xxx/sources/<id>/<文件名>         OSS 中保存的原始文件，模板、kustomization 压缩包和 helm release 描述保持原样
+//	repository-xxx/live/<id>-<文件名>.yaml       线上对象，includeLive 时导出
+//	repository-xxx/README.txt                    每个资源的版本、模板选项和导出时遇到的问题
+func exportTarGz(name string, exports []*resourceExport, includeLive bool) ([]byte, error) {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	now := time.Now()
+
+	writeFile := func(filePath string, content []byte) error {
+		header := &tar.Header{Name: path.Join(name, filePath), Mode: 0644, Size: int64(len(content)), ModTime: now}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("写入压缩包失败: %v", err)
+		}
+		if _, err := tarWriter.Write(content); err != nil {
+			return fmt.Errorf("写入压缩包失败: %v", err)
+		}
+		return nil
+	}
+
+	var readme strings.Builder
+	fmt.Fprintf(&readme, "导出时间 %s，共 %d 个资源\n", now.Format(time.RFC3339), len(exports))
+	for _, export := range exports {
+		readme.WriteString("\n")
+		readme.WriteString(export.header(""))
+
+		baseName := exportFileName(strings.TrimSuffix(path.Base(export.version.FileName), path.Ext(export.version.FileName)))
+		fileName := fmt.Sprintf("%d-%s.yaml", export.resource.Id, baseName)
+		if export.source != nil {
+			sourcePath := path.Join("sources", fmt.Sprint(export.resource.Id), exportFileName(path.Base(export.version.FileName)))
+			if err := writeFile(sourcePath, export.source); err != nil {
+				return nil, err
+			}
+		}
+		if export.manifest != nil {
+			content, err := marshalObjects(export.manifest)
+			if err != nil {
+				return nil, err
+			}
+			if err := writeFile(path.Join("manifests", fileName), content); err != nil {
+				return nil, err
+			}
+		}
+		if includeLive && len(export.live) > 0 {
+			content, err := marshalObjects(export.live)
+			if err != nil {
+				return nil, err
+			}
+			if err := writeFile(path.Join("live", fileName), content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := writeFile("README.txt", []byte(readme.String())); err != nil {
+		return nil, err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, fmt.Errorf("写入压缩包失败: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("写入压缩包失败: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// header 资源的说明，每行以 prefix 开头
+func (e *resourceExport) header(prefix string) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%sk8s_resource_id: %d，类型: %s，文件: %s，集群: %d\n", prefix, e.resource.Id, e.resource.ResourceType, e.version.FileName, e.resource.ClusterID)
+	if e.version.Id != e.resource.Id {
+		fmt.Fprintf(&builder, "%s线上运行的是回滚后的版本 %d\n", prefix, e.version.Id)
+	}
+	if e.options != "" {
+		fmt.Fprintf(&builder, "%s部署选项: %s\n", prefix, e.options)
+	}
+	for _, note := range e.notes {
+		fmt.Fprintf(&builder, "%s注意: %s\n", prefix, note)
+	}
+	return builder.String()
+}
+
+// marshalObjects 将对象序列化为多文档 YAML
+func marshalObjects(objects []*unstructured.Unstructured) ([]byte, error) {
+	documents := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		document, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("序列化 %s/%s 失败: %v", obj.GetKind(), obj.GetName(), err)
+		}
+		documents = append(documents, string(document))
+	}
+	return []byte(strings.Join(documents, "---\n")), nil
+}
+
+// exportFileName 替换文件名中不安全的字符
+func exportFileName(name string) string {
+	name = strings.Trim(exportFileNamePattern.ReplaceAllString(name, "_"), "._")
+	if name == "" {
+		return "resource"
+	}
+	return name
+}
@@ -0,0 +1,169 @@
+package k8s_manage
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPortableObject(t *testing.T) {
+	tests := []struct {
+		name string
+		live map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "去掉运行时字段和控制器注解",
+			live: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata": map[string]interface{}{
+					"name":              "web",
+					"namespace":         "team-a",
+					"uid":               "0b7c",
+					"resourceVersion":   "42",
+					"generation":        int64(3),
+					"creationTimestamp": "2026-01-01T00:00:00Z",
+					"managedFields":     []interface{}{map[string]interface{}{"manager": "easy-deploy"}},
+					"annotations": map[string]interface{}{
+						"deployment.kubernetes.io/revision": "3",
+						"team":                              "a",
+					},
+				},
+				"spec":   map[string]interface{}{"replicas": int64(2)},
+				"status": map[string]interface{}{"readyReplicas": int64(2)},
+			},
+			want: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata": map[string]interface{}{
+					"name":        "web",
+					"namespace":   "team-a",
+					"annotations": map[string]interface{}{"team": "a"},
+				},
+				"spec": map[string]interface{}{"replicas": int64(2)},
+			},
+		},
+		{
+			name: "注解全部由控制器写入时去掉 annotations",
+			live: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":        "config",
+					"annotations": map[string]interface{}{"kubectl.kubernetes.io/last-applied-configuration": "{}"},
+				},
+				"data": map[string]interface{}{"key": "value"},
+			},
+			want: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "config"},
+				"data":       map[string]interface{}{"key": "value"},
+			},
+		},
+		{
+			name: "Service 去掉集群分配的 clusterIP",
+			live: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata":   map[string]interface{}{"name": "web"},
+				"spec": map[string]interface{}{
+					"clusterIP":  "10.96.0.12",
+					"clusterIPs": []interface{}{"10.96.0.12"},
+					"ports":      []interface{}{map[string]interface{}{"port": int64(80)}},
+				},
+			},
+			want: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata":   map[string]interface{}{"name": "web"},
+				"spec": map[string]interface{}{
+					"ports": []interface{}{map[string]interface{}{"port": int64(80)}},
+				},
+			},
+		},
+		{
+			name: "headless Service 保留 clusterIP: None",
+			live: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata":   map[string]interface{}{"name": "db"},
+				"spec":       map[string]interface{}{"clusterIP": "None", "clusterIPs": []interface{}{"None"}},
+			},
+			want: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata":   map[string]interface{}{"name": "db"},
+				"spec":       map[string]interface{}{"clusterIP": "None", "clusterIPs": []interface{}{"None"}},
+			},
+		},
+		{
+			name: "Job 去掉控制器生成的 selector 和标签",
+			live: map[string]interface{}{
+				"apiVersion": "batch/v1",
+				"kind":       "Job",
+				"metadata":   map[string]interface{}{"name": "migrate"},
+				"spec": map[string]interface{}{
+					"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"batch.kubernetes.io/controller-uid": "9f1e"}},
+					"template": map[string]interface{}{
+						"metadata": map[string]interface{}{"labels": map[string]interface{}{
+							"app":                                "migrate",
+							"controller-uid":                     "9f1e",
+							"job-name":                           "migrate",
+							"batch.kubernetes.io/controller-uid": "9f1e",
+							"batch.kubernetes.io/job-name":       "migrate",
+						}},
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"apiVersion": "batch/v1",
+				"kind":       "Job",
+				"metadata":   map[string]interface{}{"name": "migrate"},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "migrate"}},
+					},
+				},
+			},
+		},
+		{
+			name: "manualSelector 的 Job 保留 selector",
+			live: map[string]interface{}{
+				"apiVersion": "batch/v1",
+				"kind":       "Job",
+				"metadata":   map[string]interface{}{"name": "migrate"},
+				"spec": map[string]interface{}{
+					"manualSelector": true,
+					"selector":       map[string]interface{}{"matchLabels": map[string]interface{}{"app": "migrate"}},
+				},
+			},
+			want: map[string]interface{}{
+				"apiVersion": "batch/v1",
+				"kind":       "Job",
+				"metadata":   map[string]interface{}{"name": "migrate"},
+				"spec": map[string]interface{}{
+					"manualSelector": true,
+					"selector":       map[string]interface{}{"matchLabels": map[string]interface{}{"app": "migrate"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live := &unstructured.Unstructured{Object: tt.live}
+			original := live.DeepCopy()
+
+			got := portableObject(live)
+			if !reflect.DeepEqual(got.Object, tt.want) {
+				t.Errorf("portableObject() = %v, want %v", got.Object, tt.want)
+			}
+			if !reflect.DeepEqual(live.Object, original.Object) {
+				t.Errorf("portableObject() modified the live object: %v", live.Object)
+			}
+		})
+	}
+}
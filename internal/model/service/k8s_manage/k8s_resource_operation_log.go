@@ -1,18 +1,26 @@
 package k8s_manage
 
 import (
+	"time"
+
 	"github.com/ZZGADA/easy-deploy/internal/model/dao"
 )
 
 // K8sResourceOperationLogService K8s 资源操作日志服务
 type K8sResourceOperationLogService struct {
+	userK8sResourceDao             *dao.UserK8sResourceDao
 	userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao
+	userK8sResourceUsageDao        *dao.UserK8sResourceUsageDao
+	userK8sResourceEventDao        *dao.UserK8sResourceEventDao
 }
 
 // NewK8sResourceOperationLogService 创建 K8s 资源操作日志服务
-func NewK8sResourceOperationLogService(userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao) *K8sResourceOperationLogService {
+func NewK8sResourceOperationLogService(userK8sResourceDao *dao.UserK8sResourceDao, userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao, userK8sResourceUsageDao *dao.UserK8sResourceUsageDao, userK8sResourceEventDao *dao.UserK8sResourceEventDao) *K8sResourceOperationLogService {
 	return &K8sResourceOperationLogService{
+		userK8sResourceDao:             userK8sResourceDao,
 		userK8sResourceOperationLogDao: userK8sResourceOperationLogDao,
+		userK8sResourceUsageDao:        userK8sResourceUsageDao,
+		userK8sResourceEventDao:        userK8sResourceEventDao,
 	}
 }
 
@@ -20,3 +28,22 @@ func NewK8sResourceOperationLogService(userK8sResourceOperationLogDao *dao.UserK
 func (s *K8sResourceOperationLogService) QueryByK8sResourceID(k8sResourceID uint, page, pageSize int) ([]*dao.UserK8sResourceOperationLog, int64, error) {
 	return s.userK8sResourceOperationLogDao.QueryByK8sResourceIDPage(k8sResourceID, page, pageSize)
 }
+
+// QueryUsage 查询 K8s 资源从 since 开始的用量采样，用于在操作日志页绘制用量曲线
+func (s *K8sResourceOperationLogService) QueryUsage(k8sResourceID uint32, since time.Time) ([]dao.UserK8sResourceUsage, error) {
+	// 采样记录在采样时的当前版本上，每次部署新版本后沿版本链一起查询，曲线不会从新版本重新开始
+	versions, err := s.userK8sResourceDao.QueryVersionChain(k8sResourceID)
+	if err != nil {
+		return nil, err
+	}
+	versionIDs := make([]uint32, 0, len(versions))
+	for _, version := range versions {
+		versionIDs = append(versionIDs, version.Id)
+	}
+	return s.userK8sResourceUsageDao.QueryByResourcesSince(versionIDs, since)
+}
+
+// QueryWarningEvents 查询 K8s 资源最近的 Warning 事件，用于定位部署失败的原因
+func (s *K8sResourceOperationLogService) QueryWarningEvents(k8sResourceID uint32, limit int) ([]dao.UserK8sResourceEvent, error) {
+	return s.userK8sResourceEventDao.QueryByResourceID(k8sResourceID, limit)
+}
@@ -0,0 +1,208 @@
+package k8s_manage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// templatePlaceholderImage 校验模板时 Image 的占位值，真正的镜像在 apply 时选择
+const templatePlaceholderImage = "template-image:validate"
+
+// ValidateManifest 读取 OssURL 对应的 YAML，依次做解析、kind 匹配、OpenAPI schema 校验和最佳实践检查；
+// 模板使用 variableResourceID 对应资源的默认变量集渲染，新资源传 0；kustomization 压缩包逐个校验 base 和各 overlay；
+// 用户无权访问 clusterID 时直接返回错误，不会连接集群
+func (s *K8sResourceService) ValidateManifest(userID uint, clusterID uint32, resourceType string, ossURL string, variableResourceID uint32) (*ManifestValidation, error) {
+	if err := s.k8sClusterService.CanAccess(userID, clusterID); err != nil {
+		return nil, err
+	}
+
+	validation := &ManifestValidation{Valid: true, Findings: []ManifestFinding{}}
+
+	content, err := s.ossService.GetObject(userID, ossURL)
+	if err != nil {
+		validation.addFinding(FindingError, "fetch", nil, "", fmt.Sprintf("读取 YAML 文件失败: %v", err))
+		return validation, nil
+	}
+
+	values, err := s.TemplateValues(userID, variableResourceID, DefaultEnvironment, 0)
+	if err != nil {
+		values = make(map[string]interface{})
+	}
+	if _, ok := values["Image"]; !ok {
+		values["Image"] = templatePlaceholderImage
+	}
+
+	if IsKustomizeArchive(ossURL) {
+		s.validateKustomize(validation, userID, clusterID, resourceType, ossURL, content, values)
+		return validation, nil
+	}
+
+	if IsTemplate(content) {
+		rendered, err := RenderManifest(content, values)
+		if err != nil {
+			// 变量可能要到 apply 时才确定，此时无法继续校验
+			validation.addFinding(FindingWarning, "template", nil, "", fmt.Sprintf("模板无法用默认变量集渲染，跳过校验: %v", err))
+			return validation, nil
+		}
+		content = rendered
+	}
+
+	if resourceType == "helm" {
+		s.validateHelmRelease(validation, userID, clusterID, content)
+		return validation, nil
+	}
+
+	s.validateObjects(validation, userID, clusterID, resourceType, content)
+	return validation, nil
+}
+
+// validateKustomize 渲染 base 和每个 overlay 后分别校验，结果中标明所属的 overlay
+func (s *K8sResourceService) validateKustomize(validation *ManifestValidation, userID uint, clusterID uint32, resourceType string, fileName string, archive []byte, values map[string]interface{}) {
+	layout, err := LoadKustomizeArchive(archive, fileName, values)
+	if err != nil {
+		validation.addFinding(FindingError, "kustomize", nil, "", err.Error())
+		return
+	}
+
+	for _, overlay := range append([]string{""}, layout.Overlays...) {
+		start := len(validation.Findings)
+		content, err := layout.Render(overlay)
+		if err != nil {
+			validation.addFinding(FindingError, "kustomize", nil, "", err.Error())
+		} else {
+			s.validateObjects(validation, userID, clusterID, resourceType, content)
+		}
+		for i := start; i < len(validation.Findings); i++ {
+			validation.Findings[i].Overlay = overlay
+		}
+	}
+}
+
+// validateObjects 校验一份渲染完成的 YAML
+func (s *K8sResourceService) validateObjects(validation *ManifestValidation, userID uint, clusterID uint32, resourceType string, content []byte) {
+	objects, err := SplitManifest(content)
+	if err != nil {
+		validation.addFinding(FindingError, "parse", nil, "", err.Error())
+		return
+	}
+	validation.Objects += len(objects)
+
+	// 声明的 resource_type 必须在文件中有对应 kind 的对象
+	primary := PrimaryObject(objects, resourceType)
+	if !strings.EqualFold(primary.GetKind(), resourceType) {
+		validation.addFinding(FindingError, "kind-mismatch", nil, "kind",
+			fmt.Sprintf("resource_type 为 %s，但文件中没有 kind 为 %s 的对象", resourceType, resourceType))
+	}
+
+	namespace := primary.GetNamespace()
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	s.validateSchema(validation, userID, clusterID, objects, namespace)
+	for _, obj := range objects {
+		lintObject(validation, obj)
+	}
+}
+
+// validateSchema 通过 server-side dry-run 并开启严格字段校验，使用集群的 OpenAPI schema（包括 CRD）校验每个对象；
+// 集群不可用等非校验类错误只记为 warning，不阻止保存
+func (s *K8sResourceService) validateSchema(validation *ManifestValidation, userID uint, clusterID uint32, objects []*unstructured.Unstructured, namespace string) {
+	if err := s.k8sClusterService.CanAccess(userID, clusterID); err != nil {
+		validation.addFinding(FindingError, "schema", nil, "", err.Error())
+		return
+	}
+
+	clients, err := conf.GetK8sClients(clusterID)
+	if err != nil {
+		validation.addFinding(FindingWarning, "schema", nil, "", fmt.Sprintf("无法连接集群，跳过 schema 校验: %v", err))
+		return
+	}
+
+	ctx := context.TODO()
+	force := true
+	for _, obj := range objects {
+		obj = obj.DeepCopy()
+		ri, mapping, err := clients.ResourceInterface(obj, namespace)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				validation.addFinding(FindingError, "schema", obj, "apiVersion", err.Error())
+			} else {
+				validation.addFinding(FindingWarning, "schema", obj, "", fmt.Sprintf("跳过 schema 校验: %v", err))
+			}
+			continue
+		}
+
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			if err := s.k8sNamespaceService.CheckNamespaceAccess(userID, clusterID, obj.GetNamespace()); err != nil {
+				validation.addFinding(FindingWarning, "namespace", obj, "metadata.namespace", err.Error())
+			}
+		} else {
+			validation.addFinding(FindingWarning, "namespace", obj, "", "集群级资源不允许由团队部署")
+		}
+
+		body, err := json.Marshal(obj.Object)
+		if err != nil {
+			validation.addFinding(FindingError, "schema", obj, "", err.Error())
+			continue
+		}
+		_, err = ri.Patch(ctx, obj.GetName(), types.ApplyPatchType, body, metav1.PatchOptions{
+			FieldManager:    define.K8sFieldManager,
+			Force:           &force,
+			DryRun:          []string{metav1.DryRunAll},
+			FieldValidation: metav1.FieldValidationStrict,
+		})
+		if err == nil {
+			continue
+		}
+
+		var statusErr *k8serrors.StatusError
+		if (k8serrors.IsBadRequest(err) || k8serrors.IsInvalid(err)) && errors.As(err, &statusErr) {
+			addSchemaFindings(validation, obj, statusErr)
+			continue
+		}
+		logrus.Warnf("manifest schema dry-run error: %s/%s %v", obj.GetKind(), obj.GetName(), err)
+		validation.addFinding(FindingWarning, "schema", obj, "", fmt.Sprintf("跳过 schema 校验: %v", err))
+	}
+}
+
+// validateHelmRelease helm 类型的资源只校验 release 描述，chart 中的对象由 helm 在安装时校验
+func (s *K8sResourceService) validateHelmRelease(validation *ManifestValidation, userID uint, clusterID uint32, content []byte) {
+	release, err := ParseHelmRelease(content)
+	if err != nil {
+		validation.addFinding(FindingError, "parse", nil, "", err.Error())
+		return
+	}
+	validation.Objects = 1
+
+	if err := s.k8sNamespaceService.CheckNamespaceAccess(userID, clusterID, release.Namespace); err != nil {
+		validation.addFinding(FindingWarning, "namespace", nil, "namespace", err.Error())
+	}
+	if release.Version == "" && !release.IsOssChart() {
+		validation.addFinding(FindingWarning, "chart-version", nil, "version", fmt.Sprintf("chart %s 没有固定版本，每次安装可能得到不同的版本", release.Chart))
+	}
+}
+
+// addSchemaFindings 将 API Server 返回的校验错误展开为逐字段的结果
+func addSchemaFindings(validation *ManifestValidation, obj *unstructured.Unstructured, statusErr *k8serrors.StatusError) {
+	details := statusErr.ErrStatus.Details
+	if details == nil || len(details.Causes) == 0 {
+		validation.addFinding(FindingError, "schema", obj, "", statusErr.ErrStatus.Message)
+		return
+	}
+	for _, cause := range details.Causes {
+		validation.addFinding(FindingError, "schema", obj, cause.Field, cause.Message)
+	}
+}
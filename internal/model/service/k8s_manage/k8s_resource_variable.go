@@ -0,0 +1,122 @@
+package k8s_manage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"gorm.io/gorm"
+)
+
+// SaveVariables 保存资源某个环境的模板变量集，environment 为空时保存为默认变量集
+func (s *K8sResourceService) SaveVariables(userID uint, k8sResourceID uint32, environment string, variables map[string]interface{}) error {
+	if err := s.checkResourceAccess(userID, k8sResourceID); err != nil {
+		return err
+	}
+	if environment == "" {
+		environment = DefaultEnvironment
+	}
+
+	variablesJSON, err := json.Marshal(variables)
+	if err != nil {
+		return fmt.Errorf("序列化变量失败: %v", err)
+	}
+
+	return s.userK8sResourceVariableDao.Save(&dao.UserK8sResourceVariable{
+		K8sResourceID: k8sResourceID,
+		Environment:   environment,
+		Variables:     string(variablesJSON),
+		UserID:        uint32(userID),
+	})
+}
+
+// QueryVariables 查询资源的全部模板变量集
+func (s *K8sResourceService) QueryVariables(userID uint, k8sResourceID uint32) ([]dao.UserK8sResourceVariable, error) {
+	if err := s.checkResourceAccess(userID, k8sResourceID); err != nil {
+		return nil, err
+	}
+	return s.userK8sResourceVariableDao.QueryByResourceID(k8sResourceID)
+}
+
+// DeleteVariables 删除模板变量集
+func (s *K8sResourceService) DeleteVariables(userID uint, id uint32) error {
+	variable, err := s.userK8sResourceVariableDao.QueryById(id)
+	if err != nil {
+		return fmt.Errorf("查询变量集失败: %v", err)
+	}
+	if err := s.checkResourceAccess(userID, variable.K8sResourceID); err != nil {
+		return err
+	}
+	return s.userK8sResourceVariableDao.Delete(id)
+}
+
+// TemplateValues 组装渲染模板的变量：默认变量集 < 指定环境的变量集 < 选择的镜像（覆盖 Image），environment 为空时只使用默认变量集
+func (s *K8sResourceService) TemplateValues(userID uint, k8sResourceID uint32, environment string, dockerImageID uint32) (map[string]interface{}, error) {
+	if environment == "" {
+		environment = DefaultEnvironment
+	}
+	// 模板中可以通过 {{ .Env }} 引用当前环境名
+	values := map[string]interface{}{"Env": environment}
+
+	environments := []string{DefaultEnvironment}
+	if environment != DefaultEnvironment {
+		environments = append(environments, environment)
+	}
+	for _, env := range environments {
+		variable, err := s.userK8sResourceVariableDao.QueryByResourceAndEnvironment(k8sResourceID, env)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				if env != DefaultEnvironment {
+					return nil, fmt.Errorf("资源 %d 没有环境 %s 的变量集", k8sResourceID, env)
+				}
+				continue
+			}
+			return nil, err
+		}
+
+		// 使用 json.Number 保留数字原样，避免大数渲染成科学计数法
+		var envValues map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(variable.Variables))
+		decoder.UseNumber()
+		if err := decoder.Decode(&envValues); err != nil {
+			return nil, fmt.Errorf("解析环境 %s 的变量失败: %v", env, err)
+		}
+		for key, value := range envValues {
+			values[key] = value
+		}
+	}
+
+	if dockerImageID != 0 {
+		image, err := s.userDockerImageDao.GetByID(context.TODO(), dockerImageID)
+		if err != nil {
+			return nil, fmt.Errorf("查询镜像失败: %v", err)
+		}
+		// 回滚和恢复会沿用部署者选择的镜像，团队成员构建的镜像都可以使用
+		if err := checkImageOwner(userID, image); err != nil {
+			return nil, err
+		}
+		values["Image"] = image.FullImageName
+	}
+	return values, nil
+}
+
+// checkImageOwner 校验镜像由用户本人或用户所在团队的成员构建
+func checkImageOwner(userID uint, image *dao.UserDockerImage) error {
+	if image.UserId == uint32(userID) {
+		return nil
+	}
+	user, err := dao.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("获取用户信息失败: %v", err)
+	}
+	builder, err := dao.GetUserByID(uint(image.UserId))
+	if err != nil {
+		return fmt.Errorf("获取镜像构建者信息失败: %v", err)
+	}
+	if user.TeamID != 0 && user.TeamID == builder.TeamID {
+		return nil
+	}
+	return fmt.Errorf("镜像 %d 不属于当前用户或其团队", image.Id)
+}
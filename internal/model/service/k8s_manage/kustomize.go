@@ -0,0 +1,200 @@
+package k8s_manage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+const (
+	// kustomizeBaseDir base 所在目录，overlays/<name> 中的 overlay 通过 ../../base 引用
+	kustomizeBaseDir     = "base"
+	kustomizeOverlaysDir = "overlays"
+	// kustomizeArchiveMaxSize 解压后的总大小上限，防止异常的压缩包占满内存
+	kustomizeArchiveMaxSize = 20 << 20
+)
+
+// kustomizationFileNames kustomize 识别的 kustomization 文件名
+var kustomizationFileNames = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// KustomizeLayout 解压到内存中的 kustomization 目录：base 加上 overlays 下的各个环境
+type KustomizeLayout struct {
+	fs       filesys.FileSystem
+	root     string
+	Overlays []string
+}
+
+// IsKustomizeArchive 资源文件是否为 kustomization 目录的压缩包（.tar.gz / .tgz / .zip）
+func IsKustomizeArchive(fileName string) bool {
+	lower := strings.ToLower(fileName)
+	return strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") || strings.HasSuffix(lower, ".zip")
+}
+
+// LoadKustomizeArchive 将压缩包解压到内存文件系统，包含模板变量的文件先用 values 渲染
+func LoadKustomizeArchive(archive []byte, fileName string, values map[string]interface{}) (*KustomizeLayout, error) {
+	files, err := extractArchive(archive, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := filesys.MakeFsInMemory()
+	for name, content := range files {
+		if IsTemplate(content) {
+			content, err = RenderManifest(content, values)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		if err := fs.WriteFile("/"+name, content); err != nil {
+			return nil, fmt.Errorf("写入 %s 失败: %v", name, err)
+		}
+	}
+
+	// 压缩包中可能多包了一层目录，以最浅的 base/kustomization.yaml 所在位置作为根目录
+	root := ""
+	found := false
+	for name := range files {
+		dir, file := path.Split(name)
+		if !isKustomizationFile(file) || path.Base(dir) != kustomizeBaseDir {
+			continue
+		}
+		candidate := path.Dir(path.Clean(dir))
+		if candidate == "." {
+			candidate = ""
+		}
+		if !found || len(candidate) < len(root) {
+			root, found = candidate, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("压缩包中没有 %s/kustomization.yaml", kustomizeBaseDir)
+	}
+
+	layout := &KustomizeLayout{fs: fs, root: "/" + root}
+	overlays := make(map[string]bool)
+	for name := range files {
+		relative := strings.TrimPrefix(name, root)
+		relative = strings.TrimPrefix(relative, "/")
+		parts := strings.Split(relative, "/")
+		if len(parts) == 3 && parts[0] == kustomizeOverlaysDir && isKustomizationFile(parts[2]) {
+			overlays[parts[1]] = true
+		}
+	}
+	for overlay := range overlays {
+		layout.Overlays = append(layout.Overlays, overlay)
+	}
+	sort.Strings(layout.Overlays)
+	return layout, nil
+}
+
+// Render 使用 kustomize 渲染指定 overlay，overlay 为空时渲染 base
+func (l *KustomizeLayout) Render(overlay string) ([]byte, error) {
+	dir := path.Join(l.root, kustomizeBaseDir)
+	if overlay != "" {
+		if !l.HasOverlay(overlay) {
+			return nil, fmt.Errorf("overlay %s 不存在，可选: %s", overlay, strings.Join(l.Overlays, ", "))
+		}
+		dir = path.Join(l.root, kustomizeOverlaysDir, overlay)
+	}
+
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(l.fs, dir)
+	if err != nil {
+		return nil, fmt.Errorf("kustomize 渲染失败: %v", err)
+	}
+	return resources.AsYaml()
+}
+
+// HasOverlay 是否存在指定的 overlay
+func (l *KustomizeLayout) HasOverlay(overlay string) bool {
+	for _, name := range l.Overlays {
+		if name == overlay {
+			return true
+		}
+	}
+	return false
+}
+
+func isKustomizationFile(name string) bool {
+	for _, fileName := range kustomizationFileNames {
+		if name == fileName {
+			return true
+		}
+	}
+	return false
+}
+
+// extractArchive 解压 tar.gz 或 zip，返回相对路径到文件内容的映射，拒绝跳出根目录的路径
+func extractArchive(archive []byte, fileName string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	total := 0
+	add := func(name string, reader io.Reader) error {
+		name = path.Clean(strings.TrimPrefix(name, "./"))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("压缩包中的路径 %s 不合法", name)
+		}
+		content, err := io.ReadAll(io.LimitReader(reader, int64(kustomizeArchiveMaxSize-total+1)))
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", name, err)
+		}
+		total += len(content)
+		if total > kustomizeArchiveMaxSize {
+			return fmt.Errorf("压缩包解压后超过 %d MB", kustomizeArchiveMaxSize>>20)
+		}
+		files[name] = content
+		return nil
+	}
+
+	if strings.HasSuffix(strings.ToLower(fileName), ".zip") {
+		reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, fmt.Errorf("解析 zip 失败: %v", err)
+		}
+		for _, file := range reader.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("读取 %s 失败: %v", file.Name, err)
+			}
+			err = add(file.Name, rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return files, nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("解析 tar.gz 失败: %v", err)
+	}
+	defer gz.Close()
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 tar.gz 失败: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := add(header.Name, reader); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,259 @@
+package k8s_manage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// archiveFile 测试压缩包中的单个文件
+type archiveFile struct {
+	name    string
+	content string
+}
+
+func tarGzArchive(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, file := range files {
+		header := &tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.content)), Typeflag: tar.TypeReg}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(file.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func zipArchive(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	zipWriter := zip.NewWriter(&buffer)
+	for _, file := range files {
+		writer, err := zipWriter.Create(file.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(file.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+const (
+	testBaseKustomization = `resources:
+- deployment.yaml
+`
+	testBaseDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: {{ .Image }}
+`
+	testOverlayKustomization = `resources:
+- ../../base
+patches:
+- patch: |-
+    - op: replace
+      path: /spec/replicas
+      value: 3
+  target:
+    kind: Deployment
+    name: web
+`
+)
+
+func TestLoadKustomizeArchive(t *testing.T) {
+	layoutFiles := func(prefix string) []archiveFile {
+		return []archiveFile{
+			{name: prefix + "base/kustomization.yaml", content: testBaseKustomization},
+			{name: prefix + "base/deployment.yaml", content: testBaseDeployment},
+			{name: prefix + "overlays/prod/kustomization.yaml", content: testOverlayKustomization},
+			{name: prefix + "overlays/dev/kustomization.yml", content: "resources:\n- ../../base\n"},
+			{name: prefix + "overlays/README.md", content: "overlay 说明"},
+		}
+	}
+	values := map[string]interface{}{"Image": "registry/web:v1"}
+
+	tests := []struct {
+		name         string
+		fileName     string
+		archive      func(t *testing.T) []byte
+		wantOverlays []string
+		wantErr      string
+	}{
+		{
+			name:         "tar.gz",
+			fileName:     "web.tar.gz",
+			archive:      func(t *testing.T) []byte { return tarGzArchive(t, layoutFiles("")) },
+			wantOverlays: []string{"dev", "prod"},
+		},
+		{
+			name:         "zip 多包一层目录",
+			fileName:     "web.zip",
+			archive:      func(t *testing.T) []byte { return zipArchive(t, layoutFiles("web/")) },
+			wantOverlays: []string{"dev", "prod"},
+		},
+		{
+			name:     "只有 base",
+			fileName: "web.tgz",
+			archive: func(t *testing.T) []byte {
+				return tarGzArchive(t, layoutFiles("./")[:2])
+			},
+		},
+		{
+			name:     "缺少 base",
+			fileName: "web.tar.gz",
+			archive: func(t *testing.T) []byte {
+				return tarGzArchive(t, []archiveFile{{name: "overlays/prod/kustomization.yaml", content: testOverlayKustomization}})
+			},
+			wantErr: "没有 base/kustomization.yaml",
+		},
+		{
+			name:     "跳出根目录的路径",
+			fileName: "web.tar.gz",
+			archive: func(t *testing.T) []byte {
+				return tarGzArchive(t, append(layoutFiles(""), archiveFile{name: "../etc/passwd", content: "root"}))
+			},
+			wantErr: "不合法",
+		},
+		{
+			name:     "模板变量缺失",
+			fileName: "web.zip",
+			archive: func(t *testing.T) []byte {
+				return zipArchive(t, []archiveFile{
+					{name: "base/kustomization.yaml", content: testBaseKustomization},
+					{name: "base/deployment.yaml", content: "image: {{ .Missing }}\n"},
+				})
+			},
+			wantErr: "base/deployment.yaml",
+		},
+		{
+			name:     "不是压缩包",
+			fileName: "web.tar.gz",
+			archive:  func(t *testing.T) []byte { return []byte("not an archive") },
+			wantErr:  "解析 tar.gz 失败",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := LoadKustomizeArchive(tt.archive(t), tt.fileName, values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadKustomizeArchive() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKustomizeArchive() error: %v", err)
+			}
+			if !reflect.DeepEqual(layout.Overlays, tt.wantOverlays) {
+				t.Errorf("Overlays = %v, want %v", layout.Overlays, tt.wantOverlays)
+			}
+			for _, overlay := range append([]string{""}, layout.Overlays...) {
+				if !strings.Contains(renderLayout(t, layout, overlay), "image: registry/web:v1") {
+					t.Errorf("overlay %q 没有使用 values 渲染模板", overlay)
+				}
+			}
+		})
+	}
+}
+
+func TestKustomizeLayoutRender(t *testing.T) {
+	layout, err := LoadKustomizeArchive(tarGzArchive(t, []archiveFile{
+		{name: "base/kustomization.yaml", content: testBaseKustomization},
+		{name: "base/deployment.yaml", content: testBaseDeployment},
+		{name: "overlays/prod/kustomization.yaml", content: testOverlayKustomization},
+	}), "web.tar.gz", map[string]interface{}{"Image": "registry/web:v1"})
+	if err != nil {
+		t.Fatalf("LoadKustomizeArchive() error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		overlay      string
+		wantReplicas string
+		wantErr      bool
+	}{
+		{name: "base", overlay: "", wantReplicas: "replicas: 1"},
+		{name: "overlay 的 patch 生效", overlay: "prod", wantReplicas: "replicas: 3"},
+		{name: "overlay 不存在", overlay: "staging", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := layout.Render(tt.overlay)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Render(%q) = %s, want error", tt.overlay, rendered)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render(%q) error: %v", tt.overlay, err)
+			}
+			if !strings.Contains(string(rendered), tt.wantReplicas) {
+				t.Errorf("Render(%q) = %s, want %q", tt.overlay, rendered, tt.wantReplicas)
+			}
+		})
+	}
+}
+
+func TestIsKustomizeArchive(t *testing.T) {
+	tests := []struct {
+		fileName string
+		want     bool
+	}{
+		{fileName: "web.tar.gz", want: true},
+		{fileName: "web.TGZ", want: true},
+		{fileName: "web.zip", want: true},
+		{fileName: "web.yaml", want: false},
+		{fileName: "web.tar", want: false},
+		{fileName: "zip.yaml", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsKustomizeArchive(tt.fileName); got != tt.want {
+			t.Errorf("IsKustomizeArchive(%q) = %v, want %v", tt.fileName, got, tt.want)
+		}
+	}
+}
+
+func renderLayout(t *testing.T, layout *KustomizeLayout, overlay string) string {
+	t.Helper()
+	rendered, err := layout.Render(overlay)
+	if err != nil {
+		t.Fatalf("Render(%q) error: %v", overlay, err)
+	}
+	return string(rendered)
+}
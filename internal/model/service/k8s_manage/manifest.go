@@ -0,0 +1,217 @@
+package k8s_manage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ManifestFinding 校验发现的单个问题，Severity 为 error 时不允许保存
+type ManifestFinding struct {
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Kind     string `json:"kind,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Overlay  string `json:"overlay,omitempty"` // kustomization 压缩包中所属的 overlay，空为 base
+	Message  string `json:"message"`
+}
+
+// ManifestValidation 一次校验的结果
+type ManifestValidation struct {
+	Valid    bool              `json:"valid"`
+	Objects  int               `json:"objects"`
+	Findings []ManifestFinding `json:"findings"`
+}
+
+const (
+	FindingError   = "error"
+	FindingWarning = "warning"
+)
+
+// DefaultEnvironment 默认变量集，其他环境的变量在此基础上覆盖
+const DefaultEnvironment = "default"
+
+// podSpecPaths 各工作负载中 Pod 模板所在的路径
+var podSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// serverManagedAnnotations 由控制器写入的注解，不属于用户声明的内容
+var serverManagedAnnotations = []string{
+	"deployment.kubernetes.io/revision",
+	"kubectl.kubernetes.io/last-applied-configuration",
+}
+
+// IsTemplate YAML 中是否包含模板变量
+func IsTemplate(content []byte) bool {
+	return bytes.Contains(content, []byte("{{"))
+}
+
+// RenderManifest 使用 text/template 渲染 YAML 中的模板变量，例如 {{ .Image }}、{{ .Replicas }}，引用了不存在的变量时报错
+func RenderManifest(content []byte, values map[string]interface{}) ([]byte, error) {
+	if !IsTemplate(content) {
+		return content, nil
+	}
+
+	tmpl, err := template.New("manifest").Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("解析模板失败: %v", err)
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, values); err != nil {
+		return nil, fmt.Errorf("渲染模板失败: %v", err)
+	}
+	return rendered.Bytes(), nil
+}
+
+// SplitManifest 将多文档 YAML 拆分为 unstructured 对象，跳过空文档
+func SplitManifest(content []byte) ([]*unstructured.Unstructured, error) {
+	decoder := yamlutil.NewYAMLOrJSONDecoder(bytes.NewReader(content), 4096)
+	var objects []*unstructured.Unstructured
+	for {
+		var raw map[string]interface{}
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("解析 YAML 失败: %v", err)
+		}
+		if len(raw) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: raw}
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+			return nil, fmt.Errorf("YAML 文档缺少 apiVersion 或 kind")
+		}
+		if obj.GetName() == "" {
+			return nil, fmt.Errorf("%s 缺少 metadata.name", obj.GetKind())
+		}
+		objects = append(objects, obj)
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("YAML 文件中没有可用的资源")
+	}
+	return objects, nil
+}
+
+// PrimaryObject 选出与 resource_type 匹配的主对象，用于记录操作日志；没有匹配时取第一个
+func PrimaryObject(objects []*unstructured.Unstructured, resourceType string) *unstructured.Unstructured {
+	for _, obj := range objects {
+		if strings.EqualFold(obj.GetKind(), resourceType) {
+			return obj
+		}
+	}
+	return objects[0]
+}
+
+// addFinding 记录一条校验结果，出现 error 时整体不通过
+func (v *ManifestValidation) addFinding(severity string, rule string, obj *unstructured.Unstructured, path string, message string) {
+	finding := ManifestFinding{Severity: severity, Rule: rule, Path: path, Message: message}
+	if obj != nil {
+		finding.Kind = obj.GetKind()
+		finding.Name = obj.GetName()
+	}
+	if severity == FindingError {
+		v.Valid = false
+	}
+	v.Findings = append(v.Findings, finding)
+}
+
+// lintObject 检查工作负载的最佳实践：资源限制、健康检查探针、镜像 tag
+func lintObject(validation *ManifestValidation, obj *unstructured.Unstructured) {
+	specPath, ok := podSpecPaths[obj.GetKind()]
+	if !ok {
+		return
+	}
+	podSpec, found, err := unstructured.NestedMap(obj.Object, specPath...)
+	if err != nil || !found {
+		validation.addFinding(FindingError, "pod-spec", obj, strings.Join(specPath, "."), "缺少 Pod 模板")
+		return
+	}
+
+	// 一次性任务运行完即退出，不要求探针
+	needProbes := obj.GetKind() != "Job" && obj.GetKind() != "CronJob"
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, _ := unstructured.NestedSlice(podSpec, field)
+		for i, item := range containers {
+			container, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			path := fmt.Sprintf("%s.%s[%d]", strings.Join(specPath, "."), field, i)
+			name, _ := container["name"].(string)
+
+			image, _ := container["image"].(string)
+			if image == "" {
+				validation.addFinding(FindingError, "image", obj, path+".image", fmt.Sprintf("容器 %s 没有指定镜像", name))
+			} else if !imagePinned(image) {
+				validation.addFinding(FindingWarning, "image-tag", obj, path+".image", fmt.Sprintf("容器 %s 的镜像 %s 使用 latest 或未指定 tag，请使用固定版本", name, image))
+			}
+
+			for _, kind := range []string{"limits", "requests"} {
+				if values, _, _ := unstructured.NestedMap(container, "resources", kind); len(values) == 0 {
+					validation.addFinding(FindingWarning, "resources", obj, path+".resources."+kind, fmt.Sprintf("容器 %s 没有设置 resources.%s", name, kind))
+				}
+			}
+
+			if !needProbes || field == "initContainers" {
+				continue
+			}
+			for _, probe := range []string{"readinessProbe", "livenessProbe"} {
+				if _, found := container[probe]; !found {
+					validation.addFinding(FindingWarning, "probes", obj, path+"."+probe, fmt.Sprintf("容器 %s 没有设置 %s", name, probe))
+				}
+			}
+		}
+	}
+}
+
+// imagePinned 镜像是否固定了版本：带 digest 或者 tag 不为 latest
+func imagePinned(image string) bool {
+	if strings.Contains(image, "@") {
+		return true
+	}
+	// 只看最后一段，避免把 registry 的端口当成 tag
+	lastSegment := image[strings.LastIndex(image, "/")+1:]
+	index := strings.LastIndex(lastSegment, ":")
+	if index < 0 {
+		return false
+	}
+	return lastSegment[index+1:] != "latest"
+}
+
+// StripServerFields 复制对象并去掉 API Server 维护的字段，只保留用户可声明的部分
+func StripServerFields(object map[string]interface{}) map[string]interface{} {
+	stripped := (&unstructured.Unstructured{Object: object}).DeepCopy()
+	delete(stripped.Object, "status")
+	for _, field := range []string{"managedFields", "resourceVersion", "uid", "generation", "creationTimestamp", "selfLink"} {
+		unstructured.RemoveNestedField(stripped.Object, "metadata", field)
+	}
+
+	annotations := stripped.GetAnnotations()
+	for _, key := range serverManagedAnnotations {
+		delete(annotations, key)
+	}
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(stripped.Object, "metadata", "annotations")
+	} else {
+		stripped.SetAnnotations(annotations)
+	}
+	return stripped.Object
+}
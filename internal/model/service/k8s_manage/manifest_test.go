@@ -0,0 +1,241 @@
+package k8s_manage
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSplitManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string // kind/name
+		wantErr bool
+	}{
+		{
+			name: "单个对象",
+			content: `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`,
+			want: []string{"Service/web"},
+		},
+		{
+			name: "多文档并跳过空文档",
+			content: `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+# 只有注释的文档
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`,
+			want: []string{"Deployment/web", "Service/web"},
+		},
+		{
+			name:    "JSON 格式",
+			content: `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "config"}}`,
+			want:    []string{"ConfigMap/config"},
+		},
+		{name: "空文件", content: "", wantErr: true},
+		{name: "只有分隔符", content: "---\n---\n", wantErr: true},
+		{name: "YAML 语法错误", content: "kind: [Service\n", wantErr: true},
+		{
+			name: "缺少 kind",
+			content: `apiVersion: v1
+metadata:
+  name: web
+`,
+			wantErr: true,
+		},
+		{
+			name: "缺少 apiVersion",
+			content: `kind: Service
+metadata:
+  name: web
+`,
+			wantErr: true,
+		},
+		{
+			name: "缺少名称",
+			content: `apiVersion: v1
+kind: Service
+metadata:
+  labels:
+    app: web
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := SplitManifest([]byte(tt.content))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("SplitManifest() = %d objects, want error", len(objects))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SplitManifest() error: %v", err)
+			}
+			if len(objects) != len(tt.want) {
+				t.Fatalf("SplitManifest() = %d objects, want %d", len(objects), len(tt.want))
+			}
+			for i, obj := range objects {
+				if got := obj.GetKind() + "/" + obj.GetName(); got != tt.want[i] {
+					t.Errorf("object %d = %s, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRenderManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		values  map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "没有模板变量时原样返回",
+			content: "image: nginx:1.25\n",
+			want:    "image: nginx:1.25\n",
+		},
+		{
+			name:    "替换变量",
+			content: "image: {{ .Image }}\nreplicas: {{ .Replicas }}\n",
+			values:  map[string]interface{}{"Image": "registry/app:v2", "Replicas": 3},
+			want:    "image: registry/app:v2\nreplicas: 3\n",
+		},
+		{
+			name:    "嵌套变量和条件",
+			content: "{{ if .Debug }}level: debug{{ else }}level: {{ .Log.Level }}{{ end }}\n",
+			values:  map[string]interface{}{"Debug": false, "Log": map[string]interface{}{"Level": "info"}},
+			want:    "level: info\n",
+		},
+		{
+			name:    "引用不存在的变量",
+			content: "image: {{ .Image }}\n",
+			values:  map[string]interface{}{},
+			wantErr: true,
+		},
+		{
+			name:    "模板语法错误",
+			content: "image: {{ .Image \n",
+			values:  map[string]interface{}{"Image": "app"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := RenderManifest([]byte(tt.content), tt.values)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("RenderManifest() = %q, want error", rendered)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderManifest() error: %v", err)
+			}
+			if string(rendered) != tt.want {
+				t.Errorf("RenderManifest() = %q, want %q", rendered, tt.want)
+			}
+		})
+	}
+}
+
+// lintTestObject 构造带单个容器的工作负载，container 为 nil 时没有 Pod 模板
+func lintTestObject(kind string, container map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": "web"},
+	}}
+	if container == nil {
+		return obj
+	}
+	podSpec := map[string]interface{}{"containers": []interface{}{container}}
+	_ = unstructured.SetNestedMap(obj.Object, podSpec, podSpecPaths[kind]...)
+	return obj
+}
+
+func TestLintObject(t *testing.T) {
+	resources := map[string]interface{}{
+		"limits":   map[string]interface{}{"cpu": "500m", "memory": "256Mi"},
+		"requests": map[string]interface{}{"cpu": "100m", "memory": "128Mi"},
+	}
+	probe := map[string]interface{}{"httpGet": map[string]interface{}{"path": "/healthz", "port": int64(8080)}}
+	complete := map[string]interface{}{
+		"name":           "app",
+		"image":          "registry/app:v1.2.0",
+		"resources":      resources,
+		"readinessProbe": probe,
+		"livenessProbe":  probe,
+	}
+	without := func(fields ...string) map[string]interface{} {
+		container := make(map[string]interface{}, len(complete))
+		for key, value := range complete {
+			container[key] = value
+		}
+		for _, field := range fields {
+			delete(container, field)
+		}
+		return container
+	}
+	with := func(field string, value interface{}) map[string]interface{} {
+		container := without()
+		container[field] = value
+		return container
+	}
+
+	tests := []struct {
+		name      string
+		obj       *unstructured.Unstructured
+		wantRules []string
+		wantValid bool
+	}{
+		{name: "符合最佳实践", obj: lintTestObject("Deployment", complete), wantValid: true},
+		{name: "非工作负载不检查", obj: lintTestObject("Service", nil), wantValid: true},
+		{name: "缺少 Pod 模板", obj: lintTestObject("Deployment", nil), wantRules: []string{"pod-spec"}},
+		{name: "没有指定镜像", obj: lintTestObject("StatefulSet", without("image")), wantRules: []string{"image"}},
+		{name: "latest 镜像", obj: lintTestObject("Deployment", with("image", "nginx:latest")), wantRules: []string{"image-tag"}, wantValid: true},
+		{name: "未指定 tag", obj: lintTestObject("Deployment", with("image", "registry:5000/team/app")), wantRules: []string{"image-tag"}, wantValid: true},
+		{name: "digest 视为固定版本", obj: lintTestObject("Deployment", with("image", "nginx@sha256:0123")), wantValid: true},
+		{name: "缺少资源限制", obj: lintTestObject("DaemonSet", without("resources")), wantRules: []string{"resources", "resources"}, wantValid: true},
+		{name: "缺少探针", obj: lintTestObject("Deployment", without("readinessProbe", "livenessProbe")), wantRules: []string{"probes", "probes"}, wantValid: true},
+		{name: "Job 不要求探针", obj: lintTestObject("Job", without("readinessProbe", "livenessProbe")), wantValid: true},
+		{name: "CronJob 不要求探针", obj: lintTestObject("CronJob", without("readinessProbe", "livenessProbe")), wantValid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validation := &ManifestValidation{Valid: true}
+			lintObject(validation, tt.obj)
+
+			var rules []string
+			for _, finding := range validation.Findings {
+				rules = append(rules, finding.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.wantRules) {
+				t.Errorf("lintObject() rules = %v, want %v", rules, tt.wantRules)
+			}
+			if validation.Valid != tt.wantValid {
+				t.Errorf("lintObject() valid = %v, want %v", validation.Valid, tt.wantValid)
+			}
+		})
+	}
+}
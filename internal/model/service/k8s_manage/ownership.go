@@ -0,0 +1,70 @@
+package k8s_manage
+
+import (
+	"strconv"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Ownership 平台部署对象的归属：对应的资源、部署者和部署者所在团队
+type Ownership struct {
+	ResourceID uint32
+	UserID     uint
+	TeamID     uint32
+}
+
+// podTemplatePaths 各类工作负载中 Pod 模板 metadata 的位置，CronJob 的 Job 模板也需要带上标签
+var podTemplatePaths = map[string][][]string{
+	"Deployment":  {{"spec", "template", "metadata"}},
+	"StatefulSet": {{"spec", "template", "metadata"}},
+	"DaemonSet":   {{"spec", "template", "metadata"}},
+	"ReplicaSet":  {{"spec", "template", "metadata"}},
+	"Job":         {{"spec", "template", "metadata"}},
+	"CronJob":     {{"spec", "jobTemplate", "metadata"}, {"spec", "jobTemplate", "spec", "template", "metadata"}},
+}
+
+// StampOwnership 在 apply 的对象上写入归属标签。Pod 模板只写入 resource-id：
+// 部署者变化时不修改模板，避免触发滚动更新，也避免修改 Job 不可变的模板
+func StampOwnership(objects []*unstructured.Unstructured, ownership Ownership) {
+	resourceID := strconv.FormatUint(uint64(ownership.ResourceID), 10)
+	for _, obj := range objects {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[define.K8sLabelResourceID] = resourceID
+		labels[define.K8sLabelUserID] = strconv.FormatUint(uint64(ownership.UserID), 10)
+		if ownership.TeamID != 0 {
+			labels[define.K8sLabelTeamID] = strconv.FormatUint(uint64(ownership.TeamID), 10)
+		} else {
+			delete(labels, define.K8sLabelTeamID)
+		}
+		obj.SetLabels(labels)
+
+		for _, path := range podTemplatePaths[obj.GetKind()] {
+			if _, found, _ := unstructured.NestedMap(obj.Object, path[:len(path)-1]...); !found {
+				continue
+			}
+			templateLabels, _, _ := unstructured.NestedStringMap(obj.Object, append(path, "labels")...)
+			if templateLabels == nil {
+				templateLabels = make(map[string]string)
+			}
+			templateLabels[define.K8sLabelResourceID] = resourceID
+			_ = unstructured.SetNestedStringMap(obj.Object, templateLabels, append(path, "labels")...)
+		}
+	}
+}
+
+// ResourceIDFromLabels 从对象标签中读取平台的 k8s_resource_id
+func ResourceIDFromLabels(labels map[string]string) (uint32, bool) {
+	value, exist := labels[define.K8sLabelResourceID]
+	if !exist {
+		return 0, false
+	}
+	resourceID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || resourceID == 0 {
+		return 0, false
+	}
+	return uint32(resourceID), true
+}
@@ -0,0 +1,103 @@
+package k8s_manage
+
+import (
+	"fmt"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ResourceKinds 资源类型对应的主对象 GVK，helm 资源由 release 管理，没有单一的主对象
+var ResourceKinds = map[string]schema.GroupVersionKind{
+	"deployment":  appsv1.SchemeGroupVersion.WithKind("Deployment"),
+	"service":     v1.SchemeGroupVersion.WithKind("Service"),
+	"ingress":     networkingv1.SchemeGroupVersion.WithKind("Ingress"),
+	"statefulset": appsv1.SchemeGroupVersion.WithKind("StatefulSet"),
+	"daemonset":   appsv1.SchemeGroupVersion.WithKind("DaemonSet"),
+	"job":         batchv1.SchemeGroupVersion.WithKind("Job"),
+	"cronjob":     batchv1.SchemeGroupVersion.WithKind("CronJob"),
+}
+
+// IsWorkload 资源类型是否为会创建 Pod 的工作负载
+func IsWorkload(resourceType string) bool {
+	switch resourceType {
+	case "deployment", "statefulset", "daemonset", "job", "cronjob":
+		return true
+	}
+	return false
+}
+
+// StatefulSetHealth StatefulSet 的副本全部 ready 且都是最新版本时运行正常，仍在滚动更新时为重启中
+func StatefulSetHealth(statefulSet *appsv1.StatefulSet) (int, string) {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	status := statefulSet.Status
+	message := fmt.Sprintf("%d/%d ready, %d updated", status.ReadyReplicas, replicas, status.UpdatedReplicas)
+	switch {
+	case status.ReadyReplicas >= replicas && status.UpdateRevision == status.CurrentRevision:
+		return define.K8sResourceStatusRun, message
+	case status.ObservedGeneration < statefulSet.Generation || status.UpdateRevision != status.CurrentRevision:
+		return define.K8sResourceStatusRestart, message
+	}
+	return define.K8sResourceStatusStop, message
+}
+
+// DaemonSetHealth DaemonSet 在所有目标节点上都有最新版本的 Pod 且可用时运行正常
+func DaemonSetHealth(daemonSet *appsv1.DaemonSet) (int, string) {
+	status := daemonSet.Status
+	message := fmt.Sprintf("%d desired, %d ready, %d up-to-date, %d available",
+		status.DesiredNumberScheduled, status.NumberReady, status.UpdatedNumberScheduled, status.NumberAvailable)
+	switch {
+	case status.NumberAvailable >= status.DesiredNumberScheduled && status.UpdatedNumberScheduled >= status.DesiredNumberScheduled:
+		return define.K8sResourceStatusRun, message
+	case status.ObservedGeneration < daemonSet.Generation || status.UpdatedNumberScheduled < status.DesiredNumberScheduled:
+		return define.K8sResourceStatusRestart, message
+	}
+	return define.K8sResourceStatusStop, message
+}
+
+// JobHealth Job 以 Complete / Failed 条件判断成功或失败，尚未结束时为运行中
+func JobHealth(job *batchv1.Job) (int, string) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != v1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return define.K8sResourceStatusSucceeded, fmt.Sprintf("completed, %d succeeded", job.Status.Succeeded)
+		case batchv1.JobFailed:
+			return define.K8sResourceStatusFailed, fmt.Sprintf("failed: %s %s", condition.Reason, condition.Message)
+		}
+	}
+	if job.Spec.Suspend != nil && *job.Spec.Suspend {
+		return define.K8sResourceStatusStop, "suspended"
+	}
+	return define.K8sResourceStatusRun, fmt.Sprintf("%d active, %d succeeded, %d failed", job.Status.Active, job.Status.Succeeded, job.Status.Failed)
+}
+
+// CronJobHealth CronJob 暂停时为停止；最近一次调度没有运行中的 Job 且晚于最近一次成功时，说明该次执行失败
+func CronJobHealth(cronJob *batchv1.CronJob) (int, string) {
+	status := cronJob.Status
+	message := "never scheduled"
+	if status.LastScheduleTime != nil {
+		message = fmt.Sprintf("last schedule %s", status.LastScheduleTime.Format("2006-01-02 15:04:05"))
+	}
+	if status.LastSuccessfulTime != nil {
+		message += fmt.Sprintf(", last successful %s", status.LastSuccessfulTime.Format("2006-01-02 15:04:05"))
+	}
+
+	if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+		return define.K8sResourceStatusStop, "suspended, " + message
+	}
+	if status.LastScheduleTime != nil && len(status.Active) == 0 &&
+		(status.LastSuccessfulTime == nil || status.LastSuccessfulTime.Before(status.LastScheduleTime)) {
+		return define.K8sResourceStatusFailed, message
+	}
+	return define.K8sResourceStatusRun, message
+}
@@ -2,7 +2,12 @@ package oss_manage
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"strings"
+
 	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"gorm.io/gorm"
 )
 
@@ -51,3 +56,46 @@ func (s *OssService) QueryOssAccess(userID uint) (*dao.UserOss, error) {
 func (s *OssService) DeleteOssAccess(userID uint) error {
 	return s.userOssDao.DeleteByUserID(userID)
 }
+
+// GetBucket 使用用户保存的 OSS 访问信息创建 bucket 客户端
+func (s *OssService) GetBucket(userID uint) (*oss.Bucket, error) {
+	userOss, err := s.userOssDao.QueryByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := oss.New(fmt.Sprintf("https://%s.aliyuncs.com", userOss.Region), userOss.AccessKeyID, userOss.AccessKeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("创建 OSS 客户端失败: %v", err)
+	}
+
+	bucket, err := client.Bucket(userOss.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("获取 bucket 失败: %v", err)
+	}
+	return bucket, nil
+}
+
+// GetObject 根据 OSS 文件 URL 读取文件内容
+func (s *OssService) GetObject(userID uint, ossURL string) ([]byte, error) {
+	bucket, err := s.GetBucket(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 从 URL 中提取 object-name，去掉域名部分
+	objectNameUrl := strings.TrimPrefix(ossURL, "https://")
+	objectName := strings.Join(strings.Split(objectNameUrl, "/")[1:], "/")
+
+	body, err := bucket.GetObject(objectName)
+	if err != nil {
+		return nil, fmt.Errorf("从 OSS 下载文件失败: %v", err)
+	}
+	defer body.Close()
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("读取 OSS 文件失败: %v", err)
+	}
+	return content, nil
+}
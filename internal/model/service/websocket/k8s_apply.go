@@ -0,0 +1,312 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ApplyResult 单个 k8s 对象的 apply 结果
+type ApplyResult struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Action    string `json:"action"` // created / configured / unchanged
+	Success   bool   `json:"success"`
+	Message   string `json:"message"`
+}
+
+// appliedResource 一次 apply 的汇总结果，用于记录操作日志
+type appliedResource struct {
+	Namespace     string
+	Name          string
+	Labels        string // 主对象标签的 JSON
+	OperationType string // create / update
+	Status        int
+	RolloutStatus string // deployment rollout 的最终状态，非 deployment 资源为空
+	Command       string
+	Options       k8s_manage.RenderOptions // 本次 apply 使用的模板变量集、镜像和 overlay，记录到操作日志中用于重新渲染
+	Results       []ApplyResult
+}
+
+// Succeeded 成功 apply 的对象个数
+func (a *appliedResource) Succeeded() int {
+	succeeded := 0
+	for _, r := range a.Results {
+		if r.Success {
+			succeeded++
+		}
+	}
+	return succeeded
+}
+
+// ossObjectName 从 OSS 文件 URL 中提取 object-name
+func ossObjectName(ossURL string) string {
+	objectNameUrl := strings.TrimPrefix(ossURL, "https://")
+	objectNameS := strings.Split(objectNameUrl, "/")[1:] // 去掉域名部分
+	return strings.Join(objectNameS, "/")
+}
+
+// loadResourceObjects 按 apply 的方式渲染资源某个版本，返回对象列表、默认 namespace 和展示用的文件路径。
+// resourceID 为对象归属的资源，回滚时与实际渲染的历史版本 resource 不同
+func (s *SocketService) loadResourceObjects(clients *conf.K8sClients, resourceID uint32, resource *dao.UserK8sResource, options k8s_manage.RenderOptions, userID uint) ([]*unstructured.Unstructured, string, string, error) {
+	if resource.ResourceType == "helm" {
+		return nil, "", "", fmt.Errorf("helm 类型的资源请使用 helm install / upgrade / rollback")
+	}
+
+	rendered, err := s.k8sResourceService.RenderResource(userID, resourceID, resource, options)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	// 所有对象都必须落在团队的受管 namespace 中，否则整体拒绝
+	if err := s.checkObjectsNamespace(clients, rendered.Objects, rendered.Namespace, userID); err != nil {
+		return nil, "", "", err
+	}
+	localFilePath := filepath.Join("k8s", fmt.Sprintf("%d_%s", resource.Id, resource.FileName))
+	return rendered.Objects, rendered.Namespace, localFilePath, nil
+}
+
+// parseRenderOptions 从 websocket 参数中读取模板选项
+func parseRenderOptions(data map[string]interface{}) k8s_manage.RenderOptions {
+	environment, _ := data["env"].(string)
+	dockerImageID, _ := data["docker_image_id"].(float64)
+	overlay, _ := data["overlay"].(string)
+	return k8s_manage.RenderOptions{Environment: environment, DockerImageID: uint32(dockerImageID), Overlay: overlay}
+}
+
+// applyStoredResource 下载资源某个版本的 YAML，apply 到集群并检查主对象状态；resourceID 为对象归属的资源
+func (s *SocketService) applyStoredResource(conn *websocket.Conn, clients *conf.K8sClients, command string, resourceID uint32, resource *dao.UserK8sResource, options k8s_manage.RenderOptions, userID uint) (*appliedResource, error) {
+	objects, namespace, localFilePath, err := s.loadResourceObjects(clients, resourceID, resource, options, userID)
+	if err != nil {
+		return nil, err
+	}
+	primary := k8s_manage.PrimaryObject(objects, resource.ResourceType)
+	if err := s.k8sResourceService.KeepLiveReplicas(context.TODO(), clients, resourceID, namespace, primary); err != nil {
+		return nil, err
+	}
+
+	// 逐个 apply 资源
+	results, err := s.createResourceFromYAML(conn, clients, command, objects, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("创建资源失败: %v", err)
+	}
+
+	// Deployment 按扩缩容策略同步 HPA，失败不影响本次 apply
+	if resource.ResourceType == "deployment" {
+		primaryNamespace := primary.GetNamespace()
+		if primaryNamespace == "" {
+			primaryNamespace = namespace
+		}
+		message, err := s.k8sResourceService.SyncAutoscaler(context.TODO(), clients, resourceID, primaryNamespace, primary.GetName())
+		if err != nil {
+			logrus.Errorf("同步 HPA 失败: %v", err)
+			SendError(conn, err.Error())
+		} else {
+			SendSuccess(conn, "object apply success", K8sCommandResponse{
+				Command: command,
+				Result:  message,
+			})
+		}
+	}
+
+	// 主对象已存在于集群中时记录为 update
+	operationType := "create"
+	for _, r := range results {
+		if r.Success && r.Kind == primary.GetKind() && r.Name == primary.GetName() && r.Action != "created" {
+			operationType = "update"
+		}
+	}
+
+	// 获取主对象的资源名称和标签
+	resourceName := primary.GetName()
+	labels := primary.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	if primary.GetNamespace() != "" {
+		namespace = primary.GetNamespace()
+	}
+
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		logrus.Errorf("序列化标签失败: %v", err)
+		// 继续执行，不中断流程
+	}
+
+	// 构建完整的 kubectl 命令
+	fullCommand := fmt.Sprintf("kubectl apply --server-side --field-manager=%s -f %s -n %s", define.K8sFieldManager, localFilePath, namespace)
+	if rendered := options.String(); rendered != "" {
+		fullCommand += " " + rendered
+	}
+
+	// Deployment / StatefulSet / DaemonSet 由 finishApply 跟踪 rollout 后更新状态；Job 和 CronJob 不等待执行结果，由状态检查跟踪
+	return &appliedResource{
+		Namespace:     namespace,
+		Name:          resourceName,
+		Labels:        string(labelsJSON),
+		OperationType: operationType,
+		Status:        define.K8sResourceStatusRun, // service 等非工作负载资源创建后默认为运行正常
+		Command:       fullCommand,
+		Options:       options,
+		Results:       results,
+	}, nil
+}
+
+// operationLog 构造本次 apply 的操作日志，k8sResourceID 为日志所属的资源版本
+func (a *appliedResource) operationLog(k8sResourceID uint, userID uint, operationType string) *dao.UserK8sResourceOperationLog {
+	return &dao.UserK8sResourceOperationLog{
+		K8sResourceID:  k8sResourceID,
+		UserID:         userID,
+		Namespace:      a.Namespace,
+		MetadataName:   a.Name,
+		MetadataLabels: a.Labels,
+		OperationType:  operationType,
+		Status:         a.Status,
+		RolloutStatus:  a.RolloutStatus,
+		Command:        a.Command,
+		Overlay:        a.Options.Overlay,
+		Environment:    a.Options.Environment,
+		DockerImageID:  a.Options.DockerImageID,
+	}
+}
+
+// finishApply apply 成功后立即保存操作日志，rollout 跟踪期间服务重启或连接断开也不会丢失部署记录；
+// Deployment / StatefulSet / DaemonSet 在后台跟踪 rollout 直到完成、失败或超时，把结果写入 applied 和操作日志后再调用 done，
+// 其他资源直接调用 done。connected 为 false 时跟踪已被 stop 或断开连接取消，done 不应再写入 websocket
+func (s *SocketService) finishApply(conn *websocket.Conn, userID uint, clients *conf.K8sClients, resourceType string, applied *appliedResource, operationLog *dao.UserK8sResourceOperationLog, done func(connected bool)) {
+	switch resourceType {
+	case "deployment", "statefulset", "daemonset":
+		// 跟踪期间记为容器重启，与 rollout 超时时的状态一致
+		operationLog.Status, operationLog.RolloutStatus = define.K8sResourceStatusRestart, define.K8sRolloutProgressing
+		if err := s.userK8sResourceOperationLogDao.Create(operationLog); err != nil {
+			logrus.Errorf("保存操作日志失败: %v", err)
+			// 继续执行，不中断流程
+		}
+		s.watchRollout(conn, userID, "apply", clients, resourceType, applied.Namespace, applied.Name, func(rollout rolloutResult, connected bool) {
+			applied.Status, applied.RolloutStatus = rollout.resourceStatus(), rollout.Status
+			if operationLog.ID != 0 {
+				if err := s.userK8sResourceOperationLogDao.UpdateRolloutStatus(operationLog.ID, applied.Status, applied.RolloutStatus); err != nil {
+					logrus.Errorf("更新操作日志 %d 的 rollout 状态失败: %v", operationLog.ID, err)
+				}
+			}
+			done(connected)
+		})
+	default:
+		if err := s.userK8sResourceOperationLogDao.Create(operationLog); err != nil {
+			logrus.Errorf("保存操作日志失败: %v", err)
+			// 继续执行，不中断流程
+		}
+		done(true)
+	}
+}
+
+// checkObjectsNamespace 校验 YAML 中的对象只会写入调用者团队的受管 namespace，集群级资源不允许通过平台创建
+func (s *SocketService) checkObjectsNamespace(clients *conf.K8sClients, objects []*unstructured.Unstructured, defaultNamespace string, userID uint) error {
+	for _, obj := range objects {
+		_, mapping, err := clients.ResourceInterface(obj, defaultNamespace)
+		if err != nil {
+			return err
+		}
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			return fmt.Errorf("不允许创建集群级资源 %s/%s", obj.GetKind(), obj.GetName())
+		}
+		if err := s.k8sNamespaceService.CheckNamespaceAccess(userID, clients.ClusterID, obj.GetNamespace()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyObject 使用 server-side apply 创建或更新单个对象，返回本次操作的动作
+func (s *SocketService) applyObject(ctx context.Context, clients *conf.K8sClients, obj *unstructured.Unstructured, defaultNamespace string) (schema.GroupVersionResource, string, error) {
+	ri, mapping, err := clients.ResourceInterface(obj, defaultNamespace)
+	if err != nil {
+		return schema.GroupVersionResource{}, "", err
+	}
+
+	// 先查询线上对象，用于区分 created / configured / unchanged
+	oldVersion := ""
+	live, err := ri.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err == nil {
+		oldVersion = live.GetResourceVersion()
+	} else if !k8serrors.IsNotFound(err) {
+		return mapping.Resource, "", err
+	}
+
+	applied, err := ri.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{
+		FieldManager: define.K8sFieldManager,
+		Force:        true,
+	})
+	if err != nil {
+		return mapping.Resource, "", err
+	}
+
+	switch {
+	case oldVersion == "":
+		return mapping.Resource, "created", nil
+	case applied.GetResourceVersion() == oldVersion:
+		return mapping.Resource, "unchanged", nil
+	default:
+		return mapping.Resource, "configured", nil
+	}
+}
+
+// createResourceFromYAML 逐个 server-side apply YAML 中的所有对象，并通过 websocket 推送每个对象的结果
+func (s *SocketService) createResourceFromYAML(conn *websocket.Conn, clients *conf.K8sClients, command string, objects []*unstructured.Unstructured, namespace string) ([]ApplyResult, error) {
+	ctx := context.TODO()
+	results := make([]ApplyResult, 0, len(objects))
+	failed := 0
+
+	for _, obj := range objects {
+		gvr, action, err := s.applyObject(ctx, clients, obj, namespace)
+		result := ApplyResult{
+			Kind:      obj.GetKind(),
+			Name:      obj.GetName(),
+			Namespace: obj.GetNamespace(),
+			Action:    action,
+			Success:   err == nil,
+		}
+
+		target := fmt.Sprintf("%s/%s", strings.ToLower(obj.GetKind()), obj.GetName())
+		if gvr.Group != "" {
+			target = fmt.Sprintf("%s.%s/%s", gvr.Resource, gvr.Group, obj.GetName())
+		} else if gvr.Resource != "" {
+			target = fmt.Sprintf("%s/%s", gvr.Resource, obj.GetName())
+		}
+
+		if err != nil {
+			failed++
+			result.Message = fmt.Sprintf("%s apply 失败: %v", target, err)
+			logrus.Errorf("resource apply error: %s", result.Message)
+			SendError(conn, result.Message)
+		} else {
+			result.Message = fmt.Sprintf("%s %s", target, action)
+			SendSuccess(conn, "object apply success", K8sCommandResponse{
+				Command: command,
+				Result:  result.Message,
+			})
+		}
+		results = append(results, result)
+	}
+
+	// 只有全部失败时才返回错误，部分失败的结果已逐个推送给客户端
+	if failed == len(objects) {
+		return results, fmt.Errorf("全部 %d 个对象 apply 失败", failed)
+	}
+	return results, nil
+}
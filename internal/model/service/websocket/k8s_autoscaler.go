@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gorilla/websocket"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// handleAutoscale 处理 kubectl autoscale 命令，立即把资源当前的扩缩容策略同步到集群
+func (s *SocketService) handleAutoscale(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	k8sResourceID, exist := data["k8s_resource_id"].(float64)
+	if !exist {
+		SendError(conn, "缺少k8s_resource_id 参数")
+		return
+	}
+
+	resource, err := s.userK8sResourceDao.QueryById(uint32(k8sResourceID))
+	if err != nil {
+		SendError(conn, fmt.Sprintf("查询资源失败: %v", err))
+		return
+	}
+	if resource.ResourceType != "deployment" {
+		SendError(conn, "只有 deployment 类型的资源可以配置自动扩缩容")
+		return
+	}
+
+	clients, err := s.clusterClientsByID(resource.ClusterID, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	logs, err := s.userK8sResourceOperationLogDao.QueryByK8sResourceIDFirst(uint(k8sResourceID))
+	if err != nil || len(logs) == 0 {
+		SendError(conn, "资源尚未部署，策略会在下一次 apply 时同步")
+		return
+	}
+	latestLog := logs[0]
+	if latestLog.OperationType == "delete" {
+		SendError(conn, fmt.Sprintf("%s 已经关闭，策略会在下一次 apply 时同步", latestLog.MetadataName))
+		return
+	}
+	if err := s.k8sNamespaceService.CheckNamespaceAccess(userID, resource.ClusterID, latestLog.Namespace); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	message, err := s.k8sResourceService.SyncAutoscaler(context.TODO(), clients, resource.Id, latestLog.Namespace, latestLog.MetadataName)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	SendSuccess(conn, "command execute success", K8sCommandResponse{
+		Command: fmt.Sprintf("%s deployment %s -n %s", command, latestLog.MetadataName, latestLog.Namespace),
+		Result:  message,
+	})
+}
+
+// findAutoscalers 查询以该 Deployment 为目标的全部 HPA，包括团队在平台外创建的
+func findAutoscalers(ctx context.Context, clients *conf.K8sClients, namespace string, deploymentName string) ([]autoscalingv2.HorizontalPodAutoscaler, error) {
+	list, err := clients.Client.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var hpas []autoscalingv2.HorizontalPodAutoscaler
+	for _, hpa := range list.Items {
+		target := hpa.Spec.ScaleTargetRef
+		if target.Kind == "Deployment" && target.Name == deploymentName {
+			hpas = append(hpas, hpa)
+		}
+	}
+	return hpas, nil
+}
+
+// formatAutoscalers kubectl get hpa 风格的 HPA 列表，附加在 Deployment 的 get 结果之后
+func formatAutoscalers(hpas []autoscalingv2.HorizontalPodAutoscaler) string {
+	if len(hpas) == 0 {
+		return ""
+	}
+	result := fmt.Sprintf("\n%-20s %-30s %-30s %-8s %-8s %-10s %-10s\n",
+		"HPA", "REFERENCE", "TARGETS", "MINPODS", "MAXPODS", "REPLICAS", "DESIRED")
+	for _, hpa := range hpas {
+		minReplicas := int32(1)
+		if hpa.Spec.MinReplicas != nil {
+			minReplicas = *hpa.Spec.MinReplicas
+		}
+		result += fmt.Sprintf("%-20s %-30s %-30s %-8d %-8d %-10d %-10d\n",
+			hpa.Name,
+			fmt.Sprintf("%s/%s", hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name),
+			formatAutoscalerTargets(&hpa),
+			minReplicas,
+			hpa.Spec.MaxReplicas,
+			hpa.Status.CurrentReplicas,
+			hpa.Status.DesiredReplicas)
+	}
+	return result
+}
+
+// formatAutoscalerDetails kubectl describe hpa 风格的 HPA 详情，附加在 Deployment 的 describe 结果之后
+func formatAutoscalerDetails(hpas []autoscalingv2.HorizontalPodAutoscaler) string {
+	var result string
+	for _, hpa := range hpas {
+		managedBy := "外部创建"
+		if hpa.Labels[k8s_manage.AutoscalerManagedByLabel] == define.K8sFieldManager {
+			managedBy = "平台创建"
+		}
+		minReplicas := int32(1)
+		if hpa.Spec.MinReplicas != nil {
+			minReplicas = *hpa.Spec.MinReplicas
+		}
+
+		result += fmt.Sprintf("HorizontalPodAutoscaler: %s (%s)\n", hpa.Name, managedBy)
+		result += fmt.Sprintf("  Metrics (current / target): %s\n", formatAutoscalerTargets(&hpa))
+		result += fmt.Sprintf("  Min replicas: %d\n", minReplicas)
+		result += fmt.Sprintf("  Max replicas: %d\n", hpa.Spec.MaxReplicas)
+		result += fmt.Sprintf("  Replicas: %d current / %d desired\n", hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas)
+		if behavior := hpa.Spec.Behavior; behavior != nil {
+			if behavior.ScaleUp != nil && behavior.ScaleUp.StabilizationWindowSeconds != nil {
+				result += fmt.Sprintf("  Scale up stabilization: %ds\n", *behavior.ScaleUp.StabilizationWindowSeconds)
+			}
+			if behavior.ScaleDown != nil && behavior.ScaleDown.StabilizationWindowSeconds != nil {
+				result += fmt.Sprintf("  Scale down stabilization: %ds\n", *behavior.ScaleDown.StabilizationWindowSeconds)
+			}
+		}
+		if hpa.Status.LastScaleTime != nil {
+			result += fmt.Sprintf("  Last scale time: %s\n", hpa.Status.LastScaleTime.Format("2006-01-02 15:04:05"))
+		}
+		result += "  Conditions:\n"
+		for _, condition := range hpa.Status.Conditions {
+			result += fmt.Sprintf("    Type: %s, Status: %s, Reason: %s, Message: %s\n",
+				condition.Type, condition.Status, condition.Reason, condition.Message)
+		}
+	}
+	return result
+}
+
+// formatAutoscalerTargets 与 kubectl get hpa 一致，按 "当前/目标" 展示资源使用率
+func formatAutoscalerTargets(hpa *autoscalingv2.HorizontalPodAutoscaler) string {
+	current := make(map[string]*int32)
+	for _, metric := range hpa.Status.CurrentMetrics {
+		if metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil {
+			current[string(metric.Resource.Name)] = metric.Resource.Current.AverageUtilization
+		}
+	}
+
+	var targets []string
+	for _, metric := range hpa.Spec.Metrics {
+		if metric.Type != autoscalingv2.ResourceMetricSourceType || metric.Resource == nil || metric.Resource.Target.AverageUtilization == nil {
+			targets = append(targets, fmt.Sprintf("%s metric", metric.Type))
+			continue
+		}
+		name := string(metric.Resource.Name)
+		value := "<unknown>"
+		if utilization := current[name]; utilization != nil {
+			value = fmt.Sprintf("%d%%", *utilization)
+		}
+		targets = append(targets, fmt.Sprintf("%s: %s/%d%%", name, value, *metric.Resource.Target.AverageUtilization))
+	}
+	if len(targets) == 0 {
+		return "<none>"
+	}
+	return strings.Join(targets, ", ")
+}
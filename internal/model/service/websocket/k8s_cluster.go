@@ -0,0 +1,26 @@
+package websocket
+
+import (
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+)
+
+// clusterClients 根据消息中的 cluster_id 获取目标集群的客户端，未指定时使用默认集群
+func (s *SocketService) clusterClients(data map[string]interface{}, userID uint) (*conf.K8sClients, error) {
+	return s.clusterClientsByID(clusterIDOf(data), userID)
+}
+
+// clusterIDOf 消息中的 cluster_id，未指定时为默认集群
+func clusterIDOf(data map[string]interface{}) uint32 {
+	if id, ok := data["cluster_id"].(float64); ok {
+		return uint32(id)
+	}
+	return conf.DefaultClusterID
+}
+
+// clusterClientsByID 校验用户对集群的访问权限后返回集群客户端
+func (s *SocketService) clusterClientsByID(clusterID uint32, userID uint) (*conf.K8sClients, error) {
+	if err := s.k8sClusterService.CanAccess(userID, clusterID); err != nil {
+		return nil, err
+	}
+	return conf.GetK8sClients(clusterID)
+}
@@ -0,0 +1,492 @@
+package websocket
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/config"
+	"github.com/ZZGADA/easy-deploy/internal/define"
+)
+
+// KubeCommand 解析后的 kubectl / helm 命令
+type KubeCommand struct {
+	Verb          string // 动词，rollout 与 helm 带子命令，如 "rollout undo"、"helm install"
+	Kind          string // 规范化后的资源类型（复数形式），如 pods
+	Name          string // 资源名称；rollout / scale / autoscale / apply 等命令为 k8s_resource_id
+	Namespace     string
+	AllNamespaces bool
+	LabelSelector string
+	FieldSelector string
+	Output        string
+	Flags         map[string]string // 动词专用的参数，key 为规范化后的长参数名
+}
+
+// flagSpec 命令行参数：名称为规范化的长参数名，bool 参数不带值，optional 参数的值可以省略
+type flagSpec struct {
+	name     string
+	isBool   bool
+	optional bool
+}
+
+// 通用参数
+var (
+	namespaceFlag     = flagSpec{name: "namespace"}
+	allNamespacesFlag = flagSpec{name: "all-namespaces", isBool: true}
+	selectorFlag      = flagSpec{name: "selector"}
+	fieldSelectorFlag = flagSpec{name: "field-selector"}
+	outputFlag        = flagSpec{name: "output"}
+	filenameFlag      = flagSpec{name: "filename", optional: true} // 前端发送 kubectl apply -f，资源 ID 在 websocket 参数中
+	renderFlags       = map[string]flagSpec{
+		"--env":             {name: "env"},
+		"--docker-image-id": {name: "docker-image-id"},
+		"--overlay":         {name: "overlay"},
+	}
+)
+
+// verbSpec 动词支持的资源类型和参数
+type verbSpec struct {
+	kinds        []string // 可用的资源类型，为空表示不接受资源类型
+	kindOptional bool     // 资源类型可省略，省略时由 websocket 参数指定对象
+	maxArgs      int      // 资源类型之后最多的位置参数个数
+	flags        map[string]flagSpec
+}
+
+// kindAliases 资源类型的别名，与 kubectl 一致
+var kindAliases = map[string]string{
+	"pod": "pods", "pods": "pods", "po": "pods",
+	"service": "services", "services": "services", "svc": "services",
+	"deployment": "deployments", "deployments": "deployments", "deploy": "deployments",
+	"ingress": "ingresses", "ingresses": "ingresses", "ing": "ingresses",
+	"statefulset": "statefulsets", "statefulsets": "statefulsets", "sts": "statefulsets",
+	"daemonset": "daemonsets", "daemonsets": "daemonsets", "ds": "daemonsets",
+	"job": "jobs", "jobs": "jobs",
+	"cronjob": "cronjobs", "cronjobs": "cronjobs", "cj": "cronjobs",
+	"namespace": "namespaces", "namespaces": "namespaces", "ns": "namespaces",
+	"node": "nodes", "nodes": "nodes", "no": "nodes",
+	"horizontalpodautoscaler": "horizontalpodautoscalers", "horizontalpodautoscalers": "horizontalpodautoscalers", "hpa": "horizontalpodautoscalers",
+	"event": "events", "events": "events", "ev": "events",
+}
+
+// clusterScopedKinds 集群级资源，不接受 namespace
+var clusterScopedKinds = map[string]bool{"namespaces": true, "nodes": true}
+
+// flags 合并参数表
+func flags(tables ...map[string]flagSpec) map[string]flagSpec {
+	merged := make(map[string]flagSpec)
+	for _, table := range tables {
+		for key, spec := range table {
+			merged[key] = spec
+		}
+	}
+	return merged
+}
+
+var (
+	listFlags = map[string]flagSpec{
+		"-n": namespaceFlag, "--namespace": namespaceFlag,
+		"-A": allNamespacesFlag, "--all-namespaces": allNamespacesFlag,
+		"-l": selectorFlag, "--selector": selectorFlag,
+		"--field-selector": fieldSelectorFlag,
+		"-o":               outputFlag, "--output": outputFlag,
+	}
+	outputFlags = map[string]flagSpec{"-o": outputFlag, "--output": outputFlag}
+	fileFlags   = map[string]flagSpec{"-f": filenameFlag, "--filename": filenameFlag}
+	watchFlag   = flagSpec{name: "watch", isBool: true}
+)
+
+// kubectlVerbs 支持的 kubectl 动词
+var kubectlVerbs = map[string]verbSpec{
+	"get": {
+		kinds:        []string{"pods", "services", "deployments", "ingresses", "statefulsets", "daemonsets", "jobs", "cronjobs", "namespaces", "nodes", "horizontalpodautoscalers", "events"},
+		kindOptional: true,
+		maxArgs:      1,
+		flags:        listFlags,
+	},
+	"describe": {
+		kinds:        []string{"pods", "services", "deployments", "ingresses", "statefulsets", "daemonsets", "jobs", "cronjobs"},
+		kindOptional: true,
+		maxArgs:      1,
+		flags:        flags(outputFlags, map[string]flagSpec{"-n": namespaceFlag, "--namespace": namespaceFlag}),
+	},
+	"top": {
+		kinds:   []string{"pods", "nodes"},
+		maxArgs: 0,
+		flags:   flags(outputFlags, map[string]flagSpec{"-n": namespaceFlag, "--namespace": namespaceFlag, "-A": allNamespacesFlag, "--all-namespaces": allNamespacesFlag, "-l": selectorFlag, "--selector": selectorFlag}),
+	},
+	"logs": {
+		maxArgs: 1,
+		flags: map[string]flagSpec{
+			"-n": namespaceFlag, "--namespace": namespaceFlag,
+			"-c": {name: "container"}, "--container": {name: "container"},
+			"-f": {name: "follow", isBool: true}, "--follow": {name: "follow", isBool: true},
+			"-p": {name: "previous", isBool: true}, "--previous": {name: "previous", isBool: true},
+			"--tail":  {name: "tail"},
+			"--since": {name: "since"},
+		},
+	},
+	"apply":        {maxArgs: 0, flags: flags(fileFlags, outputFlags, renderFlags)},
+	"diff":         {maxArgs: 0, flags: flags(fileFlags, renderFlags)},
+	"delete":       {maxArgs: 1, flags: fileFlags},
+	"autoscale":    {maxArgs: 1, flags: outputFlags},
+	"scale":        {maxArgs: 1, flags: map[string]flagSpec{"--replicas": {name: "replicas"}}},
+	"events":       {maxArgs: 1, flags: flags(outputFlags, map[string]flagSpec{"-w": watchFlag, "--watch": watchFlag})},
+	"drift":        {maxArgs: 1, flags: outputFlags},
+	"reconcile":    {maxArgs: 1, flags: outputFlags},
+	"cluster-info": {maxArgs: 0, flags: outputFlags},
+}
+
+// rolloutVerbs kubectl rollout 的子命令，位置参数为 k8s_resource_id
+var rolloutVerbs = map[string]verbSpec{
+	"history": {maxArgs: 1, flags: outputFlags},
+	"undo":    {maxArgs: 1, flags: flags(outputFlags, renderFlags, map[string]flagSpec{"--to-revision": {name: "to-revision"}})},
+	"restart": {maxArgs: 1},
+	"pause":   {maxArgs: 1},
+	"resume":  {maxArgs: 1},
+	"status":  {maxArgs: 1},
+}
+
+// helmVerbs 支持的 helm 子命令，release 信息来自资源配置
+var helmVerbs = map[string]verbSpec{
+	"install":   {maxArgs: 0, flags: helmWaitFlags},
+	"upgrade":   {maxArgs: 0, flags: helmWaitFlags},
+	"rollback":  {maxArgs: 1, flags: helmWaitFlags},
+	"uninstall": {maxArgs: 0},
+	"history":   {maxArgs: 0},
+}
+
+var helmWaitFlags = map[string]flagSpec{"--wait": {name: "wait", isBool: true}}
+
+// clusterScopeVerb 白名单中的伪动词：允许 get / describe / top 使用 -A 查看全部 namespace，以及查看 nodes、namespaces 等集群级资源。
+// 没有该权限时这些命令只能查看团队自己的 namespace
+const clusterScopeVerb = "cluster-scope"
+
+// defaultCommandAllowlist 未配置 k8s_command.allowlist 时的默认白名单，只有团队创建者可以查看集群级资源
+var defaultCommandAllowlist = map[string][]string{
+	define.TeamRoleCreator: {"*"},
+	define.TeamRoleMember: {"get", "describe", "events", "logs", "top", "cluster-info", "diff", "apply", "delete", "rollout history", "rollout undo", "autoscale",
+		"scale", "rollout restart", "rollout pause", "rollout resume", "rollout status", "drift", "reconcile",
+		"helm install", "helm upgrade", "helm rollback", "helm uninstall", "helm history"},
+	define.TeamRoleNone: {"get", "describe", "events", "top", "cluster-info", "rollout status", "drift"},
+}
+
+// ParseKubeCommand 解析 kubectl / helm 命令，不支持的动词、资源类型和参数返回错误
+func ParseKubeCommand(command string) (*KubeCommand, error) {
+	tokens, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) < 2 {
+		return nil, fmt.Errorf("命令不完整: %q", command)
+	}
+
+	var spec verbSpec
+	var verb string
+	var ok bool
+	rest := tokens[2:]
+	switch tokens[0] {
+	case "kubectl":
+		verb = tokens[1]
+		if verb == "rollout" {
+			if len(rest) == 0 {
+				return nil, fmt.Errorf("kubectl rollout 缺少子命令，可选: history / undo / restart / pause / resume / status")
+			}
+			spec, ok = rolloutVerbs[rest[0]]
+			if !ok {
+				return nil, fmt.Errorf("不支持的命令 kubectl rollout %s，可选: history / undo / restart / pause / resume / status", rest[0])
+			}
+			verb = "rollout " + rest[0]
+			rest = rest[1:]
+		} else if spec, ok = kubectlVerbs[verb]; !ok {
+			return nil, fmt.Errorf("不支持的命令 kubectl %s", verb)
+		}
+	case "helm":
+		if spec, ok = helmVerbs[tokens[1]]; !ok {
+			return nil, fmt.Errorf("不支持的命令 helm %s", tokens[1])
+		}
+		verb = "helm " + tokens[1]
+	default:
+		return nil, fmt.Errorf("只支持 kubectl 和 helm 命令: %q", command)
+	}
+
+	cmd := &KubeCommand{Verb: verb, Flags: make(map[string]string)}
+	args, err := parseFlags(cmd, spec, verb, rest)
+	if err != nil {
+		return nil, err
+	}
+
+	// 第一个位置参数为资源类型，支持 kind/name 写法
+	if len(spec.kinds) > 0 && len(args) > 0 {
+		kind, name, hasName := strings.Cut(args[0], "/")
+		cmd.Kind, ok = kindAliases[strings.ToLower(kind)]
+		if !ok || !containsString(spec.kinds, cmd.Kind) {
+			return nil, fmt.Errorf("kubectl %s 不支持资源类型 %s，可选: %s", verb, kind, strings.Join(spec.kinds, ", "))
+		}
+		args = args[1:]
+		if hasName {
+			if name == "" || len(args) > 0 {
+				return nil, fmt.Errorf("资源名称不合法: %s", strings.Join(append([]string{kind + "/" + name}, args...), " "))
+			}
+			args = []string{name}
+		}
+	} else if len(spec.kinds) > 0 && !spec.kindOptional {
+		return nil, fmt.Errorf("kubectl %s 缺少资源类型，可选: %s", verb, strings.Join(spec.kinds, ", "))
+	}
+
+	if len(args) > spec.maxArgs {
+		return nil, fmt.Errorf("%s 的参数过多: %s", verb, strings.Join(args, " "))
+	}
+	if len(args) == 1 {
+		cmd.Name = args[0]
+	}
+
+	if clusterScopedKinds[cmd.Kind] && (cmd.Namespace != "" || cmd.AllNamespaces) {
+		return nil, fmt.Errorf("%s 是集群级资源，不能指定 namespace", cmd.Kind)
+	}
+	if cmd.Namespace != "" && cmd.AllNamespaces {
+		return nil, fmt.Errorf("-n 与 -A 不能同时使用")
+	}
+	if cmd.Output != "" {
+		if _, err := parseOutput(map[string]interface{}{"output": cmd.Output}); err != nil {
+			return nil, err
+		}
+	}
+	return cmd, nil
+}
+
+// parseFlags 解析参数，支持 -n foo、-n=foo、--namespace=foo 和 -ojson 写法，返回剩余的位置参数
+func parseFlags(cmd *KubeCommand, spec verbSpec, verb string, tokens []string) ([]string, error) {
+	var args []string
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if !strings.HasPrefix(token, "-") || token == "-" {
+			args = append(args, token)
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(token, "=")
+		flag, ok := spec.flags[key]
+		// 短参数直接跟值，如 -ojson、-nfoo
+		if !ok && !strings.HasPrefix(token, "--") && len(token) > 2 {
+			if short, exist := spec.flags[token[:2]]; exist && !short.isBool {
+				flag, ok, value, hasValue = short, true, token[2:], true
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s 不支持参数 %s", verb, key)
+		}
+
+		if flag.isBool {
+			if hasValue && value != "true" && value != "false" {
+				return nil, fmt.Errorf("参数 %s 的值只能为 true 或 false", key)
+			}
+			if !hasValue {
+				value = "true"
+			}
+		} else if !hasValue {
+			if i+1 >= len(tokens) || (flag.optional && strings.HasPrefix(tokens[i+1], "-")) {
+				if !flag.optional {
+					return nil, fmt.Errorf("参数 %s 缺少值", key)
+				}
+			} else {
+				i++
+				value = tokens[i]
+			}
+		}
+
+		switch flag.name {
+		case namespaceFlag.name:
+			cmd.Namespace = value
+		case allNamespacesFlag.name:
+			cmd.AllNamespaces = value == "true"
+		case selectorFlag.name:
+			cmd.LabelSelector = value
+		case fieldSelectorFlag.name:
+			cmd.FieldSelector = value
+		case outputFlag.name:
+			cmd.Output = value
+		default:
+			cmd.Flags[flag.name] = value
+		}
+	}
+	return args, nil
+}
+
+// splitCommand 按空白拆分命令，支持单引号和双引号
+func splitCommand(command string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	var quote rune
+	inToken := false
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inToken = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("命令中的引号不匹配: %q", command)
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// applyTo 将命令行中的参数写入 websocket 参数，命令行优先；资源 ID 类参数必须为数字
+func (c *KubeCommand) applyTo(data map[string]interface{}) error {
+	if c.Namespace != "" {
+		data["namespace"] = c.Namespace
+	}
+	if c.AllNamespaces {
+		data["namespace"] = ""
+	}
+	if c.LabelSelector != "" {
+		data["label_selector"] = c.LabelSelector
+	}
+	if c.FieldSelector != "" {
+		data["field_selector"] = c.FieldSelector
+	}
+	if c.Output != "" {
+		data["output"] = c.Output
+	}
+
+	// 资源 ID 可以写在 -f 或位置参数中，如 kubectl apply -f 12、kubectl rollout undo 12
+	resourceID := c.Flags["filename"]
+	if resourceID == "" && c.Kind == "" && c.Verb != "logs" && !strings.HasPrefix(c.Verb, "helm ") {
+		resourceID = c.Name
+	}
+	if c.Verb == "helm rollback" && c.Name != "" {
+		revision, err := strconv.Atoi(c.Name)
+		if err != nil || revision < 1 {
+			return fmt.Errorf("helm rollback 的版本号不合法: %s", c.Name)
+		}
+		data["revision"] = float64(revision)
+	}
+	if resourceID != "" {
+		id, err := strconv.ParseUint(resourceID, 10, 32)
+		if err != nil || id == 0 {
+			return fmt.Errorf("%s 需要资源 ID，%s 不是合法的资源 ID", c.Verb, resourceID)
+		}
+		data["k8s_resource_id"] = float64(id)
+	}
+
+	if c.Verb == "logs" && c.Name != "" {
+		data["pod_name"] = c.Name
+	}
+	for flag, key := range map[string]string{"container": "container", "env": "env", "overlay": "overlay"} {
+		if value, ok := c.Flags[flag]; ok {
+			data[key] = value
+		}
+	}
+	for flag, key := range map[string]string{"follow": "follow", "previous": "previous", "wait": "wait", "watch": "watch"} {
+		if value, ok := c.Flags[flag]; ok {
+			data[key] = value == "true"
+		}
+	}
+	for flag, key := range map[string]string{"tail": "tail_lines", "docker-image-id": "docker_image_id", "to-revision": "to_resource_id", "replicas": "replicas"} {
+		if value, ok := c.Flags[flag]; ok {
+			number, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return fmt.Errorf("参数 --%s 需要数字: %s", flag, value)
+			}
+			data[key] = float64(number)
+		}
+	}
+	if value, ok := c.Flags["since"]; ok {
+		since, err := time.ParseDuration(value)
+		if err != nil || since <= 0 {
+			return fmt.Errorf("参数 --since 不合法: %s", value)
+		}
+		data["since_seconds"] = since.Seconds()
+	}
+	return nil
+}
+
+// checkCommandAllowed 按用户的团队角色校验动词是否在白名单中
+func (s *SocketService) checkCommandAllowed(userID uint, cmd *KubeCommand) error {
+	role, err := s.k8sNamespaceService.TeamRole(userID)
+	if err != nil {
+		return fmt.Errorf("查询团队角色失败: %v", err)
+	}
+	if !verbAllowed(role, cmd.Verb) {
+		return fmt.Errorf("团队角色 %s 不允许执行 %s", role, cmd.Verb)
+	}
+	return nil
+}
+
+// checkCommandScope 直接查询集群对象的 get / describe / top 只能作用于团队的 namespace；
+// -A、nodes 和 namespaces 需要团队角色的白名单中包含 cluster-scope
+func (s *SocketService) checkCommandScope(userID uint, clusterID uint32, cmd *KubeCommand, data map[string]interface{}) error {
+	// 不带资源类型的 get / describe 查询平台管理的资源，处理函数只读取调用者自己的运行中资源列表
+	if cmd.Kind == "" {
+		return nil
+	}
+	var namespace string
+	switch cmd.Verb {
+	case "get", "describe":
+		namespace = commandNamespace(cmd, data)
+	case "top":
+		// kubectl top pod 未指定 namespace 时查询全部 namespace
+		if !clusterScopedKinds[cmd.Kind] {
+			namespace, _ = data["namespace"].(string)
+		}
+	default:
+		return nil
+	}
+
+	if namespace != "" {
+		return s.k8sNamespaceService.CheckNamespaceAccess(userID, clusterID, namespace)
+	}
+	role, err := s.k8sNamespaceService.TeamRole(userID)
+	if err != nil {
+		return fmt.Errorf("查询团队角色失败: %v", err)
+	}
+	if !verbAllowed(role, clusterScopeVerb) {
+		if clusterScopedKinds[cmd.Kind] {
+			return fmt.Errorf("团队角色 %s 不允许查看集群级资源 %s", role, cmd.Kind)
+		}
+		return fmt.Errorf("团队角色 %s 不允许查看全部 namespace，请使用 -n 指定团队的 namespace", role)
+	}
+	return nil
+}
+
+// verbAllowed 动词是否在团队角色的白名单中
+func verbAllowed(role string, target string) bool {
+	allowlist := config.GlobalConfig.K8sCommand.Allowlist
+	if allowlist == nil {
+		allowlist = defaultCommandAllowlist
+	}
+	for _, verb := range allowlist[role] {
+		if verb == "*" || verb == target {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
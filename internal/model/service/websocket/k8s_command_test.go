@@ -0,0 +1,207 @@
+package websocket
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ZZGADA/easy-deploy/internal/config"
+	"github.com/ZZGADA/easy-deploy/internal/define"
+)
+
+func TestParseKubeCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    *KubeCommand
+		wantErr bool
+	}{
+		{
+			name:    "get 使用别名和 -n",
+			command: "kubectl get po -n team-a",
+			want:    &KubeCommand{Verb: "get", Kind: "pods", Namespace: "team-a", Flags: map[string]string{}},
+		},
+		{
+			name:    "get 支持 kind/name 和 -ojson",
+			command: "kubectl get deploy/web -ojson",
+			want:    &KubeCommand{Verb: "get", Kind: "deployments", Name: "web", Output: "json", Flags: map[string]string{}},
+		},
+		{
+			name:    "get 全部 namespace 和 label selector",
+			command: "kubectl get svc -A -l app=web",
+			want:    &KubeCommand{Verb: "get", Kind: "services", AllNamespaces: true, LabelSelector: "app=web", Flags: map[string]string{}},
+		},
+		{
+			name:    "引号中的参数值",
+			command: `kubectl get pods --field-selector "status.phase=Running"`,
+			want:    &KubeCommand{Verb: "get", Kind: "pods", FieldSelector: "status.phase=Running", Flags: map[string]string{}},
+		},
+		{
+			name:    "不带资源类型的 get 查询平台资源",
+			command: "kubectl get",
+			want:    &KubeCommand{Verb: "get", Flags: map[string]string{}},
+		},
+		{
+			name:    "apply 的 -f 可以省略值",
+			command: "kubectl apply -f --env prod",
+			want:    &KubeCommand{Verb: "apply", Flags: map[string]string{"filename": "", "env": "prod"}},
+		},
+		{
+			name:    "rollout 子命令",
+			command: "kubectl rollout undo 12 --to-revision=3",
+			want:    &KubeCommand{Verb: "rollout undo", Name: "12", Flags: map[string]string{"to-revision": "3"}},
+		},
+		{
+			name:    "logs 参数",
+			command: "kubectl logs web-0 -c app -f --tail 100",
+			want:    &KubeCommand{Verb: "logs", Name: "web-0", Flags: map[string]string{"container": "app", "follow": "true", "tail": "100"}},
+		},
+		{
+			name:    "helm rollback 带版本号",
+			command: "helm rollback 2 --wait",
+			want:    &KubeCommand{Verb: "helm rollback", Name: "2", Flags: map[string]string{"wait": "true"}},
+		},
+		{name: "不支持的程序", command: "bash -c ls", wantErr: true},
+		{name: "命令不完整", command: "kubectl", wantErr: true},
+		{name: "不支持的动词", command: "kubectl exec web -- sh", wantErr: true},
+		{name: "不支持的资源类型", command: "kubectl get secrets", wantErr: true},
+		{name: "不支持的参数", command: "kubectl get pods --kubeconfig /tmp/config", wantErr: true},
+		{name: "缺少资源类型", command: "kubectl top", wantErr: true},
+		{name: "参数过多", command: "kubectl get pods a b", wantErr: true},
+		{name: "集群级资源不能指定 namespace", command: "kubectl get nodes -n team-a", wantErr: true},
+		{name: "-n 与 -A 不能同时使用", command: "kubectl get pods -n team-a -A", wantErr: true},
+		{name: "不支持的 output", command: "kubectl get pods -o name", wantErr: true},
+		{name: "bool 参数的值不合法", command: "kubectl logs web -f=yes", wantErr: true},
+		{name: "参数缺少值", command: "kubectl get pods -n", wantErr: true},
+		{name: "引号不匹配", command: `kubectl get pods -l "app=web`, wantErr: true},
+		{name: "rollout 缺少子命令", command: "kubectl rollout", wantErr: true},
+		{name: "不支持的 helm 子命令", command: "helm template", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKubeCommand(tt.command)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseKubeCommand(%q) = %+v, want error", tt.command, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKubeCommand(%q) error: %v", tt.command, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseKubeCommand(%q) = %+v, want %+v", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubeCommandApplyTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:    "资源 ID 写在 -f 中",
+			command: "kubectl apply -f 12 --env prod --docker-image-id 3",
+			want:    map[string]interface{}{"k8s_resource_id": float64(12), "env": "prod", "docker_image_id": float64(3)},
+		},
+		{
+			name:    "资源 ID 写在位置参数中",
+			command: "kubectl rollout undo 12 --to-revision 8",
+			want:    map[string]interface{}{"k8s_resource_id": float64(12), "to_resource_id": float64(8)},
+		},
+		{
+			name:    "-A 清空 namespace",
+			command: "kubectl get pods -A",
+			want:    map[string]interface{}{"namespace": ""},
+		},
+		{
+			name:    "logs 的 Pod 名称和 since",
+			command: "kubectl logs web-0 --since 1m -p",
+			want:    map[string]interface{}{"pod_name": "web-0", "since_seconds": float64(60), "previous": true},
+		},
+		{
+			name:    "helm rollback 的版本号",
+			command: "helm rollback 2",
+			want:    map[string]interface{}{"revision": float64(2)},
+		},
+		{name: "资源 ID 不是数字", command: "kubectl apply -f app.yaml", wantErr: true},
+		{name: "资源 ID 为 0", command: "kubectl rollout undo 0", wantErr: true},
+		{name: "helm 版本号不合法", command: "helm rollback 0", wantErr: true},
+		{name: "数字参数不合法", command: "kubectl scale 12 --replicas=two", wantErr: true},
+		{name: "since 不合法", command: "kubectl logs web-0 --since=-1m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := ParseKubeCommand(tt.command)
+			if err != nil {
+				t.Fatalf("ParseKubeCommand(%q) error: %v", tt.command, err)
+			}
+			data := make(map[string]interface{})
+			err = cmd.applyTo(data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("applyTo(%q) = %v, want error", tt.command, data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyTo(%q) error: %v", tt.command, err)
+			}
+			if !reflect.DeepEqual(data, tt.want) {
+				t.Errorf("applyTo(%q) = %v, want %v", tt.command, data, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerbAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist map[string][]string
+		role      string
+		verb      string
+		want      bool
+	}{
+		{name: "创建者允许全部", role: define.TeamRoleCreator, verb: "helm uninstall", want: true},
+		{name: "创建者可以查看集群级资源", role: define.TeamRoleCreator, verb: clusterScopeVerb, want: true},
+		{name: "成员可以 apply", role: define.TeamRoleMember, verb: "apply", want: true},
+		{name: "成员可以 delete", role: define.TeamRoleMember, verb: "delete", want: true},
+		{name: "成员可以 helm uninstall", role: define.TeamRoleMember, verb: "helm uninstall", want: true},
+		{name: "成员不能查看集群级资源", role: define.TeamRoleMember, verb: clusterScopeVerb, want: false},
+		{name: "没有团队时只读", role: define.TeamRoleNone, verb: "get", want: true},
+		{name: "没有团队时不能 apply", role: define.TeamRoleNone, verb: "apply", want: false},
+		{name: "没有团队时不能查看日志", role: define.TeamRoleNone, verb: "logs", want: false},
+		{name: "未知角色", role: "guest", verb: "get", want: false},
+		{
+			name:      "配置的白名单覆盖默认值",
+			allowlist: map[string][]string{define.TeamRoleMember: {"get"}},
+			role:      define.TeamRoleMember,
+			verb:      "apply",
+			want:      false,
+		},
+		{
+			name:      "配置的白名单中的 cluster-scope",
+			allowlist: map[string][]string{define.TeamRoleMember: {"get", clusterScopeVerb}},
+			role:      define.TeamRoleMember,
+			verb:      clusterScopeVerb,
+			want:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := config.GlobalConfig.K8sCommand.Allowlist
+			config.GlobalConfig.K8sCommand.Allowlist = tt.allowlist
+			defer func() { config.GlobalConfig.K8sCommand.Allowlist = previous }()
+
+			if got := verbAllowed(tt.role, tt.verb); got != tt.want {
+				t.Errorf("verbAllowed(%q, %q) = %v, want %v", tt.role, tt.verb, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package websocket
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/scheduled_tasks"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// driftCheckInterval 差异检测的间隔
+const driftCheckInterval = 5 * time.Minute
+
+// ResourceDrift 推送给团队成员的差异通知，Objects 为存在差异的对象，已恢复一致时为空
+type ResourceDrift struct {
+	ResourceID       uint32       `json:"resource_id"`
+	TargetResourceID uint32       `json:"target_resource_id"`
+	ResourceName     string       `json:"resource_name"`
+	ResourceType     string       `json:"resource_type"`
+	Namespace        string       `json:"namespace"`
+	Resolution       string       `json:"resolution,omitempty"`
+	Objects          []ObjectPlan `json:"objects,omitempty"`
+}
+
+// StartDriftDetector 定时检测平台部署的资源是否在平台外被修改
+func (s *SocketService) StartDriftDetector() {
+	go func() {
+		ticker := time.NewTicker(driftCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.detectDrifts()
+		}
+	}()
+	logrus.Info("K8s 资源差异检测器已启动")
+}
+
+// detectDrifts 检测一轮所有运行中资源的差异：每个资源从当前版本沿版本链找到线上运行的版本再比较，
+// 保存了新版本但尚未 apply 时比较之前部署的版本；helm 资源由 release 管理不参与比较
+func (s *SocketService) detectDrifts() {
+	resources, err := s.userK8sResourceDao.QueryAll()
+	if err != nil {
+		logrus.Errorf("查询 K8s 资源失败: %v", err)
+		return
+	}
+
+	ctx := context.TODO()
+	for _, resource := range resources {
+		if resource.IsUpdate || resource.ResourceType == "helm" {
+			continue
+		}
+		state, err := s.k8sResourceService.DeployedState(resource)
+		if err != nil {
+			logrus.Warnf("查询资源 %d 的部署版本失败: %v", resource.Id, err)
+			continue
+		}
+		if state == nil {
+			continue
+		}
+		logs, err := s.userK8sResourceOperationLogDao.QueryByK8sResourceIDFirst(uint(state.Resource.Id))
+		if err != nil || len(logs) == 0 || logs[0].OperationType == "delete" {
+			continue
+		}
+
+		clients, err := conf.GetK8sClients(resource.ClusterID)
+		if err != nil {
+			logrus.Warnf("获取集群 %d 失败: %v", resource.ClusterID, err)
+			continue
+		}
+		drift, plan, err := s.resourceDrift(ctx, clients, resource, state, logs[0])
+		if err != nil {
+			logrus.Warnf("检测资源 %d 的差异失败: %v", resource.Id, err)
+			continue
+		}
+		s.recordDrift(resource, drift, plan)
+	}
+}
+
+// deployedState 资源当前部署的内容，尚未部署的资源不能比较或恢复
+func (s *SocketService) deployedState(resource *dao.UserK8sResource) (*k8s_manage.DeployedState, error) {
+	state, err := s.k8sResourceService.DeployedState(resource)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, fmt.Errorf("资源尚未部署")
+	}
+	return state, nil
+}
+
+// resourceDrift 对资源当前部署的内容做 server-side dry-run 并与线上对象比较，比较方式与 kubectl diff 相同，
+// 只包含重新 apply 会改变的字段，API Server 填充的默认值和其他 field manager 管理的字段不算差异。
+// 归属标签按部署记录所在的版本写入，与 apply 时一致
+func (s *SocketService) resourceDrift(ctx context.Context, clients *conf.K8sClients, resource *dao.UserK8sResource, state *k8s_manage.DeployedState, latestLog *dao.UserK8sResourceOperationLog) (*ResourceDrift, *ApplyPlan, error) {
+	objects, namespace, _, err := s.loadResourceObjects(clients, state.Resource.Id, state.Version, state.Options, state.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.k8sResourceService.KeepLiveReplicas(ctx, clients, resource.Id, namespace, k8s_manage.PrimaryObject(objects, state.Version.ResourceType)); err != nil {
+		return nil, nil, err
+	}
+
+	drift := &ResourceDrift{
+		ResourceID:       resource.Id,
+		TargetResourceID: state.Version.Id,
+		ResourceName:     latestLog.MetadataName,
+		ResourceType:     resource.ResourceType,
+		Namespace:        latestLog.Namespace,
+	}
+	plan := &ApplyPlan{K8sResourceID: resource.Id}
+	for _, obj := range objects {
+		objectPlan := s.planObject(ctx, clients, obj, namespace)
+		switch objectPlan.Action {
+		case "create":
+			// 线上对象在平台外被删除
+			plan.Create++
+			drift.Objects = append(drift.Objects, objectPlan)
+		case "change":
+			plan.Change++
+			drift.Objects = append(drift.Objects, objectPlan)
+		case "unchanged":
+			plan.Unchanged++
+		default:
+			plan.Failed++
+		}
+		plan.Objects = append(plan.Objects, objectPlan)
+	}
+	if plan.Failed > 0 && len(drift.Objects) == 0 {
+		return nil, nil, fmt.Errorf("%d 个对象 dry-run 失败", plan.Failed)
+	}
+	return drift, plan, nil
+}
+
+// recordDrift 记录差异：新出现或内容变化时推送给团队成员，差异消失时标记为已恢复
+func (s *SocketService) recordDrift(resource *dao.UserK8sResource, drift *ResourceDrift, plan *ApplyPlan) {
+	open, err := s.userK8sResourceDriftDao.QueryOpen(resource.Id)
+	if err != nil {
+		logrus.Errorf("查询资源 %d 的差异记录失败: %v", resource.Id, err)
+		return
+	}
+
+	now := time.Now()
+	if len(drift.Objects) == 0 {
+		if open == nil {
+			return
+		}
+		if err := s.userK8sResourceDriftDao.Resolve(resource.Id, "converged", &now); err != nil {
+			logrus.Errorf("更新资源 %d 的差异记录失败: %v", resource.Id, err)
+			return
+		}
+		drift.Resolution = "converged"
+		s.pushDrift(uint(resource.UserID), "resource_drift_resolved", drift)
+		return
+	}
+
+	objects, err := json.Marshal(drift.Objects)
+	if err != nil {
+		logrus.Errorf("序列化资源 %d 的差异失败: %v", resource.Id, err)
+		return
+	}
+	sum := sha256.Sum256(objects)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	if open == nil {
+		err = s.userK8sResourceDriftDao.Create(&dao.UserK8sResourceDrift{
+			K8sResourceID:    resource.Id,
+			TargetResourceID: drift.TargetResourceID,
+			ClusterID:        resource.ClusterID,
+			Namespace:        drift.Namespace,
+			MetadataName:     drift.ResourceName,
+			Objects:          string(objects),
+			Fingerprint:      fingerprint,
+			FirstDetected:    &now,
+			LastDetected:     &now,
+		})
+	} else {
+		err = s.userK8sResourceDriftDao.UpdateDetected(open.Id, string(objects), fingerprint, &now)
+	}
+	if err != nil {
+		logrus.Errorf("保存资源 %d 的差异记录失败: %v", resource.Id, err)
+		return
+	}
+
+	// 同样的差异只通知一次
+	if open == nil || open.Fingerprint != fingerprint {
+		logrus.Infof("资源 %d 与版本 %d 存在差异: %d 个对象", resource.Id, drift.TargetResourceID, len(drift.Objects))
+		s.pushDrift(uint(resource.UserID), "resource_drift_detected", drift)
+	}
+}
+
+// pushDrift 将差异通知推送给资源所属用户的团队成员
+func (s *SocketService) pushDrift(userID uint, messageType string, drift *ResourceDrift) {
+	users, err := s.teamMembers(userID)
+	if err != nil {
+		logrus.Errorf("获取用户 %d 的团队成员失败: %v", userID, err)
+		return
+	}
+	for _, user := range users {
+		conn, exists := conf.WSServer.Connections[uint(user.Id)]
+		if !exists {
+			continue
+		}
+
+		response := map[string]interface{}{
+			"success": true,
+			"message": messageType,
+			"data": map[string]interface{}{
+				"type":      messageType,
+				"drift":     drift,
+				"timestamp": time.Now().Unix(),
+			},
+		}
+		if err := conf.WSServer.WriteJSON(conn, response); err != nil {
+			logrus.Errorf("向用户 %d 推送资源差异失败: %v", user.Id, err)
+		}
+	}
+}
+
+// teamMembers 获取用户所在团队的全部成员，没有团队时只返回用户本人
+func (s *SocketService) teamMembers(userID uint) ([]*dao.Users, error) {
+	user, err := s.usersDao.GetUserByID(uint32(userID))
+	if err != nil {
+		return nil, err
+	}
+	if user.TeamID == 0 {
+		return []*dao.Users{user}, nil
+	}
+	return s.usersDao.GetUsersByTeamID(user.TeamID)
+}
+
+// handleDrift 处理 kubectl drift 命令，立即检测资源与当前部署版本的差异
+func (s *SocketService) handleDrift(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	resource, clients, latestLog, err := s.loadDeployedResource(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	if resource.ResourceType == "helm" {
+		SendError(conn, "helm 类型的资源由 release 管理，请使用 helm history")
+		return
+	}
+
+	state, err := s.deployedState(resource)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	drift, plan, err := s.resourceDrift(context.TODO(), clients, resource, state, latestLog)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	s.recordDrift(resource, drift, plan)
+
+	output, _ := parseOutput(data)
+	sendCommandOutput(conn, fmt.Sprintf("kubectl drift %d", resource.Id), output, drift, func(bool) string {
+		if len(drift.Objects) == 0 && plan.Failed == 0 {
+			return fmt.Sprintf("资源 %s 与版本 %d 一致，没有差异\n", drift.ResourceName, drift.TargetResourceID)
+		}
+		return fmt.Sprintf("资源 %s 与版本 %d 存在差异，kubectl reconcile %d 会执行以下修改:\n", drift.ResourceName, drift.TargetResourceID, resource.Id) +
+			formatResourcePlan(plan)
+	})
+}
+
+// handleReconcile 处理 kubectl reconcile 命令，重新 apply 资源当前部署的版本，消除平台外的修改
+func (s *SocketService) handleReconcile(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	logrus.Info("resource reconcile ", "data: ", data)
+	resource, clients, _, err := s.loadDeployedResource(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	if resource.ResourceType == "helm" {
+		SendError(conn, "helm 类型的资源由 release 管理，请使用 helm upgrade")
+		return
+	}
+
+	state, err := s.deployedState(resource)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	applied, err := s.applyStoredResource(conn, clients, command, resource.Id, state.Version, state.Options, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	// 操作日志指向重新 apply 的版本，回滚后仍为回滚的目标版本；apply 后立即记录，rollout 结束后更新状态
+	operationLog := applied.operationLog(uint(resource.Id), userID, "reconcile")
+	if state.Version.Id != resource.Id {
+		operationLog.TargetResourceID = uint(state.Version.Id)
+	}
+	output, _ := parseOutput(data)
+	s.finishApply(conn, userID, clients, state.Version.ResourceType, applied, operationLog, func(connected bool) {
+		now := time.Now()
+		if err := s.userK8sResourceDriftDao.Resolve(resource.Id, "reconciled", &now); err != nil {
+			logrus.Errorf("更新资源 %d 的差异记录失败: %v", resource.Id, err)
+		}
+
+		if connected {
+			sendCommandOutput(conn, fmt.Sprintf("kubectl reconcile %d", resource.Id), output, applied.Results, func(bool) string {
+				result := fmt.Sprintf("资源 %s 已按版本 %d 重新部署，成功 %d/%d 个对象", applied.Name, state.Version.Id, applied.Succeeded(), len(applied.Results))
+				if applied.RolloutStatus != "" {
+					result += fmt.Sprintf("，rollout %s", applied.RolloutStatus)
+				}
+				return result
+			})
+		}
+		scheduled_tasks.PushRunningResource()
+	})
+}
@@ -0,0 +1,255 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// eventStreamName 事件流在用户流登记表中的名称
+const eventStreamName = "events"
+
+// intermediateOwners 工作负载与 Pod 之间的中间控制器：Deployment 通过 ReplicaSet、CronJob 通过 Job 管理 Pod
+var intermediateOwners = map[string]string{
+	"Deployment": "ReplicaSet",
+	"CronJob":    "Job",
+}
+
+// eventTargets 资源事件时间线关注的对象，key 为 kind/name
+type eventTargets struct {
+	clients   *conf.K8sClients
+	namespace string
+	kind      string
+	name      string
+	workload  bool
+	objects   map[string]bool
+}
+
+// newEventTargets 工作负载关注自身、中间控制器和 Pod，其他资源只关注主对象
+func newEventTargets(ctx context.Context, clients *conf.K8sClients, resource *dao.UserK8sResource, latestLog *dao.UserK8sResourceOperationLog) *eventTargets {
+	targets := &eventTargets{
+		clients:   clients,
+		namespace: latestLog.Namespace,
+		kind:      k8s_manage.ResourceKinds[resource.ResourceType].Kind,
+		name:      latestLog.MetadataName,
+		workload:  k8s_manage.IsWorkload(resource.ResourceType),
+	}
+	targets.refresh(ctx)
+	return targets
+}
+
+// refresh 按 ownerReference 重新查询工作负载当前的中间控制器和 Pod，rollout 或定时调度过程中会不断出现新的对象
+func (t *eventTargets) refresh(ctx context.Context) {
+	t.objects = map[string]bool{t.kind + "/" + t.name: true}
+	if !t.workload {
+		return
+	}
+
+	podOwners := map[string]bool{t.kind + "/" + t.name: true}
+	switch intermediate := intermediateOwners[t.kind]; intermediate {
+	case "ReplicaSet":
+		replicaSets, err := t.clients.Client.AppsV1().ReplicaSets(t.namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			logrus.Warnf("查询 %s %s 的 ReplicaSet 失败: %v", t.kind, t.name, err)
+			return
+		}
+		for i := range replicaSets.Items {
+			t.addOwned(&replicaSets.Items[i], intermediate, podOwners)
+		}
+	case "Job":
+		jobs, err := t.clients.Client.BatchV1().Jobs(t.namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			logrus.Warnf("查询 %s %s 的 Job 失败: %v", t.kind, t.name, err)
+			return
+		}
+		for i := range jobs.Items {
+			t.addOwned(&jobs.Items[i], intermediate, podOwners)
+		}
+	}
+
+	pods, err := t.clients.Client.CoreV1().Pods(t.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		logrus.Warnf("查询 %s %s 的 Pod 失败: %v", t.kind, t.name, err)
+		return
+	}
+	for _, pod := range pods.Items {
+		if owner := metav1.GetControllerOf(&pod); owner != nil && podOwners[owner.Kind+"/"+owner.Name] {
+			t.objects["Pod/"+pod.Name] = true
+		}
+	}
+}
+
+// addOwned 中间控制器由工作负载控制时加入关注对象，同时作为 Pod 的控制器候选
+func (t *eventTargets) addOwned(object metav1.Object, kind string, podOwners map[string]bool) {
+	if owner := metav1.GetControllerOf(object); owner != nil && owner.Kind == t.kind && owner.Name == t.name {
+		key := kind + "/" + object.GetName()
+		t.objects[key] = true
+		podOwners[key] = true
+	}
+}
+
+// matches 事件是否属于关注的对象；工作负载新建的 ReplicaSet、Job 和 Pod 以工作负载名称为前缀，遇到时重新查询
+func (t *eventTargets) matches(ctx context.Context, event *v1.Event) bool {
+	key := event.InvolvedObject.Kind + "/" + event.InvolvedObject.Name
+	if t.kind == "" {
+		return event.InvolvedObject.Name == t.name
+	}
+	if t.objects[key] {
+		return true
+	}
+	switch event.InvolvedObject.Kind {
+	case "ReplicaSet", "Job", "Pod":
+		if t.workload && strings.HasPrefix(event.InvolvedObject.Name, t.name+"-") {
+			t.refresh(ctx)
+			return t.objects[key]
+		}
+	}
+	return false
+}
+
+// handleResourceEvents 处理 kubectl events 命令，输出资源的事件时间线，-w 时在后台持续推送新事件
+func (s *SocketService) handleResourceEvents(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	resource, clients, latestLog, err := s.loadDeployedResource(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	ctx := context.TODO()
+	targets := newEventTargets(ctx, clients, resource, latestLog)
+
+	events := clients.Client.CoreV1().Events(targets.namespace)
+	list, err := events.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		SendError(conn, fmt.Sprintf("failed to get events: %v", err))
+		return
+	}
+	timeline := &v1.EventList{}
+	for i := range list.Items {
+		if targets.matches(ctx, &list.Items[i]) {
+			timeline.Items = append(timeline.Items, list.Items[i])
+		}
+	}
+
+	fullCommand := fmt.Sprintf("kubectl events --for %s/%s -n %s", strings.ToLower(targets.kind), targets.name, targets.namespace)
+	if targets.kind == "" {
+		fullCommand = fmt.Sprintf("kubectl events -n %s", targets.namespace)
+	}
+	output, _ := parseOutput(data)
+	sendCommandOutput(conn, fullCommand, output, timeline, func(wide bool) string {
+		if len(timeline.Items) == 0 {
+			return noResourcesFound(targets.namespace, false)
+		}
+		return formatEvents(timeline.Items, false, wide)
+	})
+
+	if watching, _ := data["watch"].(bool); !watching {
+		return
+	}
+
+	streamCtx := s.startStream(userID, eventStreamName)
+	watcher, err := watchtools.NewRetryWatcher(list.ResourceVersion, &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return events.Watch(streamCtx, options)
+		},
+	})
+	if err != nil {
+		s.finishStream(userID, eventStreamName, streamCtx)
+		SendError(conn, fmt.Sprintf("监听事件失败: %v", err))
+		return
+	}
+
+	fullCommand += " --watch"
+	SendSuccess(conn, "event_stream_start", K8sCommandResponse{
+		Command: fullCommand,
+		Result:  "",
+	})
+
+	// 在后台推送事件，读循环可以继续接收 stop 消息
+	go func() {
+		defer watcher.Stop()
+		defer s.finishStream(userID, eventStreamName, streamCtx)
+
+		for {
+			select {
+			case <-streamCtx.Done():
+				// 被 stop 或断开连接取消时，已经回复过 stream stopped 或连接已关闭
+				logrus.Infof("用户 %d 的事件流 %s 已停止", userID, fullCommand)
+				return
+			case result, ok := <-watcher.ResultChan():
+				if !ok {
+					if streamCtx.Err() != nil {
+						return
+					}
+					SendSuccess(conn, "event_stream_end", K8sCommandResponse{Command: fullCommand, Result: "event stream end"})
+					return
+				}
+				event, isEvent := result.Object.(*v1.Event)
+				if !isEvent || result.Type == watch.Deleted || !targets.matches(streamCtx, event) || streamCtx.Err() != nil {
+					continue
+				}
+				SendSuccess(conn, "event_stream", K8sCommandResponse{
+					Command: fullCommand,
+					Result:  strings.TrimRight(formatEventRow(event, false, false), "\n"),
+					Objects: withTypeMeta(event),
+				})
+			}
+		}
+	}()
+}
+
+// formatEvents 与 kubectl get events 一致按最后出现时间正序输出
+func formatEvents(events []v1.Event, allNamespaces bool, wide bool) string {
+	sort.SliceStable(events, func(i, j int) bool {
+		return k8s_manage.EventLastSeen(&events[i]).Before(k8s_manage.EventLastSeen(&events[j]))
+	})
+
+	result := ""
+	if allNamespaces {
+		result += fmt.Sprintf("%-20s ", "NAMESPACE")
+	}
+	result += fmt.Sprintf("%-10s %-8s %-24s %-45s", "LAST SEEN", "TYPE", "REASON", "OBJECT")
+	if wide {
+		result += fmt.Sprintf(" %-24s %-10s %-6s", "SOURCE", "FIRST SEEN", "COUNT")
+	}
+	result += " MESSAGE\n"
+	for i := range events {
+		result += formatEventRow(&events[i], allNamespaces, wide)
+	}
+	return result
+}
+
+func formatEventRow(event *v1.Event, allNamespaces bool, wide bool) string {
+	now := time.Now()
+	row := ""
+	if allNamespaces {
+		row += fmt.Sprintf("%-20s ", event.Namespace)
+	}
+	object := strings.ToLower(event.InvolvedObject.Kind) + "/" + event.InvolvedObject.Name
+	row += fmt.Sprintf("%-10s %-8s %-24s %-45s", formatDuration(now.Sub(k8s_manage.EventLastSeen(event))), event.Type, event.Reason, object)
+	if wide {
+		source := event.Source.Component
+		if source == "" {
+			source = event.ReportingController
+		}
+		count := event.Count
+		if count == 0 {
+			count = 1
+		}
+		row += fmt.Sprintf(" %-24s %-10s %-6d", source, formatDuration(now.Sub(k8s_manage.EventFirstSeen(event))), count)
+	}
+	return row + " " + strings.TrimSpace(event.Message) + "\n"
+}
@@ -0,0 +1,206 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// TerminalMessage 终端 websocket 消息
+// 客户端 -> 服务端: stdin / resize；服务端 -> 客户端: stdout / stderr / error / exit
+type TerminalMessage struct {
+	Type string `json:"type"`
+	Data string `json:"data,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+	Cols uint16 `json:"cols,omitempty"`
+}
+
+// terminalSession 将 websocket 连接桥接为 remotecommand 所需的 stdin / stdout / stderr 和终端尺寸队列
+type terminalSession struct {
+	conn     *websocket.Conn
+	ctx      context.Context
+	sizeChan chan remotecommand.TerminalSize
+	pending  []byte
+}
+
+// Read 读取客户端输入，resize 消息会被转入尺寸队列
+func (t *terminalSession) Read(p []byte) (int, error) {
+	// 上一条 stdin 消息没读完时先返回剩余部分
+	if len(t.pending) > 0 {
+		n := copy(p, t.pending)
+		t.pending = t.pending[n:]
+		return n, nil
+	}
+
+	for {
+		_, message, err := t.conn.ReadMessage()
+		if err != nil {
+			return 0, io.EOF
+		}
+
+		var msg TerminalMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			logrus.Warnf("无效的终端消息: %s", string(message))
+			continue
+		}
+
+		switch msg.Type {
+		case "stdin":
+			n := copy(p, msg.Data)
+			t.pending = []byte(msg.Data)[n:]
+			return n, nil
+		case "resize":
+			select {
+			case t.sizeChan <- remotecommand.TerminalSize{Width: msg.Cols, Height: msg.Rows}:
+			case <-t.ctx.Done():
+				return 0, io.EOF
+			}
+		case "close":
+			return 0, io.EOF
+		default:
+			logrus.Warnf("未知的终端消息类型: %s", msg.Type)
+		}
+	}
+}
+
+// Next 返回下一次终端尺寸变化，会话结束时返回 nil
+func (t *terminalSession) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-t.sizeChan:
+		return &size
+	case <-t.ctx.Done():
+		return nil
+	}
+}
+
+// terminalWriter 将容器输出写回 websocket
+type terminalWriter struct {
+	conn       *websocket.Conn
+	streamType string
+}
+
+func (w *terminalWriter) Write(p []byte) (int, error) {
+	err := conf.WSServer.WriteJSON(w.conn, TerminalMessage{
+		Type: w.streamType,
+		Data: string(p),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// CheckPodAccess 校验用户是否有权限进入 Pod：Pod 必须属于该用户或其团队成员部署的资源
+func (s *SocketService) CheckPodAccess(userID uint, clusterID uint32, namespace string, podName string) (*v1.Pod, *conf.K8sClients, error) {
+	clients, err := s.clusterClientsByID(clusterID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx := context.TODO()
+	pod, err := clients.Client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取 Pod 信息失败: %v", err)
+	}
+
+	// 平台部署时在 Pod 模板上写入了资源 ID 标签，资源必须属于同一个集群
+	resourceID, ok := k8s_manage.ResourceIDFromLabels(pod.Labels)
+	if !ok {
+		return nil, nil, fmt.Errorf("Pod %s 不属于平台管理的资源", podName)
+	}
+	resource, err := s.userK8sResourceDao.QueryById(resourceID)
+	if err != nil || resource.ClusterID != clusterID {
+		return nil, nil, fmt.Errorf("Pod %s 不属于平台管理的资源", podName)
+	}
+	logs, err := s.userK8sResourceOperationLogDao.QueryByK8sResourceIDFirst(uint(resourceID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询操作日志失败: %v", err)
+	}
+	if len(logs) == 0 || logs[0].OperationType == "delete" {
+		return nil, nil, fmt.Errorf("Pod %s 不属于平台管理的资源", podName)
+	}
+
+	// 资源的部署者本人可以访问
+	deployerID := logs[0].UserID
+	if deployerID == userID {
+		return pod, clients, nil
+	}
+
+	// 同一团队的成员也可以访问
+	user, err := dao.GetUserByID(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取用户信息失败: %v", err)
+	}
+	deployer, err := dao.GetUserByID(deployerID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取资源部署者信息失败: %v", err)
+	}
+	if user.TeamID == 0 || user.TeamID != deployer.TeamID {
+		return nil, nil, fmt.Errorf("无权访问 Pod %s", podName)
+	}
+	return pod, clients, nil
+}
+
+// HandlePodExec 在容器中打开交互式 TTY，并桥接到 websocket 连接，直到会话结束
+func (s *SocketService) HandlePodExec(conn *websocket.Conn, clients *conf.K8sClients, pod *v1.Pod, container string, command []string) {
+	if container == "" && len(pod.Spec.Containers) > 0 {
+		container = pod.Spec.Containers[0].Name
+	}
+
+	req := clients.Client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(pod.Name).
+		Namespace(pod.Namespace).
+		SubResource("exec").
+		VersionedParams(&v1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     true,
+			Stdout:    true,
+			Stderr:    true,
+			TTY:       true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(clients.Config, "POST", req.URL())
+	if err != nil {
+		conf.WSServer.WriteJSON(conn, TerminalMessage{Type: "error", Data: fmt.Sprintf("创建 exec 连接失败: %v", err)})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	session := &terminalSession{
+		conn:     conn,
+		ctx:      ctx,
+		sizeChan: make(chan remotecommand.TerminalSize, 1),
+	}
+
+	logrus.Infof("exec 会话开始: %s/%s -c %s %v", pod.Namespace, pod.Name, container, command)
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:             session,
+		Stdout:            &terminalWriter{conn: conn, streamType: "stdout"},
+		Stderr:            &terminalWriter{conn: conn, streamType: "stderr"},
+		Tty:               true,
+		TerminalSizeQueue: session,
+	})
+	if err != nil {
+		logrus.Warnf("exec 会话异常结束: %v", err)
+		conf.WSServer.WriteJSON(conn, TerminalMessage{Type: "error", Data: err.Error()})
+		return
+	}
+
+	conf.WSServer.WriteJSON(conn, TerminalMessage{Type: "exit", Data: "session closed"})
+	logrus.Infof("exec 会话结束: %s/%s", pod.Namespace, pod.Name)
+}
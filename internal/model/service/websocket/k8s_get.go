@@ -0,0 +1,177 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/gorilla/websocket"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// commandNamespace 命令作用的 namespace：-A 为全部 namespace，未指定时与 kubectl 一致使用 default
+func commandNamespace(cmd *KubeCommand, data map[string]interface{}) string {
+	if cmd.AllNamespaces || clusterScopedKinds[cmd.Kind] {
+		return ""
+	}
+	if namespace, _ := data["namespace"].(string); namespace != "" {
+		return namespace
+	}
+	return "default"
+}
+
+// handleListResources 处理 kubectl get <kind> [name]，按 namespace、label selector 和 field selector 过滤
+func (s *SocketService) handleListResources(conn *websocket.Conn, clients *conf.K8sClients, cmd *KubeCommand, command string, data map[string]interface{}, output string) {
+	ctx := context.TODO()
+	namespace := commandNamespace(cmd, data)
+	labelSelector, _ := data["label_selector"].(string)
+	fieldSelector, _ := data["field_selector"].(string)
+	if cmd.Name != "" {
+		nameSelector := "metadata.name=" + cmd.Name
+		if fieldSelector != "" {
+			nameSelector = fieldSelector + "," + nameSelector
+		}
+		fieldSelector = nameSelector
+	}
+	options := metav1.ListOptions{LabelSelector: labelSelector, FieldSelector: fieldSelector}
+
+	var list runtime.Object
+	var table func(wide bool) string
+	var err error
+	switch cmd.Kind {
+	case "pods":
+		pods, listErr := clients.Client.CoreV1().Pods(namespace).List(ctx, options)
+		list, err = pods, listErr
+		table = func(wide bool) string { return formatPods(pods, wide) }
+	case "services":
+		services, listErr := clients.Client.CoreV1().Services(namespace).List(ctx, options)
+		list, err = services, listErr
+		table = func(wide bool) string { return formatServices(services, wide) }
+	case "deployments":
+		deployments, listErr := clients.Client.AppsV1().Deployments(namespace).List(ctx, options)
+		list, err = deployments, listErr
+		table = func(wide bool) string { return formatDeployments(deployments, wide) }
+	case "ingresses":
+		ingresses, listErr := clients.Client.NetworkingV1().Ingresses(namespace).List(ctx, options)
+		list, err = ingresses, listErr
+		table = func(wide bool) string { return formatIngresses(clients, ingresses, wide) }
+	case "statefulsets":
+		statefulSets, listErr := clients.Client.AppsV1().StatefulSets(namespace).List(ctx, options)
+		list, err = statefulSets, listErr
+		table = func(wide bool) string { return formatStatefulSets(statefulSets, wide) }
+	case "daemonsets":
+		daemonSets, listErr := clients.Client.AppsV1().DaemonSets(namespace).List(ctx, options)
+		list, err = daemonSets, listErr
+		table = func(wide bool) string { return formatDaemonSets(daemonSets, wide) }
+	case "jobs":
+		jobs, listErr := clients.Client.BatchV1().Jobs(namespace).List(ctx, options)
+		list, err = jobs, listErr
+		table = func(wide bool) string { return formatJobs(jobs, wide) }
+	case "cronjobs":
+		cronJobs, listErr := clients.Client.BatchV1().CronJobs(namespace).List(ctx, options)
+		list, err = cronJobs, listErr
+		table = func(wide bool) string { return formatCronJobs(cronJobs, wide) }
+	case "namespaces":
+		namespaces, listErr := clients.Client.CoreV1().Namespaces().List(ctx, options)
+		list, err = namespaces, listErr
+		table = func(bool) string { return formatNamespace(namespaces) }
+	case "nodes":
+		nodes, listErr := clients.Client.CoreV1().Nodes().List(ctx, options)
+		list, err = nodes, listErr
+		table = func(wide bool) string { return formatNodes(nodes, wide) }
+	case "horizontalpodautoscalers":
+		hpas, listErr := clients.Client.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, options)
+		list, err = hpas, listErr
+		table = func(bool) string { return strings.TrimPrefix(formatAutoscalers(hpas.Items), "\n") }
+	case "events":
+		events, listErr := clients.Client.CoreV1().Events(namespace).List(ctx, options)
+		list, err = events, listErr
+		table = func(wide bool) string { return formatEvents(events.Items, namespace == "", wide) }
+	default:
+		SendError(conn, fmt.Sprintf("kubectl get 不支持资源类型 %s", cmd.Kind))
+		return
+	}
+	if err != nil {
+		SendError(conn, fmt.Sprintf("failed to get %s: %v", cmd.Kind, err))
+		return
+	}
+
+	sendCommandOutput(conn, command, output, list, func(wide bool) string {
+		if meta.LenList(list) == 0 {
+			return noResourcesFound(namespace, clusterScopedKinds[cmd.Kind])
+		}
+		return table(wide)
+	})
+}
+
+// handleDescribeObject 处理 kubectl describe <kind> <name>
+func (s *SocketService) handleDescribeObject(conn *websocket.Conn, clients *conf.K8sClients, cmd *KubeCommand, command string, data map[string]interface{}, output string) {
+	if cmd.Name == "" {
+		SendError(conn, fmt.Sprintf("kubectl describe %s 缺少资源名称", cmd.Kind))
+		return
+	}
+
+	ctx := context.TODO()
+	namespace := commandNamespace(cmd, data)
+	var objects []runtime.Object
+	var result string
+	var err error
+	switch cmd.Kind {
+	case "pods":
+		pod, getErr := clients.Client.CoreV1().Pods(namespace).Get(ctx, cmd.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			objects, result = []runtime.Object{pod}, formatPodDetail(clients, pod)
+		}
+	case "services":
+		service, getErr := clients.Client.CoreV1().Services(namespace).Get(ctx, cmd.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			objects, result = []runtime.Object{service}, formatServiceDetail(clients, service)
+		}
+	case "deployments":
+		deployment, getErr := clients.Client.AppsV1().Deployments(namespace).Get(ctx, cmd.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			objects, result = []runtime.Object{deployment}, formatDeploymentDetail(deployment)
+			if hpas, err := findAutoscalers(ctx, clients, namespace, cmd.Name); err == nil {
+				result += formatAutoscalerDetails(hpas)
+				for i := range hpas {
+					objects = append(objects, &hpas[i])
+				}
+			}
+		}
+	case "ingresses":
+		ingress, getErr := clients.Client.NetworkingV1().Ingresses(namespace).Get(ctx, cmd.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			objects, result = []runtime.Object{ingress}, formatIngressDetail(clients, ingress)
+		}
+	case "statefulsets", "daemonsets", "jobs", "cronjobs":
+		workload, getErr := getWorkload(ctx, clients, strings.TrimSuffix(cmd.Kind, "s"), namespace, cmd.Name)
+		if err = getErr; err == nil {
+			objects, result = []runtime.Object{workload}, formatWorkloadDetail(workload)
+		}
+	default:
+		SendError(conn, fmt.Sprintf("kubectl describe 不支持资源类型 %s", cmd.Kind))
+		return
+	}
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			SendError(conn, fmt.Sprintf("在命名空间 %s 中未找到 %s %s", namespace, cmd.Kind, cmd.Name))
+			return
+		}
+		SendError(conn, fmt.Sprintf("获取 %s %s 失败: %v", cmd.Kind, cmd.Name, err))
+		return
+	}
+
+	sendResourceOutput(conn, command, output, objects, result)
+}
+
+// noResourcesFound 与 kubectl 一致的空结果提示
+func noResourcesFound(namespace string, clusterScoped bool) string {
+	if clusterScoped || namespace == "" {
+		return "No resources found\n"
+	}
+	return fmt.Sprintf("No resources found in %s namespace.\n", namespace)
+}
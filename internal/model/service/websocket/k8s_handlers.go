@@ -5,44 +5,64 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/ZZGADA/easy-deploy/internal/model/scheduled_tasks"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/ZZGADA/easy-deploy/internal/define"
 
 	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
 
 	"github.com/sirupsen/logrus"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/version"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	"github.com/ZZGADA/easy-deploy/internal/model/conf"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/gorilla/websocket"
-	"gopkg.in/yaml.v3"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	_ "k8s.io/client-go/kubernetes"
 )
 
+// 前端使用的常用命令，与其他 kubectl / helm 命令一样由 ParseKubeCommand 解析。
+// GetAll* 和 GetNodes 查看全部 namespace 或集群级资源，需要团队角色包含 cluster-scope（默认只有创建者）；
+// 其他角色使用 GetPods 等命令，并在消息的 namespace 字段中指定团队的 namespace
 const (
 	GetAllNameSpace          = "kubectl get namespace"
 	GetAllPods               = "kubectl get pod -A"
 	GetAllService            = "kubectl get svc -A"
 	GetAllDeployment         = "kubectl get deployment -A"
+	GetAllIngress            = "kubectl get ingress -A"
+	GetPods                  = "kubectl get pod"
+	GetServices              = "kubectl get svc"
+	GetDeployments           = "kubectl get deployment"
+	GetIngresses             = "kubectl get ingress"
 	GetClusterInfo           = "kubectl cluster-info"
 	GetNodes                 = "kubectl get nodes"
 	ResourceApply            = "kubectl apply -f"
+	ResourcePlan             = "kubectl diff -f"
 	ResourceDelete           = "kubectl delete"
 	GetSpecificResource      = "kubectl get"
 	DescribeSpecificResource = "kubectl describe"
+	RolloutHistory           = "kubectl rollout history"
+	RolloutUndo              = "kubectl rollout undo"
+	PodLogs                  = "kubectl logs"
+	HelmInstall              = "helm install"
+	HelmUpgrade              = "helm upgrade"
+	HelmRollback             = "helm rollback"
+	HelmUninstall            = "helm uninstall"
+	HelmHistory              = "helm history"
+	TopPods                  = "kubectl top pod"
+	TopNodes                 = "kubectl top node"
+	Autoscale                = "kubectl autoscale"
 )
 
 // 远程服务器配置
@@ -54,114 +74,135 @@ const (
 )
 
 type K8sCommandResponse struct {
-	Command string `json:"command"`
-	Result  string `json:"result"`
+	Command string      `json:"command"`
+	Result  string      `json:"result"`
+	Output  string      `json:"output,omitempty"`  // table / wide / json / yaml
+	Objects interface{} `json:"objects,omitempty"` // output 为 json / yaml 时的结构化对象
+}
+
+// ClusterInfo kubectl cluster-info 的结构化结果
+type ClusterInfo struct {
+	ControlPlane string        `json:"control_plane"`
+	Version      *version.Info `json:"version"`
 }
 
+// HandleKubeCommand 解析 kubectl / helm 命令，按团队角色白名单校验动词后分发到对应的处理函数
 func (s *SocketService) HandleKubeCommand(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
-	switch command {
-	case GetAllNameSpace:
-		{
-			namespaces, err := conf.KubeClient.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
-			if err != nil {
-				panic(fmt.Errorf("failed to get namespaces: %v", err))
-			}
+	cmd, err := ParseKubeCommand(command)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	if err := s.checkCommandAllowed(userID, cmd); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
 
-			SendSuccess(conn, "command execute success", K8sCommandResponse{
-				Command: command,
-				Result:  formatNamespace(namespaces),
-			})
-			return
-		}
-	case GetAllPods:
-		{
-			pods, err := conf.KubeClient.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
-			if err != nil {
-				panic(fmt.Errorf("failed to get pods: %v", err))
-			}
+	// 命令行中的参数写入 websocket 参数，处理函数统一从 data 中读取
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	if err := cmd.applyTo(data); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
 
-			SendSuccess(conn, "command execute success", K8sCommandResponse{
-				Command: command,
-				Result:  formatPods(pods),
-			})
-			return
-		}
-	case GetAllService:
-		{
-			services, err := conf.KubeClient.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{})
-			if err != nil {
-				panic(fmt.Errorf("failed to get services: %v", err))
-			}
+	// 未指定 cluster_id 时使用默认集群
+	clients, err := s.clusterClients(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	if err := s.checkCommandScope(userID, clients.ClusterID, cmd, data); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	output, err := parseOutput(data)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
 
-			SendSuccess(conn, "command execute success", K8sCommandResponse{
-				Command: command,
-				Result:  formatServices(services),
-			})
+	switch cmd.Verb {
+	case "get":
+		// 不带资源类型时查询平台管理的资源
+		if cmd.Kind == "" {
+			s.handleResourceGet(conn, command, data, userID)
 			return
 		}
-	case GetAllDeployment:
-		{
-			deployments, err := conf.KubeClient.AppsV1().Deployments("").List(context.TODO(), metav1.ListOptions{})
-			if err != nil {
-				panic(fmt.Errorf("failed to get deployments: %v", err))
-			}
-
-			SendSuccess(conn, "command execute success", K8sCommandResponse{
-				Command: command,
-				Result:  formatDeployments(deployments),
-			})
+		s.handleListResources(conn, clients, cmd, command, data, output)
+	case "describe":
+		if cmd.Kind == "" {
+			s.handleResourceDescribe(conn, command, data, userID)
 			return
 		}
-	case GetClusterInfo:
-		{
-			version, err := conf.KubeClient.Discovery().ServerVersion()
-			if err != nil {
-				panic(fmt.Errorf("failed to get cluster info: %v", err))
-			}
-
-			SendSuccess(conn, "command execute success", K8sCommandResponse{
-				Command: command,
-				Result:  formatClusterInfo(version),
-			})
+		s.handleDescribeObject(conn, clients, cmd, command, data, output)
+	case "cluster-info":
+		version, err := clients.Client.Discovery().ServerVersion()
+		if err != nil {
+			SendError(conn, fmt.Sprintf("failed to get cluster info: %v", err))
 			return
 		}
-	case GetNodes:
-		{
-			nodes, err := conf.KubeClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
-			if err != nil {
-				panic(fmt.Errorf("failed to get nodes: %v", err))
-			}
 
-			SendSuccess(conn, "command execute success", K8sCommandResponse{
-				Command: command,
-				Result:  formatNodes(nodes),
-			})
-			return
-		}
+		info := ClusterInfo{ControlPlane: clients.Config.Host, Version: version}
+		sendCommandOutput(conn, command, output, info, func(bool) string {
+			return formatClusterInfo(clients.Config, version)
+		})
 	default:
-		s.baseProcess(conn, command, data, userID)
+		s.baseProcess(conn, clients, cmd, command, data, userID)
 	}
 }
 
-func (s *SocketService) baseProcess(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
-	switch command {
-	case ResourceApply:
+func (s *SocketService) baseProcess(conn *websocket.Conn, clients *conf.K8sClients, cmd *KubeCommand, command string, data map[string]interface{}, userID uint) {
+	switch cmd.Verb {
+	case "apply":
 		s.resourceApply(conn, command, data, userID)
-		return
-	case ResourceDelete:
+	case "diff":
+		s.resourcePlan(conn, command, data, userID)
+	case "delete":
 		s.resourceDelete(conn, command, data, userID)
-		return
-	case GetSpecificResource:
-		s.handleResourceGet(conn, command, data, userID)
-		return
-	case DescribeSpecificResource:
-		s.handleResourceDescribe(conn, command, data, userID)
-		return
+	case "rollout history":
+		s.resourceRolloutHistory(conn, command, data, userID)
+	case "rollout undo":
+		s.resourceRollback(conn, command, data, userID)
+	case "logs":
+		s.handlePodLogs(conn, command, data, userID)
+	case "events":
+		s.handleResourceEvents(conn, command, data, userID)
+	case "drift":
+		s.handleDrift(conn, command, data, userID)
+	case "reconcile":
+		s.handleReconcile(conn, command, data, userID)
+	case "top":
+		if cmd.Kind == "nodes" {
+			s.handleTopNodes(conn, clients, TopNodes, data)
+			return
+		}
+		s.handleTopPods(conn, clients, TopPods, data)
+	case "autoscale":
+		s.handleAutoscale(conn, command, data, userID)
+	case "scale":
+		s.handleScale(conn, command, data, userID)
+	case "rollout restart":
+		s.handleRolloutRestart(conn, command, data, userID)
+	case "rollout pause":
+		s.handleRolloutPause(conn, command, data, userID, true)
+	case "rollout resume":
+		s.handleRolloutPause(conn, command, data, userID, false)
+	case "rollout status":
+		s.handleRolloutStatus(conn, command, data, userID)
+	case "helm install":
+		s.helmInstall(conn, command, data, userID, false)
+	case "helm upgrade":
+		s.helmInstall(conn, command, data, userID, true)
+	case "helm rollback":
+		s.helmRollback(conn, command, data, userID)
+	case "helm uninstall":
+		s.helmUninstall(conn, command, data, userID)
+	case "helm history":
+		s.helmHistory(conn, command, data, userID)
 	default:
-		SendSuccess(conn, "command execute success", K8sCommandResponse{
-			Command: command,
-			Result:  command,
-		})
+		SendError(conn, fmt.Sprintf("不支持的命令: %s", command))
 	}
 }
 
@@ -179,209 +220,115 @@ func (s *SocketService) resourceApply(conn *websocket.Conn, command string, data
 		SendError(conn, fmt.Sprintf("查询资源失败: %v", err))
 		return
 	}
-
-	// 创建本地 k8s 目录（如果不存在）
-	k8sDir := "k8s"
-	if err := os.MkdirAll(k8sDir, 0755); err != nil {
-		SendError(conn, fmt.Sprintf("创建 k8s 目录失败: %v", err))
-		return
-	}
-
-	// 生成本地文件路径
-	localFilePath := filepath.Join(k8sDir, fmt.Sprintf("%d_%s", resource.Id, resource.FileName))
-
-	// 从 OSS 下载文件
-	ossClient, exist := conf.WSServer.OssClient[userID]
-	if !exist {
-		SendError(conn, fmt.Sprintf("获取 OSS 客户端失败: %v", err))
-		return
-	}
-
-	// 从 URL 中提取 object-name
-	objectNameUrl := strings.TrimPrefix(resource.OssURL, "https://")
-	objectNameS := strings.Split(objectNameUrl, "/")[1:] // 去掉域名部分
-	objectName := strings.Join(objectNameS, "/")
-
-	// 下载文件
-	err = ossClient.GetObjectToFile(objectName, localFilePath)
-	if err != nil {
-		SendError(conn, fmt.Sprintf("从 OSS 下载文件失败: %v", err))
-		return
-	}
-
-	// 确保函数结束时删除本地文件
-	defer func() {
-		if err := os.Remove(localFilePath); err != nil {
-			logrus.Errorf("删除本地文件失败: %v", err)
-		}
-	}()
-
-	// 读取 YAML 文件内容
-	yamlContent, err := ioutil.ReadFile(localFilePath)
-	if err != nil {
-		SendError(conn, fmt.Sprintf("读取 YAML 文件失败: %v", err))
+	if err := s.k8sResourceService.CheckResourceOwner(userID, &resource); err != nil {
+		SendError(conn, err.Error())
 		return
 	}
 
-	// 解析 YAML 文件，获取 namespace
-	namespace, err := s.extractNamespaceFromYAML(yamlContent)
+	clients, err := s.clusterClientsByID(resource.ClusterID, userID)
 	if err != nil {
-		SendError(conn, fmt.Sprintf("解析 YAML 文件失败: %v", err))
-		return
-	}
-
-	// 如果 namespace 为空，使用默认的 "default"
-	if namespace == "" {
-		namespace = "default"
-	}
-
-	// 检查 namespace 是否存在，如果不存在则创建
-	if err := s.ensureNamespaceExists(namespace); err != nil {
-		SendError(conn, fmt.Sprintf("创建 namespace 失败: %v", err))
+		SendError(conn, err.Error())
 		return
 	}
 
-	// 解析 YAML 文件，获取资源名称和标签
-	resourceName, labels, err := s.extractResourceInfo(yamlContent)
+	applied, err := s.applyStoredResource(conn, clients, command, resource.Id, &resource, parseRenderOptions(data), userID)
 	if err != nil {
-		SendError(conn, fmt.Sprintf("解析资源信息失败: %v", err))
-		return
-	}
-
-	// 创建资源
-	if err := s.createResourceFromYAML(conf.KubeClient, localFilePath, namespace); err != nil {
-		SendError(conn, fmt.Sprintf("创建资源失败: %v", err))
+		SendError(conn, err.Error())
 		return
 	}
 
-	// 记录操作日志
-	labelsJSON, err := json.Marshal(labels)
-	if err != nil {
-		logrus.Errorf("序列化标签失败: %v", err)
-		// 继续执行，不中断流程
-	}
-
-	// 构建完整的 kubectl 命令
-	fullCommand := fmt.Sprintf("kubectl apply -f %s -n %s", localFilePath, namespace)
-
-	// 检查资源状态
-	var status int
-	if resource.ResourceType == "deployment" {
-		// 等待一段时间，让部署有时间启动
-		time.Sleep(5 * time.Second)
-
-		// 检查部署状态
-		deployment, err := conf.KubeClient.AppsV1().Deployments(namespace).Get(context.TODO(), resourceName, metav1.GetOptions{})
-		if err != nil {
-			// 部署失败
-			status = define.K8sResourceStatusStop // 运行停止
-		} else {
-			// 检查部署状态
-			if deployment.Status.AvailableReplicas == *deployment.Spec.Replicas {
-				status = define.K8sResourceStatusRun // 运行正常
-			} else if deployment.Status.UpdatedReplicas < *deployment.Spec.Replicas {
-				status = define.K8sResourceStatusRestart // 容器重启
-			} else {
-				status = define.K8sResourceStatusStop // 运行停止
-			}
+	// 操作日志在 apply 后立即记录，rollout 结束后更新状态
+	output, _ := parseOutput(data)
+	operationLog := applied.operationLog(uint(k8sResourceID), userID, applied.OperationType)
+	s.finishApply(conn, userID, clients, resource.ResourceType, applied, operationLog, func(connected bool) {
+		// 发送成功响应，json / yaml 输出时携带每个对象的 apply 结果
+		if connected {
+			sendCommandOutput(conn, command, output, applied.Results, func(bool) string {
+				result := fmt.Sprintf("资源 %s 已部署到 namespace %s，成功 %d/%d 个对象", resource.FileName, applied.Namespace, applied.Succeeded(), len(applied.Results))
+				if applied.RolloutStatus != "" {
+					result += fmt.Sprintf("，rollout %s", applied.RolloutStatus)
+				}
+				return result
+			})
 		}
-	} else if resource.ResourceType == "service" {
-		// 服务创建后默认为运行正常
-		status = define.K8sResourceStatusRun
-	}
-
-	// 创建操作日志
-	operationLog := &dao.UserK8sResourceOperationLog{
-		K8sResourceID:  uint(k8sResourceID),
-		UserID:         userID,
-		Namespace:      namespace,
-		MetadataName:   resourceName,
-		MetadataLabels: string(labelsJSON),
-		OperationType:  "create",
-		Status:         status,
-		Command:        fullCommand,
-	}
-
-	// 保存操作日志
-	if err := s.userK8sResourceOperationLogDao.Create(operationLog); err != nil {
-		logrus.Errorf("保存操作日志失败: %v", err)
-		// 继续执行，不中断流程
-	}
-
-	// 发送成功响应
-	SendSuccess(conn, "command execute success", K8sCommandResponse{
-		Command: command,
-		Result:  fmt.Sprintf("资源 %s 已成功部署到 namespace %s", resource.FileName, namespace),
+		scheduled_tasks.PushRunningResource()
 	})
-	scheduled_tasks.PushRunningResource()
 }
 
 func (s *SocketService) resourceDelete(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
 	logrus.Info("resource delete ", "data: ", data)
-	k8sResourceID, exist := data["k8s_resource_id"].(float64)
-	if !exist {
-		SendError(conn, "缺少k8s_resource_id 参数")
-		return
-	}
 
-	// 从数据库查询资源信息
-	resource, err := s.userK8sResourceDao.QueryById(uint32(k8sResourceID))
+	// 查询资源和最新的操作日志，只能停止本人或团队成员部署在团队 namespace 中的资源
+	resource, clients, latestLog, err := s.loadDeployedResource(data, userID)
 	if err != nil {
-		SendError(conn, fmt.Sprintf("查询资源失败: %v", err))
+		SendError(conn, err.Error())
 		return
 	}
+	k8sResourceID := resource.Id
 
-	// 查询最新的操作日志，获取资源信息
-	logs, err := s.userK8sResourceOperationLogDao.QueryByK8sResourceIDFirst(uint(k8sResourceID))
-	if err != nil || len(logs) == 0 {
-		SendError(conn, fmt.Sprintf("查询资源操作日志失败: %v", err))
+	// helm 资源通过卸载 release 删除
+	if resource.ResourceType == "helm" {
+		s.helmUninstall(conn, command, data, userID)
 		return
 	}
 
-	// 获取最新的操作日志
-	latestLog := logs[0]
 	namespace := latestLog.Namespace
 	metadataName := latestLog.MetadataName
 
 	// 检查资源是否正在运行
 	resourceType := resource.ResourceType
 
-	if resourceType == "deployment" {
-		_, err = conf.KubeClient.AppsV1().Deployments(namespace).Get(context.TODO(), metadataName, metav1.GetOptions{})
-	} else if resourceType == "service" {
-		_, err = conf.KubeClient.CoreV1().Services(namespace).Get(context.TODO(), metadataName, metav1.GetOptions{})
-	} else {
+	gvk, ok := k8s_manage.ResourceKinds[resourceType]
+	if !ok {
 		SendError(conn, fmt.Sprintf("不支持的资源类型: %s", resourceType))
 		return
 	}
-
-	// 如果资源不存在，说明已经停止运行
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(metadataName)
+	ri, _, err := clients.ResourceInterface(obj, namespace)
 	if err != nil {
-		if k8sErr, ok := err.(*k8serrors.StatusError); ok && k8sErr.Status().Code == 404 {
-			SendError(conn, fmt.Sprintf("%s 已经关闭", metadataName))
-			return
-		}
+		SendError(conn, err.Error())
+		return
+	}
+	primary, err := ri.Get(context.TODO(), metadataName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
 		SendError(conn, fmt.Sprintf("检查资源状态失败: %v", err))
 		return
 	}
 
-	// 资源正在运行，执行删除操作
-	var deleteCommand string
-	var deleteErr error
-
-	if resourceType == "deployment" {
-		deleteCommand = fmt.Sprintf("kubectl delete deployment %s -n %s", metadataName, namespace)
-		deleteErr = conf.KubeClient.AppsV1().Deployments(namespace).Delete(context.TODO(), metadataName, metav1.DeleteOptions{})
-	} else if resourceType == "service" {
-		deleteCommand = fmt.Sprintf("kubectl delete service %s -n %s", metadataName, namespace)
-		deleteErr = conf.KubeClient.CoreV1().Services(namespace).Delete(context.TODO(), metadataName, metav1.DeleteOptions{})
+	// 删除 apply 时写入的全部对象（ConfigMap、Secret、Service 等），与 apply 一样按归属标签找到同一个资源的对象；
+	// Job 和 CronJob 默认不级联删除 Pod，与 kubectl 一致使用后台级联删除
+	deleted, err := deleteOwnedObjects(context.TODO(), clients, namespace, resource.Id)
+	if err != nil {
+		SendError(conn, fmt.Sprintf("删除资源失败: %v", err))
+		return
+	}
+	// 写入归属标签之前部署的主对象没有标签，仍然按名称删除
+	if primary != nil {
+		if _, labelled := k8s_manage.ResourceIDFromLabels(primary.GetLabels()); !labelled {
+			propagation := metav1.DeletePropagationBackground
+			if err := ri.Delete(context.TODO(), metadataName, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !k8serrors.IsNotFound(err) {
+				SendError(conn, fmt.Sprintf("删除资源失败: %v", err))
+				return
+			}
+			deleted = append(deleted, fmt.Sprintf("%s/%s", resourceType, metadataName))
+		}
 	}
 
-	// 检查删除操作是否成功
-	if deleteErr != nil {
-		SendError(conn, fmt.Sprintf("删除资源失败: %v", deleteErr))
+	// 没有任何对象，说明已经停止运行
+	if len(deleted) == 0 {
+		SendError(conn, fmt.Sprintf("%s 已经关闭", metadataName))
 		return
 	}
+	deleteCommand := fmt.Sprintf("kubectl delete %s -n %s", strings.Join(deleted, " "), namespace)
+
+	// Deployment 删除后一并清理平台创建的 HPA
+	if resourceType == "deployment" {
+		if _, err := k8s_manage.DeleteManagedAutoscaler(context.TODO(), clients, namespace, metadataName); err != nil {
+			logrus.Errorf("清理 HPA 失败: %v", err)
+		}
+	}
 
 	// 记录操作日志
 	operationLog := &dao.UserK8sResourceOperationLog{
@@ -411,51 +358,45 @@ func (s *SocketService) resourceDelete(conn *websocket.Conn, command string, dat
 	scheduled_tasks.PushRunningResource()
 }
 
-// 从 YAML 内容中提取 namespace
-func (s *SocketService) extractNamespaceFromYAML(yamlContent []byte) (string, error) {
-	// 创建一个通用的 map 来解析 YAML
-	var resource map[string]interface{}
-	if err := yaml.Unmarshal(yamlContent, &resource); err != nil {
-		return "", fmt.Errorf("解析 YAML 失败: %v", err)
-	}
-
-	// 检查 metadata.namespace 字段
-	if metadata, ok := resource["metadata"].(map[string]interface{}); ok {
-		if namespace, ok := metadata["namespace"].(string); ok {
-			return namespace, nil
-		}
-	}
-
-	// 如果没有找到 namespace，返回空字符串
-	return "", nil
-}
-
-// 确保 namespace 存在，如果不存在则创建
-func (s *SocketService) ensureNamespaceExists(namespace string) error {
-	// 检查 namespace 是否存在
-	_, err := conf.KubeClient.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
-	if err == nil {
-		// namespace 已存在
-		return nil
+// deleteOwnedObjects 删除 namespace 中带有资源归属标签的全部对象，返回 kubectl 风格的对象名称。
+// 由控制器创建的对象（ReplicaSet、Pod、EndpointSlice 等）随所属对象级联删除，不单独删除
+func deleteOwnedObjects(ctx context.Context, clients *conf.K8sClients, namespace string, resourceID uint32) ([]string, error) {
+	lists, err := clients.Client.Discovery().ServerPreferredNamespacedResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("查询集群资源类型失败: %v", err)
 	}
 
-	// 检查错误是否为 "not found"
-	if k8sErr, ok := err.(*k8serrors.StatusError); ok && k8sErr.Status().Code == 404 {
-		// namespace 不存在，创建它
-		ns := &v1.Namespace{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: namespace,
-			},
-		}
-		_, err = conf.KubeClient.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{})
+	selector := fmt.Sprintf("%s=%d", define.K8sLabelResourceID, resourceID)
+	propagation := metav1.DeletePropagationBackground
+	var deleted []string
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
 		if err != nil {
-			return fmt.Errorf("创建 namespace 失败: %v", err)
+			continue
+		}
+		for _, apiResource := range list.APIResources {
+			if strings.Contains(apiResource.Name, "/") || !containsString(apiResource.Verbs, "list") || !containsString(apiResource.Verbs, "delete") {
+				continue
+			}
+			ri := clients.Dynamic.Resource(gv.WithResource(apiResource.Name)).Namespace(namespace)
+			objects, err := ri.List(ctx, metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				logrus.Warnf("查询 %s 失败: %v", apiResource.Name, err)
+				continue
+			}
+			for i := range objects.Items {
+				obj := &objects.Items[i]
+				if metav1.GetControllerOf(obj) != nil || obj.GetDeletionTimestamp() != nil {
+					continue
+				}
+				if err := ri.Delete(ctx, obj.GetName(), metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !k8serrors.IsNotFound(err) {
+					return deleted, fmt.Errorf("删除 %s/%s 失败: %v", strings.ToLower(apiResource.Kind), obj.GetName(), err)
+				}
+				deleted = append(deleted, fmt.Sprintf("%s/%s", strings.ToLower(apiResource.Kind), obj.GetName()))
+			}
 		}
-		return nil
 	}
-
-	// 其他错误
-	return fmt.Errorf("检查 namespace 失败: %v", err)
+	return deleted, nil
 }
 
 // GetOssClient 获取 OSS 客户端
@@ -483,48 +424,6 @@ func (s *SocketService) GetOssClient(userId uint) (*oss.Bucket, error) {
 	return bucket, nil
 }
 
-func (s *SocketService) createResourceFromYAML(client *kubernetes.Clientset, yamlPath string, namespace string) error {
-	yamlFile, err := ioutil.ReadFile(yamlPath)
-	if err != nil {
-		return fmt.Errorf("failed to read YAML file: %v", err)
-	}
-
-	scheme := runtime.NewScheme()
-	if err := appsv1.AddToScheme(scheme); err != nil {
-		return fmt.Errorf("failed to add apps/v1 to scheme: %v", err)
-	}
-	if err := v1.AddToScheme(scheme); err != nil {
-		return fmt.Errorf("failed to add core/v1 to scheme: %v", err)
-	}
-
-	codecFactory := serializer.NewCodecFactory(scheme)
-	decoder := codecFactory.UniversalDeserializer()
-
-	obj, _, err := decoder.Decode(yamlFile, nil, nil)
-	if err != nil {
-		return fmt.Errorf("failed to decode YAML: %v", err)
-	}
-
-	switch resource := obj.(type) {
-	case *appsv1.Deployment:
-		_, err = client.AppsV1().Deployments(namespace).Create(context.TODO(), resource, metav1.CreateOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to create Deployment: %v", err)
-		}
-		fmt.Printf("Deployment %s created successfully.\n", resource.Name)
-	case *v1.Service:
-		_, err = client.CoreV1().Services(namespace).Create(context.TODO(), resource, metav1.CreateOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to create Service: %v", err)
-		}
-		fmt.Printf("Service %s created successfully.\n", resource.Name)
-	default:
-		return fmt.Errorf("unsupported resource type: %T", resource)
-	}
-
-	return nil
-}
-
 // 执行 SSH 命令
 //func executeSSHCommand(command string) (string, error) {
 //	config := &ssh.ClientConfig{
@@ -570,10 +469,15 @@ func formatNamespace(namespaces *v1.NamespaceList) string {
 	return result
 }
 
-func formatPods(pods *v1.PodList) string {
+// formatPods wide 时追加 Pod IP 和所在节点
+func formatPods(pods *v1.PodList, wide bool) string {
 	var result string
 
-	result = fmt.Sprintf("%-20s %-40s %-10s %-10s %-10s %-10s\n", "NAMESPACE", "NAME", "READY", "STATUS", "RESTARTS", "AGE")
+	result = fmt.Sprintf("%-20s %-40s %-10s %-10s %-10s %-10s", "NAMESPACE", "NAME", "READY", "STATUS", "RESTARTS", "AGE")
+	if wide {
+		result += fmt.Sprintf(" %-16s %-20s", "IP", "NODE")
+	}
+	result += "\n"
 	now := time.Now()
 	for _, pod := range pods.Items {
 		cnt := 0
@@ -590,16 +494,25 @@ func formatPods(pods *v1.PodList) string {
 		if len(pod.Status.ContainerStatuses) > 0 {
 			restarts = int(pod.Status.ContainerStatuses[0].RestartCount)
 		}
-		result += fmt.Sprintf("%-20s %-40s %-10s %-10s %-10d %-10s\n",
+		result += fmt.Sprintf("%-20s %-40s %-10s %-10s %-10d %-10s",
 			pod.Namespace, pod.Name, ready, string(pod.Status.Phase), restarts, ageStr)
+		if wide {
+			result += fmt.Sprintf(" %-16s %-20s", pod.Status.PodIP, pod.Spec.NodeName)
+		}
+		result += "\n"
 	}
 	return result
 }
 
-func formatServices(services *v1.ServiceList) string {
+// formatServices wide 时追加 selector
+func formatServices(services *v1.ServiceList, wide bool) string {
 	var result string
 
-	result = fmt.Sprintf("%-20s %-20s %-10s %-20s %-10s\n", "NAMESPACE", "NAME", "TYPE", "CLUSTER-IP", "PORT(S)")
+	result = fmt.Sprintf("%-20s %-20s %-10s %-20s %-10s", "NAMESPACE", "NAME", "TYPE", "CLUSTER-IP", "PORT(S)")
+	if wide {
+		result += fmt.Sprintf(" %-30s", "SELECTOR")
+	}
+	result += "\n"
 	for _, svc := range services.Items {
 		ports := ""
 		for i, port := range svc.Spec.Ports {
@@ -608,39 +521,66 @@ func formatServices(services *v1.ServiceList) string {
 			}
 			ports += fmt.Sprintf("%d/%s", port.Port, port.Protocol)
 		}
-		result += fmt.Sprintf("%-20s %-20s %-10s %-20s %-10s\n",
+		result += fmt.Sprintf("%-20s %-20s %-10s %-20s %-10s",
 			svc.Namespace, svc.Name, string(svc.Spec.Type), svc.Spec.ClusterIP, ports)
+		if wide {
+			result += fmt.Sprintf(" %-30s", formatSelector(svc.Spec.Selector))
+		}
+		result += "\n"
 	}
 	return result
 }
 
-func formatDeployments(deployments *appsv1.DeploymentList) string {
+// formatDeployments wide 时追加容器、镜像和 selector
+func formatDeployments(deployments *appsv1.DeploymentList, wide bool) string {
 	var result string
 
-	result = fmt.Sprintf("%-20s %-20s %-10s %-10s %-10s %-10s\n", "NAMESPACE", "NAME", "READY", "UP-TO-DATE", "AVAILABLE", "AGE")
+	result = fmt.Sprintf("%-20s %-20s %-10s %-10s %-10s %-10s", "NAMESPACE", "NAME", "READY", "UP-TO-DATE", "AVAILABLE", "AGE")
+	if wide {
+		result += fmt.Sprintf(" %-20s %-40s %-30s", "CONTAINERS", "IMAGES", "SELECTOR")
+	}
+	result += "\n"
 	now := time.Now()
 	for _, dep := range deployments.Items {
 		ready := fmt.Sprintf("%d/%d", dep.Status.ReadyReplicas, dep.Status.Replicas)
 		age := now.Sub(dep.CreationTimestamp.Time)
 		ageStr := formatDuration(age)
-		result += fmt.Sprintf("%-20s %-20s %-10s %-10d %-10d %-10s\n",
+		result += fmt.Sprintf("%-20s %-20s %-10s %-10d %-10d %-10s",
 			dep.Namespace, dep.Name, ready, dep.Status.UpdatedReplicas, dep.Status.AvailableReplicas, ageStr)
+		if wide {
+			var containers, images []string
+			for _, container := range dep.Spec.Template.Spec.Containers {
+				containers = append(containers, container.Name)
+				images = append(images, container.Image)
+			}
+			selector := ""
+			if dep.Spec.Selector != nil {
+				selector = formatSelector(dep.Spec.Selector.MatchLabels)
+			}
+			result += fmt.Sprintf(" %-20s %-40s %-30s", strings.Join(containers, ","), strings.Join(images, ","), selector)
+		}
+		result += "\n"
 	}
 	return result
 }
 
-func formatClusterInfo(version *version.Info) string {
+func formatClusterInfo(config *rest.Config, version *version.Info) string {
 	var result string
 
-	result = fmt.Sprintf("Kubernetes control plane is running at %s\n", conf.KubeConfig.Host)
+	result = fmt.Sprintf("Kubernetes control plane is running at %s\n", config.Host)
 	result += fmt.Sprintf("Kubernetes version: %s\n", version.String())
 	return result
 }
 
-func formatNodes(nodes *v1.NodeList) string {
+// formatNodes wide 时追加操作系统、内核和容器运行时
+func formatNodes(nodes *v1.NodeList, wide bool) string {
 	var result string
 
-	result = fmt.Sprintf("%-20s %-10s %-10s %-10s %-10s %-10s\n", "NAME", "STATUS", "ROLES", "AGE", "VERSION", "INTERNAL-IP")
+	result = fmt.Sprintf("%-20s %-10s %-10s %-10s %-10s %-10s", "NAME", "STATUS", "ROLES", "AGE", "VERSION", "INTERNAL-IP")
+	if wide {
+		result += fmt.Sprintf(" %-30s %-25s %-25s", "OS-IMAGE", "KERNEL-VERSION", "CONTAINER-RUNTIME")
+	}
+	result += "\n"
 	now := time.Now()
 	for _, node := range nodes.Items {
 		age := now.Sub(node.CreationTimestamp.Time)
@@ -665,12 +605,34 @@ func formatNodes(nodes *v1.NodeList) string {
 				break
 			}
 		}
-		result += fmt.Sprintf("%-20s %-10s %-10s %-10s %-10s %-10s\n",
+		result += fmt.Sprintf("%-20s %-10s %-10s %-10s %-10s %-10s",
 			node.Name, string(node.Status.Conditions[len(node.Status.Conditions)-1].Type), roles, ageStr, node.Status.NodeInfo.KubeletVersion, internalIP)
+		if wide {
+			info := node.Status.NodeInfo
+			result += fmt.Sprintf(" %-30s %-25s %-25s", info.OSImage, info.KernelVersion, info.ContainerRuntimeVersion)
+		}
+		result += "\n"
 	}
 	return result
 }
 
+// formatSelector 将标签选择器格式化为 k1=v1,k2=v2，按 key 排序
+func formatSelector(selector map[string]string) string {
+	if len(selector) == 0 {
+		return "<none>"
+	}
+	keys := make([]string, 0, len(selector))
+	for key := range selector {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, selector[key]))
+	}
+	return strings.Join(pairs, ",")
+}
+
 // formatDuration 将时间间隔格式化为类似 "19h" 的字符串
 func formatDuration(d time.Duration) string {
 	if d.Hours() >= 1 {
@@ -682,43 +644,35 @@ func formatDuration(d time.Duration) string {
 	}
 }
 
-// 从 YAML 内容中提取资源名称和标签
-func (s *SocketService) extractResourceInfo(yamlContent []byte) (string, map[string]string, error) {
-	// 创建一个通用的 map 来解析 YAML
-	var resource map[string]interface{}
-	if err := yaml.Unmarshal(yamlContent, &resource); err != nil {
-		return "", nil, fmt.Errorf("解析 YAML 失败: %v", err)
+// sendResourceOutput 发送 get / describe 的结果，json / yaml 输出时没有取到任何对象则返回文本中的错误信息
+func sendResourceOutput(conn *websocket.Conn, command string, output string, objects []runtime.Object, result string) {
+	if isStructuredOutput(output) && len(objects) == 0 {
+		SendError(conn, result)
+		return
 	}
+	sendCommandOutput(conn, command, output, objects, func(bool) string {
+		return result
+	})
+}
 
-	// 获取资源名称
-	var resourceName string
-	if metadata, ok := resource["metadata"].(map[string]interface{}); ok {
-		if name, ok := metadata["name"].(string); ok {
-			resourceName = name
-		}
+// runningResourcesKey 读取 redis_key 参数，只能是调用者自己的运行中资源列表，其中只有本人和团队成员部署的资源
+func runningResourcesKey(data map[string]interface{}, userID uint) (string, error) {
+	redisKey, exist := data["redis_key"].(string)
+	if !exist {
+		return "", fmt.Errorf("缺少redis_key参数")
 	}
-
-	// 获取资源标签
-	labels := make(map[string]string)
-	if metadata, ok := resource["metadata"].(map[string]interface{}); ok {
-		if labelsMap, ok := metadata["labels"].(map[string]interface{}); ok {
-			for key, value := range labelsMap {
-				if strValue, ok := value.(string); ok {
-					labels[key] = strValue
-				}
-			}
-		}
+	if redisKey != fmt.Sprintf(define.K8sRunningResources, userID) {
+		return "", fmt.Errorf("无权读取 %s", redisKey)
 	}
-
-	return resourceName, labels, nil
+	return redisKey, nil
 }
 
 // 处理kubectl get命令
 func (s *SocketService) handleResourceGet(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
 	// 从data中获取redis_key
-	redisKey, exist := data["redis_key"].(string)
-	if !exist {
-		SendError(conn, "缺少redis_key参数")
+	redisKey, err := runningResourcesKey(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
 		return
 	}
 
@@ -743,6 +697,7 @@ func (s *SocketService) handleResourceGet(conn *websocket.Conn, command string,
 		ResourceType string `json:"resource_type"`
 		Namespace    string `json:"namespace"`
 		UserID       int    `json:"user_id"`
+		ClusterID    uint32 `json:"cluster_id"`
 	}
 	if err := json.Unmarshal([]byte(resourceInfoJSON), &resources); err != nil {
 		SendError(conn, fmt.Sprintf("解析资源信息失败: %v", err))
@@ -758,9 +713,12 @@ func (s *SocketService) handleResourceGet(conn *websocket.Conn, command string,
 		return
 	}
 
-	// 执行kubectl get命令并格式化结果
+	output, _ := parseOutput(data)
+
+	// 执行kubectl get命令并格式化结果，json / yaml 输出时同时收集对象
 	var result string
 	var fullCommand string
+	var objects []runtime.Object
 
 	// 根据资源类型构建表头
 
@@ -769,19 +727,33 @@ func (s *SocketService) handleResourceGet(conn *websocket.Conn, command string,
 			continue
 		}
 
+		clients, err := s.clusterClientsByID(resource.ClusterID, userID)
+		if err != nil {
+			result += err.Error() + "\n"
+			continue
+		}
+
 		switch resource.ResourceType {
 		case "deployment":
 			result = fmt.Sprintf("%-20s %-10s %-10s %-10s %-10s %-15s %-20s %-30s %-20s\n",
 				"NAME", "READY", "UP-TO-DATE", "AVAILABLE", "AGE", "CONTAINERS", "IMAGES", "SELECTOR", "NAMESPACE")
-			fullCommand = fmt.Sprintf("kubectl get deployment %s -n %s -o wide", resource.ResourceName, resource.Namespace)
+			fullCommand = fmt.Sprintf("kubectl get deployment %s -n %s", resource.ResourceName, resource.Namespace)
 		case "service":
 			result = fmt.Sprintf("%-20s %-10s %-20s %-10s %-15s %-20s %-20s\n",
 				"NAME", "TYPE", "CLUSTER-IP", "PORT(S)", "SELECTOR", "NAMESPACE", "AGE")
-			fullCommand = fmt.Sprintf("kubectl get service %s -n %s -o wide", resource.ResourceName, resource.Namespace)
+			fullCommand = fmt.Sprintf("kubectl get service %s -n %s", resource.ResourceName, resource.Namespace)
+		case "ingress":
+			result = fmt.Sprintf("%-20s %-15s %-30s %-20s %-10s %-10s %-20s\n",
+				"NAME", "CLASS", "HOSTS", "ADDRESS", "PORTS", "AGE", "NAMESPACE")
+			fullCommand = fmt.Sprintf("kubectl get ingress %s -n %s", resource.ResourceName, resource.Namespace)
 		case "pod":
 			result = fmt.Sprintf("%-20s %-10s %-10s %-10s %-10s %-15s %-20s %-20s %-20s\n",
 				"NAME", "READY", "STATUS", "RESTARTS", "AGE", "IP", "NODE", "NOMINATED NODE", "NAMESPACE")
-			fullCommand = fmt.Sprintf("kubectl get pod %s -n %s -o wide", resource.ResourceName, resource.Namespace)
+			fullCommand = fmt.Sprintf("kubectl get pod %s -n %s", resource.ResourceName, resource.Namespace)
+		case "statefulset", "daemonset", "job", "cronjob":
+			// 表头由 formatWorkload 输出
+			result = ""
+			fullCommand = fmt.Sprintf("kubectl get %s %s -n %s", resource.ResourceType, resource.ResourceName, resource.Namespace)
 		default:
 			result = fmt.Sprintf("不支持的资源类型: %s\n", resource.ResourceType)
 			fullCommand = command
@@ -791,7 +763,7 @@ func (s *SocketService) handleResourceGet(conn *websocket.Conn, command string,
 
 		switch resource.ResourceType {
 		case "deployment":
-			deployments, err := conf.KubeClient.AppsV1().Deployments(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
+			deployments, err := clients.Client.AppsV1().Deployments(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
 			if err != nil {
 				if k8serrors.IsNotFound(err) {
 					resourceResult = fmt.Sprintf("在命名空间 %s 中未找到 Deployment %s\n", resource.Namespace, resource.ResourceName)
@@ -799,11 +771,18 @@ func (s *SocketService) handleResourceGet(conn *websocket.Conn, command string,
 					resourceResult = fmt.Sprintf("获取 Deployment %s 失败: %v\n", resource.ResourceName, err)
 				}
 			} else {
-				// 格式化单个deployment
+				// 格式化单个deployment，附带以其为目标的 HPA
 				resourceResult = formatSingleDeployment(deployments)
+				objects = append(objects, deployments)
+				if hpas, err := findAutoscalers(ctx, clients, resource.Namespace, resource.ResourceName); err == nil {
+					resourceResult += formatAutoscalers(hpas)
+					for i := range hpas {
+						objects = append(objects, &hpas[i])
+					}
+				}
 			}
 		case "service":
-			services, err := conf.KubeClient.CoreV1().Services(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
+			services, err := clients.Client.CoreV1().Services(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
 			if err != nil {
 				if k8serrors.IsNotFound(err) {
 					resourceResult = fmt.Sprintf("在命名空间 %s 中未找到 Service %s\n", resource.Namespace, resource.ResourceName)
@@ -813,9 +792,23 @@ func (s *SocketService) handleResourceGet(conn *websocket.Conn, command string,
 			} else {
 				// 格式化单个service
 				resourceResult = formatSingleService(services)
+				objects = append(objects, services)
+			}
+		case "ingress":
+			ingress, err := clients.Client.NetworkingV1().Ingresses(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					resourceResult = fmt.Sprintf("在命名空间 %s 中未找到 Ingress %s\n", resource.Namespace, resource.ResourceName)
+				} else {
+					resourceResult = fmt.Sprintf("获取 Ingress %s 失败: %v\n", resource.ResourceName, err)
+				}
+			} else {
+				// 格式化单个ingress
+				resourceResult = formatSingleIngress(ingress)
+				objects = append(objects, ingress)
 			}
 		case "pod":
-			pods, err := conf.KubeClient.CoreV1().Pods(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
+			pods, err := clients.Client.CoreV1().Pods(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
 			if err != nil {
 				if k8serrors.IsNotFound(err) {
 					resourceResult = fmt.Sprintf("在命名空间 %s 中未找到 Pod %s\n", resource.Namespace, resource.ResourceName)
@@ -825,6 +818,19 @@ func (s *SocketService) handleResourceGet(conn *websocket.Conn, command string,
 			} else {
 				// 格式化单个pod
 				resourceResult = formatSinglePod(pods)
+				objects = append(objects, pods)
+			}
+		case "statefulset", "daemonset", "job", "cronjob":
+			workload, err := getWorkload(ctx, clients, resource.ResourceType, resource.Namespace, resource.ResourceName)
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					resourceResult = fmt.Sprintf("在命名空间 %s 中未找到 %s %s\n", resource.Namespace, k8s_manage.ResourceKinds[resource.ResourceType].Kind, resource.ResourceName)
+				} else {
+					resourceResult = fmt.Sprintf("获取 %s %s 失败: %v\n", k8s_manage.ResourceKinds[resource.ResourceType].Kind, resource.ResourceName, err)
+				}
+			} else {
+				resourceResult = formatWorkload(workload, true)
+				objects = append(objects, workload)
 			}
 		default:
 			resourceResult = fmt.Sprintf("不支持的资源类型: %s\n", resource.ResourceType)
@@ -833,19 +839,19 @@ func (s *SocketService) handleResourceGet(conn *websocket.Conn, command string,
 		result += resourceResult
 	}
 
-	// 发送结果
-	SendSuccess(conn, "command execute success", K8sCommandResponse{
-		Command: fullCommand,
-		Result:  result,
-	})
+	// 单个资源的表格本身就是 wide 格式
+	if output == OutputTable {
+		output = OutputWide
+	}
+	sendResourceOutput(conn, fullCommand, output, objects, result)
 }
 
 // 处理kubectl describe命令
 func (s *SocketService) handleResourceDescribe(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
 	// 从data中获取redis_key
-	redisKey, exist := data["redis_key"].(string)
-	if !exist {
-		SendError(conn, "缺少redis_key参数")
+	redisKey, err := runningResourcesKey(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
 		return
 	}
 
@@ -870,6 +876,7 @@ func (s *SocketService) handleResourceDescribe(conn *websocket.Conn, command str
 		ResourceType string `json:"resource_type"`
 		Namespace    string `json:"namespace"`
 		UserID       int    `json:"user_id"`
+		ClusterID    uint32 `json:"cluster_id"`
 	}
 	if err := json.Unmarshal([]byte(resourceInfoJSON), &resources); err != nil {
 		SendError(conn, fmt.Sprintf("解析资源信息失败: %v", err))
@@ -885,23 +892,36 @@ func (s *SocketService) handleResourceDescribe(conn *websocket.Conn, command str
 		return
 	}
 
-	// 执行kubectl describe命令并格式化结果
+	output, _ := parseOutput(data)
+
+	// 执行kubectl describe命令并格式化结果，json / yaml 输出时同时收集对象
 	var result string
 	var fullCommand string
+	var objects []runtime.Object
 
 	for _, resource := range resources {
 		if resource.ResourceName != resourceName {
 			continue
 		}
 
+		clients, err := s.clusterClientsByID(resource.ClusterID, userID)
+		if err != nil {
+			result += err.Error() + "\n"
+			continue
+		}
+
 		// 根据资源类型构建完整命令
 		switch resource.ResourceType {
 		case "deployment":
 			fullCommand = fmt.Sprintf("kubectl describe deployment %s -n %s", resource.ResourceName, resource.Namespace)
 		case "service":
 			fullCommand = fmt.Sprintf("kubectl describe service %s -n %s", resource.ResourceName, resource.Namespace)
+		case "ingress":
+			fullCommand = fmt.Sprintf("kubectl describe ingress %s -n %s", resource.ResourceName, resource.Namespace)
 		case "pod":
 			fullCommand = fmt.Sprintf("kubectl describe pod %s -n %s", resource.ResourceName, resource.Namespace)
+		case "statefulset", "daemonset", "job", "cronjob":
+			fullCommand = fmt.Sprintf("kubectl describe %s %s -n %s", resource.ResourceType, resource.ResourceName, resource.Namespace)
 		default:
 			fullCommand = command
 		}
@@ -910,7 +930,7 @@ func (s *SocketService) handleResourceDescribe(conn *websocket.Conn, command str
 
 		switch resource.ResourceType {
 		case "deployment":
-			deployments, err := conf.KubeClient.AppsV1().Deployments(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
+			deployments, err := clients.Client.AppsV1().Deployments(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
 			if err != nil {
 				if k8serrors.IsNotFound(err) {
 					resourceResult = fmt.Sprintf("在命名空间 %s 中未找到 Deployment %s\n", resource.Namespace, resource.ResourceName)
@@ -918,11 +938,18 @@ func (s *SocketService) handleResourceDescribe(conn *websocket.Conn, command str
 					resourceResult = fmt.Sprintf("获取 Deployment %s 失败: %v\n", resource.ResourceName, err)
 				}
 			} else {
-				// 格式化单个deployment的详细信息
+				// 格式化单个deployment的详细信息，附带以其为目标的 HPA
 				resourceResult = formatDeploymentDetail(deployments)
+				objects = append(objects, deployments)
+				if hpas, err := findAutoscalers(ctx, clients, resource.Namespace, resource.ResourceName); err == nil {
+					resourceResult += formatAutoscalerDetails(hpas)
+					for i := range hpas {
+						objects = append(objects, &hpas[i])
+					}
+				}
 			}
 		case "service":
-			services, err := conf.KubeClient.CoreV1().Services(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
+			services, err := clients.Client.CoreV1().Services(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
 			if err != nil {
 				if k8serrors.IsNotFound(err) {
 					resourceResult = fmt.Sprintf("在命名空间 %s 中未找到 Service %s\n", resource.Namespace, resource.ResourceName)
@@ -931,10 +958,24 @@ func (s *SocketService) handleResourceDescribe(conn *websocket.Conn, command str
 				}
 			} else {
 				// 格式化单个service的详细信息
-				resourceResult = formatServiceDetail(services)
+				resourceResult = formatServiceDetail(clients, services)
+				objects = append(objects, services)
+			}
+		case "ingress":
+			ingress, err := clients.Client.NetworkingV1().Ingresses(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					resourceResult = fmt.Sprintf("在命名空间 %s 中未找到 Ingress %s\n", resource.Namespace, resource.ResourceName)
+				} else {
+					resourceResult = fmt.Sprintf("获取 Ingress %s 失败: %v\n", resource.ResourceName, err)
+				}
+			} else {
+				// 格式化单个ingress的详细信息，包含后端 Service 的解析结果
+				resourceResult = formatIngressDetail(clients, ingress)
+				objects = append(objects, ingress)
 			}
 		case "pod":
-			pods, err := conf.KubeClient.CoreV1().Pods(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
+			pods, err := clients.Client.CoreV1().Pods(resource.Namespace).Get(ctx, resource.ResourceName, metav1.GetOptions{})
 			if err != nil {
 				if k8serrors.IsNotFound(err) {
 					resourceResult = fmt.Sprintf("在命名空间 %s 中未找到 Pod %s\n", resource.Namespace, resource.ResourceName)
@@ -943,7 +984,21 @@ func (s *SocketService) handleResourceDescribe(conn *websocket.Conn, command str
 				}
 			} else {
 				// 格式化单个pod的详细信息
-				resourceResult = formatPodDetail(pods)
+				resourceResult = formatPodDetail(clients, pods)
+				objects = append(objects, pods)
+			}
+		case "statefulset", "daemonset", "job", "cronjob":
+			workload, err := getWorkload(ctx, clients, resource.ResourceType, resource.Namespace, resource.ResourceName)
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					resourceResult = fmt.Sprintf("在命名空间 %s 中未找到 %s %s\n", resource.Namespace, k8s_manage.ResourceKinds[resource.ResourceType].Kind, resource.ResourceName)
+				} else {
+					resourceResult = fmt.Sprintf("获取 %s %s 失败: %v\n", k8s_manage.ResourceKinds[resource.ResourceType].Kind, resource.ResourceName, err)
+				}
+			} else {
+				// 详细信息包含按资源类型计算的健康状态
+				resourceResult = formatWorkloadDetail(workload)
+				objects = append(objects, workload)
 			}
 		default:
 			resourceResult = fmt.Sprintf("不支持的资源类型: %s\n", resource.ResourceType)
@@ -952,11 +1007,7 @@ func (s *SocketService) handleResourceDescribe(conn *websocket.Conn, command str
 		result += resourceResult
 	}
 
-	// 发送结果
-	SendSuccess(conn, "command execute success", K8sCommandResponse{
-		Command: fullCommand,
-		Result:  result,
-	})
+	sendResourceOutput(conn, fullCommand, output, objects, result)
 }
 
 // 格式化单个Deployment
@@ -1125,7 +1176,7 @@ func formatDeploymentDetail(deployment *appsv1.Deployment) string {
 }
 
 // 格式化Service详细信息
-func formatServiceDetail(service *v1.Service) string {
+func formatServiceDetail(clients *conf.K8sClients, service *v1.Service) string {
 	var result string
 
 	// 基本信息
@@ -1229,7 +1280,7 @@ func formatServiceDetail(service *v1.Service) string {
 	}
 
 	// 端点信息
-	endpoints, err := conf.KubeClient.CoreV1().Endpoints(service.Namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
+	endpoints, err := clients.Client.CoreV1().Endpoints(service.Namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
 	if err == nil && len(endpoints.Subsets) > 0 {
 		endpointAddresses := ""
 		for _, subset := range endpoints.Subsets {
@@ -1263,7 +1314,7 @@ func formatServiceDetail(service *v1.Service) string {
 	}
 
 	// 事件信息
-	events, err := conf.KubeClient.CoreV1().Events(service.Namespace).List(context.TODO(), metav1.ListOptions{
+	events, err := clients.Client.CoreV1().Events(service.Namespace).List(context.TODO(), metav1.ListOptions{
 		FieldSelector: fmt.Sprintf("involvedObject.name=%s,involvedObject.kind=Service", service.Name),
 	})
 	if err == nil && len(events.Items) > 0 {
@@ -1283,7 +1334,7 @@ func formatServiceDetail(service *v1.Service) string {
 }
 
 // 格式化Pod详细信息
-func formatPodDetail(pod *v1.Pod) string {
+func formatPodDetail(clients *conf.K8sClients, pod *v1.Pod) string {
 	var result string
 
 	result = fmt.Sprintf("Name: %s\n", pod.Name)
@@ -1330,7 +1381,7 @@ func formatPodDetail(pod *v1.Pod) string {
 	}
 
 	// 添加事件信息
-	events, err := conf.KubeClient.CoreV1().Events(pod.Namespace).List(context.TODO(), metav1.ListOptions{
+	events, err := clients.Client.CoreV1().Events(pod.Namespace).List(context.TODO(), metav1.ListOptions{
 		FieldSelector: fmt.Sprintf("involvedObject.name=%s,involvedObject.kind=Pod", pod.Name),
 	})
 	if err == nil {
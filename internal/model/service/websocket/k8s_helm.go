@@ -0,0 +1,476 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// helmCommandTimeout 单次 helm 命令的超时时间
+	helmCommandTimeout = 10 * time.Minute
+	// helmWaitTimeout 指定 wait 时等待资源就绪的时间
+	helmWaitTimeout = "5m0s"
+	// helmTokenExpirationSeconds helm 使用的 team-deployer token 有效期
+	helmTokenExpirationSeconds int64 = 3600
+)
+
+// helmReleaseInfo helm -o json 输出的 release 信息
+type helmReleaseInfo struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Info      struct {
+		Status      string `json:"status"`
+		Description string `json:"description"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+}
+
+// helmHistoryEntry helm history -o json 输出的单个 revision
+type helmHistoryEntry struct {
+	Revision    int    `json:"revision"`
+	Updated     string `json:"updated"`
+	Status      string `json:"status"`
+	Chart       string `json:"chart"`
+	AppVersion  string `json:"app_version"`
+	Description string `json:"description"`
+}
+
+// helmSession 一次 helm 操作的临时工作目录，kubeconfig 使用 namespace 中 team-deployer 的 token，
+// 由 RBAC 保证 chart 只能写入团队的 namespace
+type helmSession struct {
+	dir        string
+	kubeconfig string
+	namespace  string
+}
+
+// newHelmSession 校验 namespace 归属并生成 helm 使用的 kubeconfig
+func (s *SocketService) newHelmSession(ctx context.Context, clients *conf.K8sClients, namespace string, userID uint) (*helmSession, error) {
+	if err := s.k8sNamespaceService.CheckNamespaceAccess(userID, clients.ClusterID, namespace); err != nil {
+		return nil, err
+	}
+
+	expiration := helmTokenExpirationSeconds
+	token, err := clients.Client.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, define.K8sTeamServiceAccount, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expiration},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("申请 %s 的 token 失败: %v", define.K8sTeamServiceAccount, err)
+	}
+
+	kubeconfig, err := clients.Kubeconfig(token.Status.Token, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("生成 kubeconfig 失败: %v", err)
+	}
+
+	dir, err := os.MkdirTemp("", "helm-")
+	if err != nil {
+		return nil, fmt.Errorf("创建 helm 工作目录失败: %v", err)
+	}
+	session := &helmSession{dir: dir, kubeconfig: filepath.Join(dir, "kubeconfig"), namespace: namespace}
+	if err := os.WriteFile(session.kubeconfig, kubeconfig, 0600); err != nil {
+		session.cleanup()
+		return nil, fmt.Errorf("写入 kubeconfig 失败: %v", err)
+	}
+	return session, nil
+}
+
+// cleanup 删除临时工作目录
+func (h *helmSession) cleanup() {
+	if err := os.RemoveAll(h.dir); err != nil {
+		logrus.Errorf("删除 helm 工作目录失败: %v", err)
+	}
+}
+
+// run 执行 helm 命令，args 为子命令和选项，不包含连接参数；release、chart 等位置参数放在 -- 之后，
+// 避免被 helm 当成选项解析，返回标准输出
+func (h *helmSession) run(ctx context.Context, args []string, positional ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, helmCommandTimeout)
+	defer cancel()
+
+	fullArgs := append(append([]string{}, args...), "--namespace", h.namespace, "--kubeconfig", h.kubeconfig, "--")
+	fullArgs = append(fullArgs, positional...)
+	cmd := exec.CommandContext(ctx, "helm", fullArgs...)
+	cmd.Dir = h.dir
+	output, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("服务器未安装 helm 命令行，请联系管理员安装 helm %s 及以上版本", k8s_manage.HelmMinVersion)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("helm %s 失败: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("helm %s 失败: %v", args[0], err)
+	}
+	return output, nil
+}
+
+// status 查询 release 当前的状态
+func (h *helmSession) status(ctx context.Context, release string) (*helmReleaseInfo, error) {
+	output, err := h.run(ctx, []string{"status", "-o", "json"}, release)
+	if err != nil {
+		return nil, err
+	}
+	return parseHelmRelease(output)
+}
+
+// helmInstall 安装 helm 资源描述的 release，upgrade 为 true 时升级已有的 release
+func (s *SocketService) helmInstall(conn *websocket.Conn, command string, data map[string]interface{}, userID uint, upgrade bool) {
+	logrus.Info("helm install ", "data: ", data)
+	resource, clients, err := s.helmResource(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	options := parseRenderOptions(data)
+	rendered, err := s.k8sResourceService.RenderResource(userID, resource.Id, resource, options)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	release, err := k8s_manage.ParseHelmRelease(rendered.Content)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	ctx := context.TODO()
+	session, err := s.newHelmSession(ctx, clients, release.Namespace, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	defer session.cleanup()
+
+	// OSS 上的 chart 包需要先用用户的 OSS 凭证下载
+	chart := release.Chart
+	if release.IsOssChart() {
+		bucket, err := s.ossService.GetBucket(userID)
+		if err != nil {
+			SendError(conn, err.Error())
+			return
+		}
+		chart = filepath.Join(session.dir, filepath.Base(release.Chart))
+		if err := bucket.GetObjectToFile(ossObjectName(release.Chart), chart); err != nil {
+			SendError(conn, fmt.Sprintf("从 OSS 下载 chart 失败: %v", err))
+			return
+		}
+	}
+
+	valuesJSON, err := json.Marshal(release.Values)
+	if err != nil {
+		SendError(conn, fmt.Sprintf("序列化 values 失败: %v", err))
+		return
+	}
+	valuesFile := filepath.Join(session.dir, "values.json")
+	if err := os.WriteFile(valuesFile, valuesJSON, 0600); err != nil {
+		SendError(conn, fmt.Sprintf("写入 values 失败: %v", err))
+		return
+	}
+
+	action, operationType := "install", "create"
+	if upgrade {
+		action, operationType = "upgrade", "update"
+	}
+	args := []string{action, "-f", valuesFile}
+	displayArgs := []string{action, release.Release, release.Chart}
+	if release.Repo != "" {
+		args = append(args, "--repo", release.Repo)
+		displayArgs = append(displayArgs, "--repo", release.Repo)
+	}
+	if release.Version != "" {
+		args = append(args, "--version", release.Version)
+		displayArgs = append(displayArgs, "--version", release.Version)
+	}
+	if wait, _ := data["wait"].(bool); wait {
+		args = append(args, "--wait", "--timeout", helmWaitTimeout)
+		displayArgs = append(displayArgs, "--wait", "--timeout", helmWaitTimeout)
+	}
+	args = append(args, "-o", "json")
+
+	output, err := session.run(ctx, args, release.Release, chart)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	info, err := parseHelmRelease(output)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	fullCommand := helmCommand(displayArgs, release.Namespace)
+	if rendered := options.String(); rendered != "" {
+		fullCommand += " " + rendered
+	}
+	s.saveHelmOperationLog(resource, userID, info, operationType, fullCommand)
+
+	SendSuccess(conn, "command execute success", K8sCommandResponse{
+		Command: command,
+		Result:  fmt.Sprintf("release %s 已%s到 namespace %s，chart %s-%s，revision %d，状态 %s", info.Name, action, info.Namespace, info.Chart.Metadata.Name, info.Chart.Metadata.Version, info.Version, info.Info.Status),
+	})
+}
+
+// helmRollback 将 release 回滚到指定 revision，未指定时回滚到上一个 revision
+func (s *SocketService) helmRollback(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	logrus.Info("helm rollback ", "data: ", data)
+	resource, clients, err := s.helmResource(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	latestLog, err := s.latestHelmLog(resource)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	ctx := context.TODO()
+	session, err := s.newHelmSession(ctx, clients, latestLog.Namespace, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	defer session.cleanup()
+
+	args := []string{"rollback"}
+	positional := []string{latestLog.MetadataName}
+	if revision, ok := data["revision"].(float64); ok {
+		positional = append(positional, strconv.Itoa(int(revision)))
+	}
+	if wait, _ := data["wait"].(bool); wait {
+		args = append(args, "--wait", "--timeout", helmWaitTimeout)
+	}
+	if _, err := session.run(ctx, args, positional...); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	info, err := session.status(ctx, latestLog.MetadataName)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	s.saveHelmOperationLog(resource, userID, info, "rollback", helmCommand(append(args, positional...), latestLog.Namespace))
+
+	SendSuccess(conn, "command execute success", K8sCommandResponse{
+		Command: command,
+		Result:  fmt.Sprintf("release %s 已回滚，当前 revision %d，状态 %s (%s)", info.Name, info.Version, info.Info.Status, info.Info.Description),
+	})
+}
+
+// helmUninstall 卸载 release
+func (s *SocketService) helmUninstall(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	logrus.Info("helm uninstall ", "data: ", data)
+	resource, clients, err := s.helmResource(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	latestLog, err := s.latestHelmLog(resource)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	if latestLog.OperationType == "delete" {
+		SendError(conn, fmt.Sprintf("release %s 已经卸载", latestLog.MetadataName))
+		return
+	}
+
+	ctx := context.TODO()
+	session, err := s.newHelmSession(ctx, clients, latestLog.Namespace, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	defer session.cleanup()
+
+	args := []string{"uninstall", latestLog.MetadataName}
+	if _, err := session.run(ctx, args[:1], args[1:]...); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	operationLog := &dao.UserK8sResourceOperationLog{
+		K8sResourceID:   uint(resource.Id),
+		UserID:          userID,
+		Namespace:       latestLog.Namespace,
+		MetadataName:    latestLog.MetadataName,
+		MetadataLabels:  latestLog.MetadataLabels,
+		OperationType:   "delete",
+		Status:          define.K8sResourceStatusStop,
+		Command:         helmCommand(args, latestLog.Namespace),
+		ReleaseRevision: latestLog.ReleaseRevision,
+	}
+	if err := s.userK8sResourceOperationLogDao.Create(operationLog); err != nil {
+		logrus.Errorf("保存操作日志失败: %v", err)
+	}
+
+	SendSuccess(conn, "command execute success", K8sCommandResponse{
+		Command: command,
+		Result:  fmt.Sprintf("release %s 已卸载", latestLog.MetadataName),
+	})
+}
+
+// helmHistory 列出 release 的 revision 历史
+func (s *SocketService) helmHistory(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	resource, clients, err := s.helmResource(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	latestLog, err := s.latestHelmLog(resource)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	ctx := context.TODO()
+	session, err := s.newHelmSession(ctx, clients, latestLog.Namespace, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	defer session.cleanup()
+
+	args := []string{"history", latestLog.MetadataName}
+	output, err := session.run(ctx, []string{"history", "-o", "json"}, latestLog.MetadataName)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	var history []helmHistoryEntry
+	if err := json.Unmarshal(output, &history); err != nil {
+		SendError(conn, fmt.Sprintf("解析 helm history 输出失败: %v", err))
+		return
+	}
+
+	SendSuccess(conn, "command execute success", K8sCommandResponse{
+		Command: helmCommand(args, latestLog.Namespace),
+		Result:  formatHelmHistory(history),
+	})
+}
+
+// helmResource 查询 helm 类型的资源及其所在集群，只能操作本人或团队成员的资源
+func (s *SocketService) helmResource(data map[string]interface{}, userID uint) (*dao.UserK8sResource, *conf.K8sClients, error) {
+	k8sResourceID, exist := data["k8s_resource_id"].(float64)
+	if !exist {
+		return nil, nil, fmt.Errorf("缺少k8s_resource_id 参数")
+	}
+
+	resource, err := s.userK8sResourceDao.QueryById(uint32(k8sResourceID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询资源失败: %v", err)
+	}
+	if resource.ResourceType != "helm" {
+		return nil, nil, fmt.Errorf("资源 %d 不是 helm 类型", resource.Id)
+	}
+	if err := s.k8sResourceService.CheckResourceOwner(userID, &resource); err != nil {
+		return nil, nil, err
+	}
+
+	clients, err := s.clusterClientsByID(resource.ClusterID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resource, clients, nil
+}
+
+// latestHelmLog 从最新的操作日志中获取 release 名称和 namespace
+func (s *SocketService) latestHelmLog(resource *dao.UserK8sResource) (*dao.UserK8sResourceOperationLog, error) {
+	logs, err := s.userK8sResourceOperationLogDao.QueryByK8sResourceIDFirst(uint(resource.Id))
+	if err != nil {
+		return nil, fmt.Errorf("查询资源操作日志失败: %v", err)
+	}
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("资源 %d 还没有安装 release", resource.Id)
+	}
+	return logs[0], nil
+}
+
+// saveHelmOperationLog 记录 helm 操作日志，release 的 revision 记录在 ReleaseRevision 中
+func (s *SocketService) saveHelmOperationLog(resource *dao.UserK8sResource, userID uint, info *helmReleaseInfo, operationType string, command string) {
+	labelsJSON, err := json.Marshal(map[string]string{
+		"helm.sh/chart": fmt.Sprintf("%s-%s", info.Chart.Metadata.Name, info.Chart.Metadata.Version),
+	})
+	if err != nil {
+		logrus.Errorf("序列化标签失败: %v", err)
+	}
+
+	operationLog := &dao.UserK8sResourceOperationLog{
+		K8sResourceID:   uint(resource.Id),
+		UserID:          userID,
+		Namespace:       info.Namespace,
+		MetadataName:    info.Name,
+		MetadataLabels:  string(labelsJSON),
+		OperationType:   operationType,
+		Status:          helmReleaseStatus(info.Info.Status),
+		Command:         command,
+		ReleaseRevision: info.Version,
+	}
+	if err := s.userK8sResourceOperationLogDao.Create(operationLog); err != nil {
+		logrus.Errorf("保存操作日志失败: %v", err)
+	}
+}
+
+// parseHelmRelease 解析 helm -o json 输出的 release
+func parseHelmRelease(output []byte) (*helmReleaseInfo, error) {
+	var info helmReleaseInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("解析 helm 输出失败: %v", err)
+	}
+	return &info, nil
+}
+
+// helmReleaseStatus 将 release 状态映射为资源状态
+func helmReleaseStatus(status string) int {
+	switch status {
+	case "deployed":
+		return define.K8sResourceStatusRun
+	case "failed", "uninstalled", "superseded":
+		return define.K8sResourceStatusStop
+	default:
+		// pending-install / pending-upgrade / pending-rollback / uninstalling
+		return define.K8sResourceStatusRestart
+	}
+}
+
+// helmCommand 记录到操作日志中的 helm 命令，不包含 kubeconfig 等连接参数
+func helmCommand(args []string, namespace string) string {
+	return fmt.Sprintf("helm %s -n %s", strings.Join(args, " "), namespace)
+}
+
+// formatHelmHistory 格式化 release 的 revision 历史
+func formatHelmHistory(history []helmHistoryEntry) string {
+	result := fmt.Sprintf("%-10s %-30s %-15s %-30s %-15s %s\n", "REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION", "DESCRIPTION")
+	for _, entry := range history {
+		result += fmt.Sprintf("%-10d %-30s %-15s %-30s %-15s %s\n",
+			entry.Revision, entry.Updated, entry.Status, entry.Chart, entry.AppVersion, entry.Description)
+	}
+	return result
+}
@@ -0,0 +1,244 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ingressRoute Ingress 中的一条转发规则
+type ingressRoute struct {
+	Host    string
+	Path    string
+	Backend *networkingv1.IngressBackend
+}
+
+// ingressRoutes 展开 Ingress 的全部转发规则，defaultBackend 以 host/path 为 * 的规则表示
+func ingressRoutes(ingress *networkingv1.Ingress) []ingressRoute {
+	var routes []ingressRoute
+	if ingress.Spec.DefaultBackend != nil {
+		routes = append(routes, ingressRoute{Host: "*", Path: "*", Backend: ingress.Spec.DefaultBackend})
+	}
+	for _, rule := range ingress.Spec.Rules {
+		host := rule.Host
+		if host == "" {
+			host = "*"
+		}
+		if rule.HTTP == nil {
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			path := rule.HTTP.Paths[i].Path
+			if path == "" {
+				path = "/"
+			}
+			routes = append(routes, ingressRoute{Host: host, Path: path, Backend: &rule.HTTP.Paths[i].Backend})
+		}
+	}
+	return routes
+}
+
+// ingressHosts Ingress 中声明的全部 host
+func ingressHosts(ingress *networkingv1.Ingress) string {
+	var hosts []string
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+	if len(hosts) == 0 {
+		return "*"
+	}
+	return strings.Join(hosts, ",")
+}
+
+// ingressAddress 负载均衡器分配的地址
+func ingressAddress(ingress *networkingv1.Ingress) string {
+	var addresses []string
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			addresses = append(addresses, lb.IP)
+		} else if lb.Hostname != "" {
+			addresses = append(addresses, lb.Hostname)
+		}
+	}
+	if len(addresses) == 0 {
+		return "<pending>"
+	}
+	return strings.Join(addresses, ",")
+}
+
+// ingressClass Ingress 使用的 IngressClass
+func ingressClass(ingress *networkingv1.Ingress) string {
+	if ingress.Spec.IngressClassName != nil {
+		return *ingress.Spec.IngressClassName
+	}
+	if class, ok := ingress.Annotations["kubernetes.io/ingress.class"]; ok {
+		return class
+	}
+	return "<none>"
+}
+
+// ingressPorts 与 kubectl 一致：配置了 TLS 时为 80, 443
+func ingressPorts(ingress *networkingv1.Ingress) string {
+	if len(ingress.Spec.TLS) > 0 {
+		return "80, 443"
+	}
+	return "80"
+}
+
+// backendServicePort 后端 Service 的端口描述
+func backendServicePort(backend *networkingv1.IngressBackend) string {
+	if backend.Service == nil {
+		if backend.Resource != nil {
+			return fmt.Sprintf("%s/%s", backend.Resource.Kind, backend.Resource.Name)
+		}
+		return "<none>"
+	}
+	if backend.Service.Port.Name != "" {
+		return fmt.Sprintf("%s:%s", backend.Service.Name, backend.Service.Port.Name)
+	}
+	return fmt.Sprintf("%s:%d", backend.Service.Name, backend.Service.Port.Number)
+}
+
+// resolveBackend 解析后端 Service，返回 ClusterIP 和 Endpoints 地址，Service 不存在时返回错误说明
+func resolveBackend(clients *conf.K8sClients, namespace string, backend *networkingv1.IngressBackend) (string, string) {
+	if backend.Service == nil {
+		return "-", "-"
+	}
+
+	ctx := context.TODO()
+	service, err := clients.Client.CoreV1().Services(namespace).Get(ctx, backend.Service.Name, metav1.GetOptions{})
+	if err != nil {
+		return "<not found>", "<none>"
+	}
+
+	targetPort := int32(0)
+	for _, port := range service.Spec.Ports {
+		if port.Port == backend.Service.Port.Number || (backend.Service.Port.Name != "" && port.Name == backend.Service.Port.Name) {
+			targetPort = port.TargetPort.IntVal
+		}
+	}
+
+	endpoints, err := clients.Client.CoreV1().Endpoints(namespace).Get(ctx, service.Name, metav1.GetOptions{})
+	if err != nil {
+		return service.Spec.ClusterIP, "<none>"
+	}
+	return service.Spec.ClusterIP, formatEndpointAddresses(endpoints, targetPort)
+}
+
+// formatEndpointAddresses 格式化 Endpoints 地址，targetPort 为 0 时使用每个 subset 的第一个端口
+func formatEndpointAddresses(endpoints *v1.Endpoints, targetPort int32) string {
+	var addresses []string
+	for _, subset := range endpoints.Subsets {
+		port := targetPort
+		if port == 0 && len(subset.Ports) > 0 {
+			port = subset.Ports[0].Port
+		}
+		for _, address := range subset.Addresses {
+			addresses = append(addresses, fmt.Sprintf("%s:%d", address.IP, port))
+		}
+	}
+	if len(addresses) == 0 {
+		return "<none>"
+	}
+	return strings.Join(addresses, ",")
+}
+
+// formatIngresses 列出 Ingress 的全部 host / path 及其解析后的后端 Service
+// formatIngresses wide 时追加 ingress class 和创建时长
+func formatIngresses(clients *conf.K8sClients, ingresses *networkingv1.IngressList, wide bool) string {
+	var result string
+
+	result = fmt.Sprintf("%-20s %-20s %-25s %-15s %-25s %-20s %-15s", "NAMESPACE", "NAME", "HOST", "PATH", "BACKEND", "CLUSTER-IP", "ADDRESS")
+	if wide {
+		result += fmt.Sprintf(" %-15s %-10s", "CLASS", "AGE")
+	}
+	result += "\n"
+	for i := range ingresses.Items {
+		ingress := &ingresses.Items[i]
+		for _, route := range ingressRoutes(ingress) {
+			clusterIP, _ := resolveBackend(clients, ingress.Namespace, route.Backend)
+			result += fmt.Sprintf("%-20s %-20s %-25s %-15s %-25s %-20s %-15s",
+				ingress.Namespace, ingress.Name, route.Host, route.Path, backendServicePort(route.Backend), clusterIP, ingressAddress(ingress))
+			if wide {
+				result += fmt.Sprintf(" %-15s %-10s", ingressClass(ingress), formatDuration(time.Since(ingress.CreationTimestamp.Time)))
+			}
+			result += "\n"
+		}
+	}
+	return result
+}
+
+// 格式化单个Ingress
+func formatSingleIngress(ingress *networkingv1.Ingress) string {
+	age := time.Now().Sub(ingress.CreationTimestamp.Time)
+
+	return fmt.Sprintf("%-20s %-15s %-30s %-20s %-10s %-10s %-20s\n",
+		ingress.Name,
+		ingressClass(ingress),
+		ingressHosts(ingress),
+		ingressAddress(ingress),
+		ingressPorts(ingress),
+		formatDuration(age),
+		ingress.Namespace)
+}
+
+// 格式化Ingress详细信息
+func formatIngressDetail(clients *conf.K8sClients, ingress *networkingv1.Ingress) string {
+	var result string
+
+	result = fmt.Sprintf("Name:             %s\n", ingress.Name)
+	result += fmt.Sprintf("Namespace:        %s\n", ingress.Namespace)
+	result += fmt.Sprintf("Labels:           %v\n", ingress.Labels)
+	result += fmt.Sprintf("Annotations:      %v\n", ingress.Annotations)
+	result += fmt.Sprintf("Address:          %s\n", ingressAddress(ingress))
+	result += fmt.Sprintf("Ingress Class:    %s\n", ingressClass(ingress))
+
+	if ingress.Spec.DefaultBackend != nil {
+		result += fmt.Sprintf("Default backend:  %s\n", backendServicePort(ingress.Spec.DefaultBackend))
+	} else {
+		result += "Default backend:  <default>\n"
+	}
+
+	if len(ingress.Spec.TLS) > 0 {
+		result += "TLS:\n"
+		for _, tls := range ingress.Spec.TLS {
+			result += fmt.Sprintf("  %s terminates %s\n", tls.SecretName, strings.Join(tls.Hosts, ","))
+		}
+	}
+
+	// 转发规则及后端解析结果
+	result += "Rules:\n"
+	result += fmt.Sprintf("  %-25s %-20s %-25s %-20s %s\n", "Host", "Path", "Backend", "ClusterIP", "Endpoints")
+	for _, route := range ingressRoutes(ingress) {
+		clusterIP, endpoints := resolveBackend(clients, ingress.Namespace, route.Backend)
+		result += fmt.Sprintf("  %-25s %-20s %-25s %-20s %s\n",
+			route.Host, route.Path, backendServicePort(route.Backend), clusterIP, endpoints)
+	}
+
+	// 事件信息
+	events, err := clients.Client.CoreV1().Events(ingress.Namespace).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("involvedObject.name=%s,involvedObject.kind=Ingress", ingress.Name),
+	})
+	if err == nil && len(events.Items) > 0 {
+		result += "Events:\n"
+		for _, event := range events.Items {
+			result += fmt.Sprintf("  %s  %s  %s  %s\n",
+				event.FirstTimestamp.Format("2006-01-02 15:04:05"),
+				event.Type,
+				event.Reason,
+				event.Message)
+		}
+	} else {
+		result += "Events:           <none>\n"
+	}
+
+	return result
+}
@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// logStreamName 日志流在用户流登记表中的名称
+const logStreamName = "logs"
+
+// handlePodLogs 处理 kubectl logs 命令，在后台 goroutine 中逐行推送容器日志。与 exec 相同，只能查看自己或团队成员部署的 Pod
+func (s *SocketService) handlePodLogs(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	podName, exist := data["pod_name"].(string)
+	if !exist || podName == "" {
+		SendError(conn, "缺少pod_name参数")
+		return
+	}
+
+	namespace, _ := data["namespace"].(string)
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	_, clients, err := s.CheckPodAccess(userID, clusterIDOf(data), namespace, podName)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	logOptions := &v1.PodLogOptions{}
+	fullCommand := fmt.Sprintf("kubectl logs %s -n %s", podName, namespace)
+
+	if container, ok := data["container"].(string); ok && container != "" {
+		logOptions.Container = container
+		fullCommand += fmt.Sprintf(" -c %s", container)
+	}
+	if follow, ok := data["follow"].(bool); ok && follow {
+		logOptions.Follow = true
+		fullCommand += " -f"
+	}
+	if previous, ok := data["previous"].(bool); ok && previous {
+		logOptions.Previous = true
+		fullCommand += " --previous"
+	}
+	if tailLines, ok := data["tail_lines"].(float64); ok && tailLines > 0 {
+		lines := int64(tailLines)
+		logOptions.TailLines = &lines
+		fullCommand += fmt.Sprintf(" --tail=%d", lines)
+	}
+	if sinceSeconds, ok := data["since_seconds"].(float64); ok && sinceSeconds > 0 {
+		seconds := int64(sinceSeconds)
+		logOptions.SinceSeconds = &seconds
+		fullCommand += fmt.Sprintf(" --since=%ds", seconds)
+	}
+
+	ctx := s.startStream(userID, logStreamName)
+	stream, err := clients.Client.CoreV1().Pods(namespace).GetLogs(podName, logOptions).Stream(ctx)
+	if err != nil {
+		s.finishStream(userID, logStreamName, ctx)
+		SendError(conn, fmt.Sprintf("获取 Pod %s 日志失败: %v", podName, err))
+		return
+	}
+
+	SendSuccess(conn, "log_stream_start", K8sCommandResponse{
+		Command: fullCommand,
+		Result:  "",
+	})
+
+	// 在后台推送日志，读循环可以继续接收 stop 消息
+	go func() {
+		defer stream.Close()
+		defer s.finishStream(userID, logStreamName, ctx)
+
+		reader := bufio.NewReader(stream)
+		for {
+			line, err := reader.ReadString('\n')
+			// 断开连接或 stop 后不再写入，连接的写锁已经释放
+			if len(line) > 0 && ctx.Err() == nil {
+				SendSuccess(conn, "log_stream", K8sCommandResponse{
+					Command: fullCommand,
+					Result:  strings.TrimRight(line, "\n"),
+				})
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					logrus.Infof("用户 %d 的日志流 %s 已停止", userID, fullCommand)
+				} else if err != io.EOF {
+					logrus.Warnf("读取 Pod %s 日志失败: %v", podName, err)
+				}
+				break
+			}
+		}
+
+		// 被 stop 或断开连接取消时，已经回复过 stream stopped 或连接已关闭
+		if ctx.Err() != nil {
+			return
+		}
+		SendSuccess(conn, "log_stream_end", K8sCommandResponse{
+			Command: fullCommand,
+			Result:  "log stream end",
+		})
+	}()
+}
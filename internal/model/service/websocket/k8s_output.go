@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// kube 命令的 output 选项，与 kubectl -o 一致
+const (
+	OutputTable = "table"
+	OutputWide  = "wide"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// parseOutput 读取 websocket 参数中的 output，未指定时为 table
+func parseOutput(data map[string]interface{}) (string, error) {
+	output, _ := data["output"].(string)
+	switch output {
+	case "":
+		return OutputTable, nil
+	case OutputTable, OutputWide, OutputJSON, OutputYAML:
+		return output, nil
+	default:
+		return "", fmt.Errorf("不支持的 output: %s，可选 table / wide / json / yaml", output)
+	}
+}
+
+// isStructuredOutput json / yaml 输出时响应中携带结构化对象
+func isStructuredOutput(output string) bool {
+	return output == OutputJSON || output == OutputYAML
+}
+
+// outputCommand 在命令后追加 -o 参数，table 为默认输出不追加
+func outputCommand(command string, output string) string {
+	if output == OutputTable || output == "" {
+		return command
+	}
+	return fmt.Sprintf("%s -o %s", command, output)
+}
+
+// sendCommandOutput 按 output 发送命令结果：table / wide 调用 table 生成文本表格，
+// json / yaml 在 Objects 中携带结构化对象，Result 为对应格式的文本
+func sendCommandOutput(conn *websocket.Conn, command string, output string, objects interface{}, table func(wide bool) string) {
+	response := K8sCommandResponse{
+		Command: outputCommand(command, output),
+		Output:  output,
+	}
+	if !isStructuredOutput(output) {
+		response.Result = table(output == OutputWide)
+		SendSuccess(conn, "command execute success", response)
+		return
+	}
+
+	objects = withTypeMeta(objects)
+	result, err := marshalOutput(objects, output)
+	if err != nil {
+		SendError(conn, fmt.Sprintf("序列化结果失败: %v", err))
+		return
+	}
+	response.Result = result
+	response.Objects = objects
+	SendSuccess(conn, "command execute success", response)
+}
+
+// marshalOutput 将对象序列化为 json 或 yaml 文本
+func marshalOutput(objects interface{}, output string) (string, error) {
+	if output == OutputYAML {
+		content, err := yaml.Marshal(objects)
+		return string(content), err
+	}
+	content, err := json.MarshalIndent(objects, "", "  ")
+	return string(content), err
+}
+
+// withTypeMeta client-go 返回的对象不带 apiVersion / kind，与 kubectl -o json 一致补全，列表中的每个元素同样补全
+func withTypeMeta(objects interface{}) interface{} {
+	switch typed := objects.(type) {
+	case runtime.Object:
+		setTypeMeta(typed)
+		if meta.IsListType(typed) {
+			_ = meta.EachListItem(typed, func(item runtime.Object) error {
+				setTypeMeta(item)
+				return nil
+			})
+		}
+	case []runtime.Object:
+		for _, obj := range typed {
+			setTypeMeta(obj)
+		}
+	}
+	return objects
+}
+
+func setTypeMeta(obj runtime.Object) {
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil || len(gvks) == 0 {
+		return
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+}
@@ -0,0 +1,279 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// FieldChange 单个字段的变化，Op 为 add / remove / change
+type FieldChange struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// ObjectPlan 单个对象的 dry-run 结果，Action 为 create / change / unchanged / error
+type ObjectPlan struct {
+	Kind      string        `json:"kind"`
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace"`
+	Action    string        `json:"action"`
+	Changes   []FieldChange `json:"changes,omitempty"`
+	Message   string        `json:"message,omitempty"`
+}
+
+// ApplyPlan 一次 plan 的完整结果
+type ApplyPlan struct {
+	K8sResourceID uint32       `json:"k8s_resource_id"`
+	Create        int          `json:"create"`
+	Change        int          `json:"change"`
+	Unchanged     int          `json:"unchanged"`
+	Failed        int          `json:"failed"`
+	Objects       []ObjectPlan `json:"objects"`
+}
+
+// K8sPlanResponse plan 命令的响应，Result 为文本形式，Plan 为结构化结果
+type K8sPlanResponse struct {
+	Command string     `json:"command"`
+	Result  string     `json:"result"`
+	Plan    *ApplyPlan `json:"plan"`
+}
+
+// resourcePlan 对资源的 YAML 做 server-side dry-run，并与线上对象比较，不会修改集群
+func (s *SocketService) resourcePlan(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	k8sResourceID, exist := data["k8s_resource_id"].(float64)
+	if !exist {
+		SendError(conn, "缺少k8s_resource_id 参数")
+		return
+	}
+
+	resource, err := s.userK8sResourceDao.QueryById(uint32(k8sResourceID))
+	if err != nil {
+		SendError(conn, fmt.Sprintf("查询资源失败: %v", err))
+		return
+	}
+	if err := s.k8sResourceService.CheckResourceOwner(userID, &resource); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	clients, err := s.clusterClientsByID(resource.ClusterID, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	objects, namespace, localFilePath, err := s.loadResourceObjects(clients, resource.Id, &resource, parseRenderOptions(data), userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	// 与 apply 一致，配置了扩缩容策略时 spec.replicas 使用线上的副本数
+	if err := s.k8sResourceService.KeepLiveReplicas(context.TODO(), clients, resource.Id, namespace, k8s_manage.PrimaryObject(objects, resource.ResourceType)); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	ctx := context.TODO()
+	plan := &ApplyPlan{K8sResourceID: resource.Id}
+	for _, obj := range objects {
+		objectPlan := s.planObject(ctx, clients, obj, namespace)
+		switch objectPlan.Action {
+		case "create":
+			plan.Create++
+		case "change":
+			plan.Change++
+		case "unchanged":
+			plan.Unchanged++
+		default:
+			plan.Failed++
+		}
+		plan.Objects = append(plan.Objects, objectPlan)
+	}
+
+	SendSuccess(conn, "command execute success", K8sPlanResponse{
+		Command: fmt.Sprintf("kubectl diff --server-side --field-manager=%s -f %s -n %s", define.K8sFieldManager, localFilePath, namespace),
+		Result:  formatResourcePlan(plan),
+		Plan:    plan,
+	})
+}
+
+// planObject 对单个对象做 dry-run apply，比较线上对象和 apply 之后的结果
+func (s *SocketService) planObject(ctx context.Context, clients *conf.K8sClients, obj *unstructured.Unstructured, defaultNamespace string) ObjectPlan {
+	objectPlan := ObjectPlan{Kind: obj.GetKind(), Name: obj.GetName()}
+
+	ri, _, err := clients.ResourceInterface(obj, defaultNamespace)
+	objectPlan.Namespace = obj.GetNamespace()
+	if err != nil {
+		objectPlan.Action = "error"
+		objectPlan.Message = err.Error()
+		return objectPlan
+	}
+
+	live, err := ri.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			objectPlan.Action = "error"
+			objectPlan.Message = fmt.Sprintf("获取线上对象失败: %v", err)
+			return objectPlan
+		}
+		live = nil
+	}
+
+	dryRun, err := ri.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{
+		FieldManager: define.K8sFieldManager,
+		Force:        true,
+		DryRun:       []string{metav1.DryRunAll},
+	})
+	if err != nil {
+		objectPlan.Action = "error"
+		objectPlan.Message = fmt.Sprintf("dry-run 失败: %v", err)
+		logrus.Warnf("resource plan dry-run error: %s/%s %v", obj.GetKind(), obj.GetName(), err)
+		return objectPlan
+	}
+
+	// 线上不存在时整个对象都是新建，不再逐字段列出
+	if live == nil {
+		objectPlan.Action = "create"
+		return objectPlan
+	}
+
+	objectPlan.Changes = diffObjects("", k8s_manage.StripServerFields(live.Object), k8s_manage.StripServerFields(dryRun.Object))
+	if len(objectPlan.Changes) == 0 {
+		objectPlan.Action = "unchanged"
+	} else {
+		objectPlan.Action = "change"
+	}
+	return objectPlan
+}
+
+// diffObjects 递归比较两个对象，返回字段级的变化，路径形如 spec.template.spec.containers[0].image
+func diffObjects(path string, old interface{}, new interface{}) []FieldChange {
+	if reflect.DeepEqual(old, new) {
+		return nil
+	}
+	if old == nil {
+		return []FieldChange{{Path: rootPath(path), Op: "add", New: new}}
+	}
+	if new == nil {
+		return []FieldChange{{Path: rootPath(path), Op: "remove", Old: old}}
+	}
+
+	switch oldValue := old.(type) {
+	case map[string]interface{}:
+		newValue, ok := new.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool)
+		for key := range oldValue {
+			keys[key] = true
+		}
+		for key := range newValue {
+			keys[key] = true
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+
+		var changes []FieldChange
+		for _, key := range sortedKeys {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			changes = append(changes, diffObjects(childPath, oldValue[key], newValue[key])...)
+		}
+		return changes
+	case []interface{}:
+		newValue, ok := new.([]interface{})
+		if !ok {
+			break
+		}
+		var changes []FieldChange
+		for i := 0; i < len(oldValue) || i < len(newValue); i++ {
+			var oldItem, newItem interface{}
+			if i < len(oldValue) {
+				oldItem = oldValue[i]
+			}
+			if i < len(newValue) {
+				newItem = newValue[i]
+			}
+			changes = append(changes, diffObjects(fmt.Sprintf("%s[%d]", path, i), oldItem, newItem)...)
+		}
+		return changes
+	}
+	return []FieldChange{{Path: rootPath(path), Op: "change", Old: old, New: new}}
+}
+
+func rootPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+// formatResourcePlan 将 plan 结果格式化为文本，+ 为新增，- 为删除，~ 为修改
+func formatResourcePlan(plan *ApplyPlan) string {
+	var result strings.Builder
+
+	for _, objectPlan := range plan.Objects {
+		target := fmt.Sprintf("%s/%s -n %s", strings.ToLower(objectPlan.Kind), objectPlan.Name, objectPlan.Namespace)
+		switch objectPlan.Action {
+		case "create":
+			result.WriteString(fmt.Sprintf("+ %s (create)\n", target))
+		case "change":
+			result.WriteString(fmt.Sprintf("~ %s (change, %d fields)\n", target, len(objectPlan.Changes)))
+			for _, change := range objectPlan.Changes {
+				switch change.Op {
+				case "add":
+					result.WriteString(fmt.Sprintf("    + %s: %s\n", change.Path, planValue(change.New)))
+				case "remove":
+					result.WriteString(fmt.Sprintf("    - %s: %s\n", change.Path, planValue(change.Old)))
+				default:
+					result.WriteString(fmt.Sprintf("    ~ %s: %s -> %s\n", change.Path, planValue(change.Old), planValue(change.New)))
+				}
+			}
+		case "unchanged":
+			result.WriteString(fmt.Sprintf("  %s (unchanged)\n", target))
+		default:
+			result.WriteString(fmt.Sprintf("! %s (error: %s)\n", target, objectPlan.Message))
+		}
+	}
+
+	result.WriteString(fmt.Sprintf("\nPlan: %d to create, %d to change, %d unchanged, %d failed\n",
+		plan.Create, plan.Change, plan.Unchanged, plan.Failed))
+	return result.String()
+}
+
+// planValue 字段值的单行表示
+func planValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(b)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
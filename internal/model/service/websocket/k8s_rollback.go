@@ -0,0 +1,165 @@
+package websocket
+
+import (
+	"fmt"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/scheduled_tasks"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// resourceRolloutHistory 列出资源的版本历史，只能查看本人或团队成员的资源
+func (s *SocketService) resourceRolloutHistory(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	k8sResourceID, exist := data["k8s_resource_id"].(float64)
+	if !exist {
+		SendError(conn, "缺少k8s_resource_id 参数")
+		return
+	}
+
+	versions, err := s.userK8sResourceDao.QueryVersionChain(uint32(k8sResourceID))
+	if err != nil {
+		SendError(conn, fmt.Sprintf("查询资源版本失败: %v", err))
+		return
+	}
+	if len(versions) == 0 {
+		SendError(conn, fmt.Sprintf("资源 %d 不存在", uint32(k8sResourceID)))
+		return
+	}
+	if err := s.k8sResourceService.CheckResourceOwner(userID, &versions[0]); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	output, _ := parseOutput(data)
+	sendCommandOutput(conn, fmt.Sprintf("kubectl rollout history %d", uint32(k8sResourceID)), output, versions, func(bool) string {
+		return formatResourceVersions(versions)
+	})
+}
+
+// resourceRollback 将资源回滚到历史版本：重新 apply 历史版本的 YAML，并记录 rollback 操作日志
+func (s *SocketService) resourceRollback(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	logrus.Info("resource rollback ", "data: ", data)
+	k8sResourceID, exist := data["k8s_resource_id"].(float64)
+	if !exist {
+		SendError(conn, "缺少k8s_resource_id 参数")
+		return
+	}
+
+	versions, err := s.userK8sResourceDao.QueryVersionChain(uint32(k8sResourceID))
+	if err != nil {
+		SendError(conn, fmt.Sprintf("查询资源版本失败: %v", err))
+		return
+	}
+	if len(versions) == 0 {
+		SendError(conn, fmt.Sprintf("资源 %d 不存在", uint32(k8sResourceID)))
+		return
+	}
+	if err := s.k8sResourceService.CheckResourceOwner(userID, &versions[0]); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	// 状态、差异检测和 scale 都读取当前版本的操作日志，只能从当前版本发起回滚
+	if versions[0].IsUpdate {
+		SendError(conn, fmt.Sprintf("版本 %d 不是资源的当前版本，请使用当前版本的 k8s_resource_id 回滚", uint32(k8sResourceID)))
+		return
+	}
+
+	// 未指定目标版本时回滚到上一个版本
+	var target *dao.UserK8sResource
+	if toResourceID, ok := data["to_resource_id"].(float64); ok {
+		for i := range versions {
+			if versions[i].Id == uint32(toResourceID) {
+				target = &versions[i]
+				break
+			}
+		}
+		if target == nil {
+			SendError(conn, fmt.Sprintf("版本 %d 不在资源 %d 的版本历史中", uint32(toResourceID), uint32(k8sResourceID)))
+			return
+		}
+	} else {
+		if len(versions) < 2 {
+			SendError(conn, "没有可回滚的历史版本")
+			return
+		}
+		target = &versions[1]
+	}
+
+	if target.Id == uint32(k8sResourceID) {
+		SendError(conn, "目标版本与当前版本相同，无需回滚")
+		return
+	}
+
+	// 回滚到当前版本所在的集群
+	clients, err := s.clusterClientsByID(versions[0].ClusterID, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	options, err := s.rollbackRenderOptions(target, data)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	applied, err := s.applyStoredResource(conn, clients, command, uint32(k8sResourceID), target, options, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	// 操作日志挂在当前版本上，并指向被重新 apply 的历史版本；apply 后立即记录，rollout 结束后更新状态
+	operationLog := applied.operationLog(uint(k8sResourceID), userID, "rollback")
+	operationLog.TargetResourceID = uint(target.Id)
+	output, _ := parseOutput(data)
+	s.finishApply(conn, userID, clients, target.ResourceType, applied, operationLog, func(connected bool) {
+		if connected {
+			sendCommandOutput(conn, fmt.Sprintf("kubectl rollout undo %d --to-revision=%d", uint32(k8sResourceID), target.Id), output, applied.Results, func(bool) string {
+				return fmt.Sprintf("资源 %s 已回滚到版本 %d (%s)，成功 %d/%d 个对象", applied.Name, target.Id, target.FileName, applied.Succeeded(), len(applied.Results))
+			})
+		}
+		scheduled_tasks.PushRunningResource()
+	})
+}
+
+// rollbackRenderOptions 回滚使用目标版本上次部署时的模板选项，请求中显式指定的选项优先
+func (s *SocketService) rollbackRenderOptions(target *dao.UserK8sResource, data map[string]interface{}) (k8s_manage.RenderOptions, error) {
+	options := parseRenderOptions(data)
+	deployed, err := s.userK8sResourceOperationLogDao.QueryLatestVersionApply(uint(target.Id), k8s_manage.AppliedOperationTypes)
+	if err != nil {
+		return options, fmt.Errorf("查询版本 %d 的部署记录失败: %v", target.Id, err)
+	}
+	if deployed == nil {
+		return options, nil
+	}
+	if _, exist := data["env"]; !exist {
+		options.Environment = deployed.Environment
+	}
+	if _, exist := data["docker_image_id"]; !exist {
+		options.DockerImageID = deployed.DockerImageID
+	}
+	if _, exist := data["overlay"]; !exist {
+		options.Overlay = deployed.Overlay
+	}
+	return options, nil
+}
+
+// formatResourceVersions 格式化资源版本历史
+func formatResourceVersions(versions []dao.UserK8sResource) string {
+	result := fmt.Sprintf("%-10s %-10s %-30s %-20s %-10s\n", "VERSION", "FATHER", "FILE", "CREATED", "CURRENT")
+	for i, version := range versions {
+		created := ""
+		if version.CreatedAt != nil {
+			created = version.CreatedAt.Format("2006-01-02 15:04:05")
+		}
+		current := ""
+		if i == 0 {
+			current = "*"
+		}
+		result += fmt.Sprintf("%-10d %-10d %-30s %-20s %-10s\n", version.Id, version.FatherResourceId, version.FileName, created, current)
+	}
+	return result
+}
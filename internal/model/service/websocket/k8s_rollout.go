@@ -0,0 +1,262 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	rolloutPollInterval = 2 * time.Second
+	rolloutTimeout      = 5 * time.Minute // 平台等待 rollout 完成的最长时间，超过后记录为 timeout
+)
+
+// deploymentRevisionAnnotation deployment controller 写入 Deployment 和 ReplicaSet 的版本号
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// rolloutStreamName rollout 跟踪登记的流名称，按操作和工作负载区分：同一工作负载重新 apply 时替换旧的跟踪，
+// rollout status 不会停止 apply / restart 的跟踪
+func rolloutStreamName(operation string, resourceType string, namespace string, name string) string {
+	return fmt.Sprintf("rollout %s %s/%s/%s", operation, resourceType, namespace, name)
+}
+
+// rolloutResult 一次 rollout 跟踪的最终结果
+type rolloutResult struct {
+	Status  string // succeeded / failed / timeout
+	Message string
+}
+
+// resourceStatus 转换为资源状态：失败为运行停止，超时说明副本仍在更新，记为容器重启
+func (r rolloutResult) resourceStatus() int {
+	switch r.Status {
+	case define.K8sRolloutSucceeded:
+		return define.K8sResourceStatusRun
+	case define.K8sRolloutTimeout:
+		return define.K8sResourceStatusRestart
+	default:
+		return define.K8sResourceStatusStop
+	}
+}
+
+// watchRollout 在后台跟踪 rollout，读循环可以继续接收 stop 消息；跟踪结束后推送结果并调用 done 记录操作日志。
+// 被 stop 或断开连接取消时 rollout 记为 timeout，connected 为 false，done 只记录操作日志，不再写入 websocket
+func (s *SocketService) watchRollout(conn *websocket.Conn, userID uint, operation string, clients *conf.K8sClients, resourceType string, namespace string, name string, done func(rollout rolloutResult, connected bool)) {
+	streamName := rolloutStreamName(operation, resourceType, namespace, name)
+	ctx := s.startStream(userID, streamName)
+	go func() {
+		defer s.finishStream(userID, streamName, ctx)
+
+		rollout := s.trackRollout(ctx, conn, clients, resourceType, namespace, name)
+		connected := ctx.Err() == nil
+		if connected {
+			s.sendRolloutResult(conn, fmt.Sprintf("kubectl rollout status %s/%s -n %s", resourceType, name, namespace), rollout)
+		}
+		done(rollout, connected)
+	}()
+}
+
+// trackRollout 跟踪 Deployment / StatefulSet / DaemonSet 的 rollout 直到完成、失败、超时或 ctx 被取消，进度有变化时推送到 websocket。
+// 判断方式与 kubectl rollout status 一致：先等待 observedGeneration 追上 generation，
+// 再检查 Progressing 条件和新旧副本数
+func (s *SocketService) trackRollout(streamCtx context.Context, conn *websocket.Conn, clients *conf.K8sClients, resourceType string, namespace string, name string) rolloutResult {
+	ctx, cancel := context.WithTimeout(streamCtx, rolloutTimeout)
+	defer cancel()
+
+	command := fmt.Sprintf("kubectl rollout status %s/%s -n %s", resourceType, name, namespace)
+	// stopped 跟踪被 stop 或断开连接取消，rollout 仍在集群中继续，与平台等待超时一样记为 timeout
+	stopped := func(lastMessage string) rolloutResult {
+		if streamCtx.Err() != nil {
+			return s.finishRollout(command, name, rolloutResult{Status: define.K8sRolloutTimeout, Message: fmt.Sprintf("已停止跟踪 %s %q 的 rollout: %s", resourceType, name, lastMessage)})
+		}
+		return s.finishRollout(command, name, rolloutResult{Status: define.K8sRolloutTimeout, Message: fmt.Sprintf("等待 %s %q rollout 超时: %s", resourceType, name, lastMessage)})
+	}
+	ticker := time.NewTicker(rolloutPollInterval)
+	defer ticker.Stop()
+
+	lastMessage := ""
+	for {
+		message, done, failed, err := s.workloadProgress(ctx, clients, resourceType, namespace, name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return stopped(lastMessage)
+			}
+			return s.finishRollout(command, name, rolloutResult{Status: define.K8sRolloutFailed, Message: fmt.Sprintf("获取 %s %q 失败: %v", resourceType, name, err)})
+		}
+		if failed {
+			return s.finishRollout(command, name, rolloutResult{Status: define.K8sRolloutFailed, Message: message})
+		}
+		if done {
+			return s.finishRollout(command, name, rolloutResult{Status: define.K8sRolloutSucceeded, Message: message})
+		}
+
+		if message != lastMessage {
+			lastMessage = message
+			// 断开连接或 stop 后不再写入
+			if streamCtx.Err() == nil {
+				SendSuccess(conn, "rollout progress", K8sCommandResponse{
+					Command: command,
+					Result:  message,
+				})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return stopped(lastMessage)
+		case <-ticker.C:
+		}
+	}
+}
+
+// workloadProgress 查询工作负载当前的 rollout 进度，返回进度描述以及是否完成、是否失败
+func (s *SocketService) workloadProgress(ctx context.Context, clients *conf.K8sClients, resourceType string, namespace string, name string) (string, bool, bool, error) {
+	switch resourceType {
+	case "statefulset":
+		statefulSet, err := clients.Client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", false, false, err
+		}
+		message, done := statefulSetRolloutProgress(statefulSet)
+		return message, done, false, nil
+	case "daemonset":
+		daemonSet, err := clients.Client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", false, false, err
+		}
+		message, done := daemonSetRolloutProgress(daemonSet)
+		return message, done, false, nil
+	default:
+		deployment, err := clients.Client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", false, false, err
+		}
+		message, done, failed := rolloutProgress(deployment)
+		if !done && !failed {
+			if replicaSet := s.newReplicaSetProgress(ctx, clients, deployment); replicaSet != "" {
+				message += "\n" + replicaSet
+			}
+		}
+		return message, done, failed, nil
+	}
+}
+
+func (s *SocketService) finishRollout(command string, name string, result rolloutResult) rolloutResult {
+	logrus.Infof("%s: %s, %s", command, result.Status, result.Message)
+	return result
+}
+
+// rolloutProgress 根据 Deployment 的状态返回进度描述，以及是否完成、是否失败
+func rolloutProgress(deployment *appsv1.Deployment) (string, bool, bool) {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return "Waiting for deployment spec update to be observed...", false, false
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return fmt.Sprintf("deployment %q exceeded its progress deadline: %s", deployment.Name, condition.Message), false, true
+		}
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	switch {
+	case status.UpdatedReplicas < replicas:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated...", deployment.Name, status.UpdatedReplicas, replicas), false, false
+	case status.Replicas > status.UpdatedReplicas:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d old replicas are pending termination...", deployment.Name, status.Replicas-status.UpdatedReplicas), false, false
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d of %d updated replicas are available...", deployment.Name, status.AvailableReplicas, status.UpdatedReplicas), false, false
+	}
+	return fmt.Sprintf("deployment %q successfully rolled out", deployment.Name), true, false
+}
+
+// statefulSetRolloutProgress 与 kubectl rollout status statefulset 一致，OnDelete 策略不跟踪
+func statefulSetRolloutProgress(statefulSet *appsv1.StatefulSet) (string, bool) {
+	if statefulSet.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return fmt.Sprintf("statefulset %q uses the %s update strategy, rollout status is not tracked", statefulSet.Name, statefulSet.Spec.UpdateStrategy.Type), true
+	}
+	if statefulSet.Status.ObservedGeneration == 0 || statefulSet.Generation > statefulSet.Status.ObservedGeneration {
+		return "Waiting for statefulset spec update to be observed...", false
+	}
+
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	status := statefulSet.Status
+	if status.ReadyReplicas < replicas {
+		return fmt.Sprintf("Waiting for %d pods to be ready...", replicas-status.ReadyReplicas), false
+	}
+	// 分区更新时只要求分区以上的 Pod 更新完成
+	if rollingUpdate := statefulSet.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.Partition != nil && *rollingUpdate.Partition > 0 {
+		if status.UpdatedReplicas < replicas-*rollingUpdate.Partition {
+			return fmt.Sprintf("Waiting for partitioned roll out to finish: %d out of %d new pods have been updated...",
+				status.UpdatedReplicas, replicas-*rollingUpdate.Partition), false
+		}
+		return fmt.Sprintf("partitioned roll out complete: %d new pods have been updated...", status.UpdatedReplicas), true
+	}
+	if status.UpdateRevision != status.CurrentRevision {
+		return fmt.Sprintf("waiting for statefulset rolling update to complete %d pods at revision %s...", status.UpdatedReplicas, status.UpdateRevision), false
+	}
+	return fmt.Sprintf("statefulset rolling update complete %d pods at revision %s...", status.CurrentReplicas, status.CurrentRevision), true
+}
+
+// daemonSetRolloutProgress 与 kubectl rollout status daemonset 一致，OnDelete 策略不跟踪
+func daemonSetRolloutProgress(daemonSet *appsv1.DaemonSet) (string, bool) {
+	if daemonSet.Spec.UpdateStrategy.Type != appsv1.RollingUpdateDaemonSetStrategyType {
+		return fmt.Sprintf("daemonset %q uses the %s update strategy, rollout status is not tracked", daemonSet.Name, daemonSet.Spec.UpdateStrategy.Type), true
+	}
+	if daemonSet.Generation > daemonSet.Status.ObservedGeneration {
+		return "Waiting for daemon set spec update to be observed...", false
+	}
+
+	status := daemonSet.Status
+	if status.UpdatedNumberScheduled < status.DesiredNumberScheduled {
+		return fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d out of %d new pods have been updated...",
+			daemonSet.Name, status.UpdatedNumberScheduled, status.DesiredNumberScheduled), false
+	}
+	if status.NumberAvailable < status.DesiredNumberScheduled {
+		return fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d of %d updated pods are available...",
+			daemonSet.Name, status.NumberAvailable, status.DesiredNumberScheduled), false
+	}
+	return fmt.Sprintf("daemon set %q successfully rolled out", daemonSet.Name), true
+}
+
+// newReplicaSetProgress 查询当前版本 ReplicaSet 的副本进度，找不到时返回空
+func (s *SocketService) newReplicaSetProgress(ctx context.Context, clients *conf.K8sClients, deployment *appsv1.Deployment) string {
+	if deployment.Spec.Selector == nil {
+		return ""
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return ""
+	}
+	replicaSets, err := clients.Client.AppsV1().ReplicaSets(deployment.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return ""
+	}
+
+	revision := deployment.Annotations[deploymentRevisionAnnotation]
+	for _, rs := range replicaSets.Items {
+		if !metav1.IsControlledBy(&rs, deployment) || rs.Annotations[deploymentRevisionAnnotation] != revision {
+			continue
+		}
+		desired := int32(0)
+		if rs.Spec.Replicas != nil {
+			desired = *rs.Spec.Replicas
+		}
+		return fmt.Sprintf("ReplicaSet %s (revision %s): %d desired, %d ready, %d available",
+			rs.Name, revision, desired, rs.Status.ReadyReplicas, rs.Status.AvailableReplicas)
+	}
+	return ""
+}
@@ -0,0 +1,276 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/scheduled_tasks"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// restartedAtAnnotation 与 kubectl rollout restart 一致，修改 pod 模板的注解触发滚动重启
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// deploymentTarget 按 k8s_resource_id 找到的线上 Deployment
+type deploymentTarget struct {
+	resource   *dao.UserK8sResource
+	clients    *conf.K8sClients
+	latestLog  *dao.UserK8sResourceOperationLog
+	deployment *appsv1.Deployment
+}
+
+// command 作用于该 Deployment 的完整 kubectl 命令
+func (t *deploymentTarget) command(verb string) string {
+	return fmt.Sprintf("kubectl %s deployment/%s -n %s", verb, t.latestLog.MetadataName, t.latestLog.Namespace)
+}
+
+// replicas Deployment 期望的副本数，未设置时与 k8s 默认值一致为 1
+func (t *deploymentTarget) replicas() int32 {
+	if t.deployment.Spec.Replicas == nil {
+		return 1
+	}
+	return *t.deployment.Spec.Replicas
+}
+
+// loadDeployedResource 查询资源及其最新部署记录，并校验调用者对资源、集群和 namespace 的权限
+func (s *SocketService) loadDeployedResource(data map[string]interface{}, userID uint) (*dao.UserK8sResource, *conf.K8sClients, *dao.UserK8sResourceOperationLog, error) {
+	k8sResourceID, exist := data["k8s_resource_id"].(float64)
+	if !exist {
+		return nil, nil, nil, fmt.Errorf("缺少k8s_resource_id 参数")
+	}
+
+	resource, err := s.userK8sResourceDao.QueryById(uint32(k8sResourceID))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("查询资源失败: %v", err)
+	}
+	if err := s.k8sResourceService.CheckResourceOwner(userID, &resource); err != nil {
+		return nil, nil, nil, err
+	}
+
+	clients, err := s.clusterClientsByID(resource.ClusterID, userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	logs, err := s.userK8sResourceOperationLogDao.QueryByK8sResourceIDFirst(uint(k8sResourceID))
+	if err != nil || len(logs) == 0 {
+		return nil, nil, nil, fmt.Errorf("资源尚未部署")
+	}
+	latestLog := logs[0]
+	if latestLog.OperationType == "delete" {
+		return nil, nil, nil, fmt.Errorf("%s 已经关闭", latestLog.MetadataName)
+	}
+	if err := s.k8sNamespaceService.CheckNamespaceAccess(userID, resource.ClusterID, latestLog.Namespace); err != nil {
+		return nil, nil, nil, err
+	}
+	return &resource, clients, latestLog, nil
+}
+
+// loadDeploymentTarget 查询资源最新部署的 Deployment
+func (s *SocketService) loadDeploymentTarget(data map[string]interface{}, userID uint) (*deploymentTarget, error) {
+	resource, clients, latestLog, err := s.loadDeployedResource(data, userID)
+	if err != nil {
+		return nil, err
+	}
+	if resource.ResourceType != "deployment" {
+		return nil, fmt.Errorf("只有 deployment 类型的资源支持该命令")
+	}
+
+	deployment, err := clients.Client.AppsV1().Deployments(latestLog.Namespace).Get(context.TODO(), latestLog.MetadataName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("获取 deployment %s 失败: %v", latestLog.MetadataName, err)
+	}
+
+	return &deploymentTarget{
+		resource:   resource,
+		clients:    clients,
+		latestLog:  latestLog,
+		deployment: deployment,
+	}, nil
+}
+
+// saveDeploymentLog 记录 scale / rollout 操作日志，沿用最新日志中实际部署的版本信息
+func (s *SocketService) saveDeploymentLog(target *deploymentTarget, userID uint, operationType string, status int, command string, replicasBefore int32, replicasAfter int32, rolloutStatus string) {
+	latestLog := target.latestLog
+	operationLog := &dao.UserK8sResourceOperationLog{
+		K8sResourceID:    latestLog.K8sResourceID,
+		TargetResourceID: latestLog.TargetResourceID,
+		Overlay:          latestLog.Overlay,
+		Environment:      latestLog.Environment,
+		DockerImageID:    latestLog.DockerImageID,
+		UserID:           userID,
+		Namespace:        latestLog.Namespace,
+		MetadataName:     latestLog.MetadataName,
+		MetadataLabels:   latestLog.MetadataLabels,
+		OperationType:    operationType,
+		Status:           status,
+		RolloutStatus:    rolloutStatus,
+		ReplicasBefore:   &replicasBefore,
+		ReplicasAfter:    &replicasAfter,
+		Command:          command,
+	}
+	if err := s.userK8sResourceOperationLogDao.Create(operationLog); err != nil {
+		logrus.Errorf("保存操作日志失败: %v", err)
+		// 继续执行，不中断流程
+	}
+}
+
+// handleScale 处理 kubectl scale 命令，修改 Deployment 的副本数
+func (s *SocketService) handleScale(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	logrus.Info("resource scale ", "data: ", data)
+	replicas, exist := data["replicas"].(float64)
+	if !exist {
+		SendError(conn, "缺少 replicas 参数")
+		return
+	}
+
+	target, err := s.loadDeploymentTarget(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	// 配置了自动扩缩容时副本数由 HPA 管理，手动修改会被立即覆盖
+	autoscaler, err := s.k8sResourceService.QueryAutoscaler(target.resource.Id)
+	if err != nil {
+		SendError(conn, fmt.Sprintf("查询扩缩容策略失败: %v", err))
+		return
+	}
+	if autoscaler != nil {
+		SendError(conn, fmt.Sprintf("资源已配置自动扩缩容（%d-%d 副本），请修改扩缩容策略", autoscaler.MinReplicas, autoscaler.MaxReplicas))
+		return
+	}
+
+	deployments := target.clients.Client.AppsV1().Deployments(target.latestLog.Namespace)
+	scale, err := deployments.GetScale(context.TODO(), target.latestLog.MetadataName, metav1.GetOptions{})
+	if err != nil {
+		SendError(conn, fmt.Sprintf("获取 deployment %s 的副本数失败: %v", target.latestLog.MetadataName, err))
+		return
+	}
+	before := scale.Spec.Replicas
+	scale.Spec.Replicas = int32(replicas)
+	if _, err := deployments.UpdateScale(context.TODO(), target.latestLog.MetadataName, scale, metav1.UpdateOptions{FieldManager: define.K8sFieldManager}); err != nil {
+		SendError(conn, fmt.Sprintf("修改 deployment %s 的副本数失败: %v", target.latestLog.MetadataName, err))
+		return
+	}
+
+	fullCommand := fmt.Sprintf("%s --replicas=%d", target.command("scale"), int32(replicas))
+	// 从 0 扩容时资源重新运行，状态检查任务之后会按 Pod 的实际状态更新
+	status := define.K8sResourceStatusRun
+	if replicas == 0 {
+		status = define.K8sResourceStatusStop
+	}
+	s.saveDeploymentLog(target, userID, "scale", status, fullCommand, before, int32(replicas), "")
+
+	SendSuccess(conn, "command execute success", K8sCommandResponse{
+		Command: fullCommand,
+		Result:  fmt.Sprintf("deployment.apps/%s scaled (%d -> %d)", target.latestLog.MetadataName, before, int32(replicas)),
+	})
+	scheduled_tasks.PushRunningResource()
+}
+
+// handleRolloutRestart 处理 kubectl rollout restart 命令，修改 pod 模板注解触发滚动重启并跟踪 rollout
+func (s *SocketService) handleRolloutRestart(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	logrus.Info("resource rollout restart ", "data: ", data)
+	target, err := s.loadDeploymentTarget(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	if target.deployment.Spec.Paused {
+		SendError(conn, fmt.Sprintf("deployment %s 已暂停，请先执行 kubectl rollout resume", target.latestLog.MetadataName))
+		return
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`, restartedAtAnnotation, time.Now().Format(time.RFC3339))
+	if err := s.patchDeployment(target, patch); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	SendSuccess(conn, "command execute success", K8sCommandResponse{
+		Command: target.command("rollout restart"),
+		Result:  fmt.Sprintf("deployment.apps/%s restarted", target.latestLog.MetadataName),
+	})
+
+	// rollout 结束后再记录操作日志
+	replicas := target.replicas()
+	s.watchRollout(conn, userID, "restart", target.clients, "deployment", target.latestLog.Namespace, target.latestLog.MetadataName, func(rollout rolloutResult, _ bool) {
+		s.saveDeploymentLog(target, userID, "restart", rollout.resourceStatus(), target.command("rollout restart"), replicas, replicas, rollout.Status)
+		scheduled_tasks.PushRunningResource()
+	})
+}
+
+// handleRolloutPause 处理 kubectl rollout pause / resume 命令
+func (s *SocketService) handleRolloutPause(conn *websocket.Conn, command string, data map[string]interface{}, userID uint, paused bool) {
+	logrus.Info("resource rollout pause ", "paused: ", paused, " data: ", data)
+	target, err := s.loadDeploymentTarget(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	verb, action := "rollout pause", "paused"
+	if !paused {
+		verb, action = "rollout resume", "resumed"
+	}
+	if target.deployment.Spec.Paused == paused {
+		SendError(conn, fmt.Sprintf("deployment %s 已经是 %s 状态", target.latestLog.MetadataName, action))
+		return
+	}
+
+	if err := s.patchDeployment(target, fmt.Sprintf(`{"spec":{"paused":%t}}`, paused)); err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	replicas := target.replicas()
+	s.saveDeploymentLog(target, userID, verb[len("rollout "):], target.latestLog.Status, target.command(verb), replicas, replicas, "")
+
+	SendSuccess(conn, "command execute success", K8sCommandResponse{
+		Command: target.command(verb),
+		Result:  fmt.Sprintf("deployment.apps/%s %s", target.latestLog.MetadataName, action),
+	})
+}
+
+// handleRolloutStatus 处理 kubectl rollout status 命令，推送进度直到 rollout 完成、失败或超时
+func (s *SocketService) handleRolloutStatus(conn *websocket.Conn, command string, data map[string]interface{}, userID uint) {
+	target, err := s.loadDeploymentTarget(data, userID)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+	if target.deployment.Spec.Paused {
+		SendError(conn, fmt.Sprintf("deployment %s 已暂停，rollout 不会继续", target.latestLog.MetadataName))
+		return
+	}
+
+	s.watchRollout(conn, userID, "status", target.clients, "deployment", target.latestLog.Namespace, target.latestLog.MetadataName, func(rolloutResult, bool) {})
+}
+
+func (s *SocketService) patchDeployment(target *deploymentTarget, patch string) error {
+	_, err := target.clients.Client.AppsV1().Deployments(target.latestLog.Namespace).Patch(context.TODO(), target.latestLog.MetadataName,
+		types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{FieldManager: define.K8sFieldManager})
+	if err != nil {
+		return fmt.Errorf("修改 deployment %s 失败: %v", target.latestLog.MetadataName, err)
+	}
+	return nil
+}
+
+func (s *SocketService) sendRolloutResult(conn *websocket.Conn, command string, rollout rolloutResult) {
+	if rollout.Status != define.K8sRolloutSucceeded {
+		SendError(conn, fmt.Sprintf("rollout %s: %s", rollout.Status, rollout.Message))
+		return
+	}
+	SendSuccess(conn, "command execute success", K8sCommandResponse{
+		Command: command,
+		Result:  rollout.Message,
+	})
+}
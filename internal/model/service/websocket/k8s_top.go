@@ -0,0 +1,108 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/gorilla/websocket"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// handleTopPods 处理 kubectl top pod 命令，未指定 namespace 时查询全部 namespace
+func (s *SocketService) handleTopPods(conn *websocket.Conn, clients *conf.K8sClients, command string, data map[string]interface{}) {
+	namespace, _ := data["namespace"].(string)
+	labelSelector, _ := data["label_selector"].(string)
+
+	fullCommand := command + " -A"
+	if namespace != "" {
+		fullCommand = fmt.Sprintf("%s -n %s", command, namespace)
+	}
+	if labelSelector != "" {
+		fullCommand += fmt.Sprintf(" -l %s", labelSelector)
+	}
+
+	metrics, err := k8s_manage.ListPodMetrics(context.TODO(), clients, namespace, labelSelector)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	output, _ := parseOutput(data)
+	sendCommandOutput(conn, fullCommand, output, metrics, func(bool) string {
+		return formatTopPods(metrics)
+	})
+}
+
+// handleTopNodes 处理 kubectl top node 命令，百分比按节点的 allocatable 计算
+func (s *SocketService) handleTopNodes(conn *websocket.Conn, clients *conf.K8sClients, command string, data map[string]interface{}) {
+	metrics, err := k8s_manage.ListNodeMetrics(context.TODO(), clients)
+	if err != nil {
+		SendError(conn, err.Error())
+		return
+	}
+
+	nodes, err := clients.Client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		SendError(conn, fmt.Sprintf("failed to get nodes: %v", err))
+		return
+	}
+
+	output, _ := parseOutput(data)
+	sendCommandOutput(conn, command, output, metrics, func(bool) string {
+		return formatTopNodes(metrics, nodes)
+	})
+}
+
+func formatTopPods(metrics []k8s_manage.PodMetrics) string {
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Namespace != metrics[j].Namespace {
+			return metrics[i].Namespace < metrics[j].Namespace
+		}
+		return metrics[i].Name < metrics[j].Name
+	})
+
+	result := fmt.Sprintf("%-20s %-40s %-12s %-15s\n", "NAMESPACE", "NAME", "CPU(cores)", "MEMORY(bytes)")
+	for _, pod := range metrics {
+		result += fmt.Sprintf("%-20s %-40s %-12s %-15s\n",
+			pod.Namespace, pod.Name, formatCPU(pod.Usage), formatMemory(pod.Usage))
+	}
+	return result
+}
+
+func formatTopNodes(metrics []k8s_manage.NodeMetrics, nodes *v1.NodeList) string {
+	allocatable := make(map[string]v1.ResourceList, len(nodes.Items))
+	for _, node := range nodes.Items {
+		allocatable[node.Name] = node.Status.Allocatable
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+
+	result := fmt.Sprintf("%-30s %-12s %-8s %-15s %-8s\n", "NAME", "CPU(cores)", "CPU%", "MEMORY(bytes)", "MEMORY%")
+	for _, node := range metrics {
+		cpuPercent, memoryPercent := "<unknown>", "<unknown>"
+		if resources, ok := allocatable[node.Name]; ok {
+			if cpu := resources.Cpu().MilliValue(); cpu > 0 {
+				cpuPercent = fmt.Sprintf("%d%%", node.Usage.CPU.MilliValue()*100/cpu)
+			}
+			if memory := resources.Memory().Value(); memory > 0 {
+				memoryPercent = fmt.Sprintf("%d%%", node.Usage.Memory.Value()*100/memory)
+			}
+		}
+		result += fmt.Sprintf("%-30s %-12s %-8s %-15s %-8s\n",
+			node.Name, formatCPU(node.Usage), cpuPercent, formatMemory(node.Usage), memoryPercent)
+	}
+	return result
+}
+
+// formatCPU 与 kubectl top 一致，CPU 以 millicore 展示
+func formatCPU(usage k8s_manage.ResourceUsage) string {
+	return fmt.Sprintf("%dm", usage.CPU.MilliValue())
+}
+
+// formatMemory 与 kubectl top 一致，内存以 Mi 展示
+func formatMemory(usage k8s_manage.ResourceUsage) string {
+	return fmt.Sprintf("%dMi", usage.Memory.Value()/(1024*1024))
+}
@@ -0,0 +1,333 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ZZGADA/easy-deploy/internal/define"
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// resourceStatusNames 资源状态的展示名称
+var resourceStatusNames = map[int]string{
+	define.K8sResourceStatusRun:       "Running",
+	define.K8sResourceStatusStop:      "Stopped",
+	define.K8sResourceStatusRestart:   "Progressing",
+	define.K8sResourceStatusSucceeded: "Succeeded",
+	define.K8sResourceStatusFailed:    "Failed",
+}
+
+// getWorkload 查询 StatefulSet / DaemonSet / Job / CronJob
+func getWorkload(ctx context.Context, clients *conf.K8sClients, resourceType string, namespace string, name string) (runtime.Object, error) {
+	switch resourceType {
+	case "statefulset":
+		return clients.Client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	case "daemonset":
+		return clients.Client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	case "job":
+		return clients.Client.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	case "cronjob":
+		return clients.Client.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	return nil, fmt.Errorf("不支持的资源类型: %s", resourceType)
+}
+
+// formatWorkload 以表格输出单个工作负载
+func formatWorkload(obj runtime.Object, wide bool) string {
+	switch typed := obj.(type) {
+	case *appsv1.StatefulSet:
+		return formatStatefulSets(&appsv1.StatefulSetList{Items: []appsv1.StatefulSet{*typed}}, wide)
+	case *appsv1.DaemonSet:
+		return formatDaemonSets(&appsv1.DaemonSetList{Items: []appsv1.DaemonSet{*typed}}, wide)
+	case *batchv1.Job:
+		return formatJobs(&batchv1.JobList{Items: []batchv1.Job{*typed}}, wide)
+	case *batchv1.CronJob:
+		return formatCronJobs(&batchv1.CronJobList{Items: []batchv1.CronJob{*typed}}, wide)
+	}
+	return ""
+}
+
+// formatWorkloadDetail 输出单个工作负载的详细信息，包含按资源类型计算的健康状态
+func formatWorkloadDetail(obj runtime.Object) string {
+	switch typed := obj.(type) {
+	case *appsv1.StatefulSet:
+		return formatStatefulSetDetail(typed)
+	case *appsv1.DaemonSet:
+		return formatDaemonSetDetail(typed)
+	case *batchv1.Job:
+		return formatJobDetail(typed)
+	case *batchv1.CronJob:
+		return formatCronJobDetail(typed)
+	}
+	return ""
+}
+
+func formatStatefulSets(statefulSets *appsv1.StatefulSetList, wide bool) string {
+	result := fmt.Sprintf("%-20s %-20s %-10s %-10s", "NAMESPACE", "NAME", "READY", "AGE")
+	if wide {
+		result += fmt.Sprintf(" %-20s %-40s", "CONTAINERS", "IMAGES")
+	}
+	result += "\n"
+	now := time.Now()
+	for _, sts := range statefulSets.Items {
+		replicas := int32(1)
+		if sts.Spec.Replicas != nil {
+			replicas = *sts.Spec.Replicas
+		}
+		result += fmt.Sprintf("%-20s %-20s %-10s %-10s",
+			sts.Namespace, sts.Name, fmt.Sprintf("%d/%d", sts.Status.ReadyReplicas, replicas), formatDuration(now.Sub(sts.CreationTimestamp.Time)))
+		if wide {
+			containers, images := podTemplateContainers(sts.Spec.Template.Spec)
+			result += fmt.Sprintf(" %-20s %-40s", containers, images)
+		}
+		result += "\n"
+	}
+	return result
+}
+
+func formatDaemonSets(daemonSets *appsv1.DaemonSetList, wide bool) string {
+	result := fmt.Sprintf("%-20s %-20s %-8s %-8s %-8s %-11s %-10s %-20s %-10s",
+		"NAMESPACE", "NAME", "DESIRED", "CURRENT", "READY", "UP-TO-DATE", "AVAILABLE", "NODE SELECTOR", "AGE")
+	if wide {
+		result += fmt.Sprintf(" %-20s %-40s %-30s", "CONTAINERS", "IMAGES", "SELECTOR")
+	}
+	result += "\n"
+	now := time.Now()
+	for _, ds := range daemonSets.Items {
+		status := ds.Status
+		result += fmt.Sprintf("%-20s %-20s %-8d %-8d %-8d %-11d %-10d %-20s %-10s",
+			ds.Namespace, ds.Name, status.DesiredNumberScheduled, status.CurrentNumberScheduled, status.NumberReady,
+			status.UpdatedNumberScheduled, status.NumberAvailable, formatSelector(ds.Spec.Template.Spec.NodeSelector),
+			formatDuration(now.Sub(ds.CreationTimestamp.Time)))
+		if wide {
+			containers, images := podTemplateContainers(ds.Spec.Template.Spec)
+			result += fmt.Sprintf(" %-20s %-40s %-30s", containers, images, labelSelector(ds.Spec.Selector))
+		}
+		result += "\n"
+	}
+	return result
+}
+
+func formatJobs(jobs *batchv1.JobList, wide bool) string {
+	result := fmt.Sprintf("%-20s %-20s %-10s %-12s %-10s %-10s", "NAMESPACE", "NAME", "STATUS", "COMPLETIONS", "DURATION", "AGE")
+	if wide {
+		result += fmt.Sprintf(" %-20s %-40s %-30s", "CONTAINERS", "IMAGES", "SELECTOR")
+	}
+	result += "\n"
+	now := time.Now()
+	for _, job := range jobs.Items {
+		status, _ := k8s_manage.JobHealth(&job)
+		completions := "1"
+		if job.Spec.Completions != nil {
+			completions = fmt.Sprintf("%d", *job.Spec.Completions)
+		}
+		duration := ""
+		if job.Status.StartTime != nil {
+			end := now
+			if job.Status.CompletionTime != nil {
+				end = job.Status.CompletionTime.Time
+			}
+			duration = formatDuration(end.Sub(job.Status.StartTime.Time))
+		}
+		result += fmt.Sprintf("%-20s %-20s %-10s %-12s %-10s %-10s",
+			job.Namespace, job.Name, resourceStatusNames[status], fmt.Sprintf("%d/%s", job.Status.Succeeded, completions),
+			duration, formatDuration(now.Sub(job.CreationTimestamp.Time)))
+		if wide {
+			containers, images := podTemplateContainers(job.Spec.Template.Spec)
+			result += fmt.Sprintf(" %-20s %-40s %-30s", containers, images, labelSelector(job.Spec.Selector))
+		}
+		result += "\n"
+	}
+	return result
+}
+
+func formatCronJobs(cronJobs *batchv1.CronJobList, wide bool) string {
+	result := fmt.Sprintf("%-20s %-20s %-15s %-8s %-7s %-14s %-10s", "NAMESPACE", "NAME", "SCHEDULE", "SUSPEND", "ACTIVE", "LAST SCHEDULE", "AGE")
+	if wide {
+		result += fmt.Sprintf(" %-20s %-40s", "CONTAINERS", "IMAGES")
+	}
+	result += "\n"
+	now := time.Now()
+	for _, cronJob := range cronJobs.Items {
+		lastSchedule := "<none>"
+		if cronJob.Status.LastScheduleTime != nil {
+			lastSchedule = formatDuration(now.Sub(cronJob.Status.LastScheduleTime.Time))
+		}
+		result += fmt.Sprintf("%-20s %-20s %-15s %-8t %-7d %-14s %-10s",
+			cronJob.Namespace, cronJob.Name, cronJob.Spec.Schedule, cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend,
+			len(cronJob.Status.Active), lastSchedule, formatDuration(now.Sub(cronJob.CreationTimestamp.Time)))
+		if wide {
+			containers, images := podTemplateContainers(cronJob.Spec.JobTemplate.Spec.Template.Spec)
+			result += fmt.Sprintf(" %-20s %-40s", containers, images)
+		}
+		result += "\n"
+	}
+	return result
+}
+
+func formatStatefulSetDetail(statefulSet *appsv1.StatefulSet) string {
+	status, message := k8s_manage.StatefulSetHealth(statefulSet)
+	result := formatObjectMeta(statefulSet.ObjectMeta)
+	result += fmt.Sprintf("Selector: %s\n", labelSelector(statefulSet.Spec.Selector))
+	result += fmt.Sprintf("Service Name: %s\n", statefulSet.Spec.ServiceName)
+	result += fmt.Sprintf("Update Strategy: %s\n", statefulSet.Spec.UpdateStrategy.Type)
+	result += fmt.Sprintf("Pod Management Policy: %s\n", statefulSet.Spec.PodManagementPolicy)
+	result += fmt.Sprintf("Replicas: %d ready | %d current | %d updated | %d available\n",
+		statefulSet.Status.ReadyReplicas, statefulSet.Status.CurrentReplicas, statefulSet.Status.UpdatedReplicas, statefulSet.Status.AvailableReplicas)
+	result += fmt.Sprintf("Revision: current %s, update %s\n", statefulSet.Status.CurrentRevision, statefulSet.Status.UpdateRevision)
+	result += fmt.Sprintf("Health: %s (%s)\n", resourceStatusNames[status], message)
+	result += formatPodTemplate(statefulSet.Spec.Template.Spec)
+	if len(statefulSet.Spec.VolumeClaimTemplates) > 0 {
+		result += "Volume Claims:\n"
+		for i, claim := range statefulSet.Spec.VolumeClaimTemplates {
+			storageClass := "<default>"
+			if claim.Spec.StorageClassName != nil {
+				storageClass = *claim.Spec.StorageClassName
+			}
+			result += fmt.Sprintf("  %d. %s\n", i+1, claim.Name)
+			result += fmt.Sprintf("     StorageClass: %s\n", storageClass)
+			result += fmt.Sprintf("     Capacity: %s\n", claim.Spec.Resources.Requests.Storage().String())
+			result += fmt.Sprintf("     Access Modes: %v\n", claim.Spec.AccessModes)
+		}
+	}
+	return result
+}
+
+func formatDaemonSetDetail(daemonSet *appsv1.DaemonSet) string {
+	status, message := k8s_manage.DaemonSetHealth(daemonSet)
+	result := formatObjectMeta(daemonSet.ObjectMeta)
+	result += fmt.Sprintf("Selector: %s\n", labelSelector(daemonSet.Spec.Selector))
+	result += fmt.Sprintf("Node-Selector: %s\n", formatSelector(daemonSet.Spec.Template.Spec.NodeSelector))
+	result += fmt.Sprintf("Update Strategy: %s\n", daemonSet.Spec.UpdateStrategy.Type)
+	result += fmt.Sprintf("Desired Number of Nodes Scheduled: %d\n", daemonSet.Status.DesiredNumberScheduled)
+	result += fmt.Sprintf("Current Number of Nodes Scheduled: %d\n", daemonSet.Status.CurrentNumberScheduled)
+	result += fmt.Sprintf("Number of Nodes Scheduled with Up-to-date Pods: %d\n", daemonSet.Status.UpdatedNumberScheduled)
+	result += fmt.Sprintf("Number of Nodes Scheduled with Available Pods: %d\n", daemonSet.Status.NumberAvailable)
+	result += fmt.Sprintf("Number of Nodes Misscheduled: %d\n", daemonSet.Status.NumberMisscheduled)
+	result += fmt.Sprintf("Health: %s (%s)\n", resourceStatusNames[status], message)
+	result += formatPodTemplate(daemonSet.Spec.Template.Spec)
+	return result
+}
+
+func formatJobDetail(job *batchv1.Job) string {
+	status, message := k8s_manage.JobHealth(job)
+	result := formatObjectMeta(job.ObjectMeta)
+	result += fmt.Sprintf("Selector: %s\n", labelSelector(job.Spec.Selector))
+	if job.Spec.Parallelism != nil {
+		result += fmt.Sprintf("Parallelism: %d\n", *job.Spec.Parallelism)
+	}
+	if job.Spec.Completions != nil {
+		result += fmt.Sprintf("Completions: %d\n", *job.Spec.Completions)
+	}
+	if job.Spec.BackoffLimit != nil {
+		result += fmt.Sprintf("Backoff Limit: %d\n", *job.Spec.BackoffLimit)
+	}
+	if job.Spec.ActiveDeadlineSeconds != nil {
+		result += fmt.Sprintf("Active Deadline Seconds: %ds\n", *job.Spec.ActiveDeadlineSeconds)
+	}
+	if job.Status.StartTime != nil {
+		result += fmt.Sprintf("Start Time: %s\n", job.Status.StartTime.Format("2006-01-02 15:04:05"))
+	}
+	if job.Status.CompletionTime != nil {
+		result += fmt.Sprintf("Completed At: %s\n", job.Status.CompletionTime.Format("2006-01-02 15:04:05"))
+	}
+	result += fmt.Sprintf("Pods Statuses: %d Active / %d Succeeded / %d Failed\n", job.Status.Active, job.Status.Succeeded, job.Status.Failed)
+	result += fmt.Sprintf("Health: %s (%s)\n", resourceStatusNames[status], message)
+	result += formatPodTemplate(job.Spec.Template.Spec)
+	result += "Conditions:\n"
+	for _, condition := range job.Status.Conditions {
+		result += fmt.Sprintf("  Type: %s, Status: %s, Reason: %s, Message: %s\n",
+			condition.Type, condition.Status, condition.Reason, condition.Message)
+	}
+	return result
+}
+
+func formatCronJobDetail(cronJob *batchv1.CronJob) string {
+	status, message := k8s_manage.CronJobHealth(cronJob)
+	result := formatObjectMeta(cronJob.ObjectMeta)
+	result += fmt.Sprintf("Schedule: %s\n", cronJob.Spec.Schedule)
+	if cronJob.Spec.TimeZone != nil {
+		result += fmt.Sprintf("Time Zone: %s\n", *cronJob.Spec.TimeZone)
+	}
+	result += fmt.Sprintf("Concurrency Policy: %s\n", cronJob.Spec.ConcurrencyPolicy)
+	result += fmt.Sprintf("Suspend: %t\n", cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend)
+	if cronJob.Spec.SuccessfulJobsHistoryLimit != nil {
+		result += fmt.Sprintf("Successful Job History Limit: %d\n", *cronJob.Spec.SuccessfulJobsHistoryLimit)
+	}
+	if cronJob.Spec.FailedJobsHistoryLimit != nil {
+		result += fmt.Sprintf("Failed Job History Limit: %d\n", *cronJob.Spec.FailedJobsHistoryLimit)
+	}
+	if cronJob.Status.LastScheduleTime != nil {
+		result += fmt.Sprintf("Last Schedule Time: %s\n", cronJob.Status.LastScheduleTime.Format("2006-01-02 15:04:05"))
+	}
+	if cronJob.Status.LastSuccessfulTime != nil {
+		result += fmt.Sprintf("Last Successful Time: %s\n", cronJob.Status.LastSuccessfulTime.Format("2006-01-02 15:04:05"))
+	}
+	var active []string
+	for _, job := range cronJob.Status.Active {
+		active = append(active, job.Name)
+	}
+	if len(active) == 0 {
+		active = []string{"<none>"}
+	}
+	result += fmt.Sprintf("Active Jobs: %s\n", strings.Join(active, ", "))
+	result += fmt.Sprintf("Health: %s (%s)\n", resourceStatusNames[status], message)
+	result += formatPodTemplate(cronJob.Spec.JobTemplate.Spec.Template.Spec)
+	return result
+}
+
+// formatObjectMeta 详细信息中的通用元数据
+func formatObjectMeta(meta metav1.ObjectMeta) string {
+	result := fmt.Sprintf("Name: %s\n", meta.Name)
+	result += fmt.Sprintf("Namespace: %s\n", meta.Namespace)
+	result += fmt.Sprintf("CreationTimestamp: %s\n", meta.CreationTimestamp.Format("2006-01-02 15:04:05"))
+	result += fmt.Sprintf("Labels: %v\n", meta.Labels)
+	result += fmt.Sprintf("Annotations: %v\n", meta.Annotations)
+	return result
+}
+
+// formatPodTemplate 详细信息中 Pod 模板的容器和卷，与 Deployment 的详细信息格式一致
+func formatPodTemplate(spec v1.PodSpec) string {
+	result := "Containers:\n"
+	for i, container := range spec.Containers {
+		result += fmt.Sprintf("  %d. %s\n", i+1, container.Name)
+		result += fmt.Sprintf("     Image: %s\n", container.Image)
+		result += fmt.Sprintf("     Port: %v\n", container.Ports)
+		result += fmt.Sprintf("     Command: %v\n", container.Command)
+		result += fmt.Sprintf("     Args: %v\n", container.Args)
+		result += fmt.Sprintf("     Env: %v\n", container.Env)
+		result += fmt.Sprintf("     Resources: %v\n", container.Resources)
+		result += fmt.Sprintf("     VolumeMounts: %v\n", container.VolumeMounts)
+	}
+	result += "Volumes:\n"
+	for i, volume := range spec.Volumes {
+		result += fmt.Sprintf("  %d. %s\n", i+1, volume.Name)
+		result += fmt.Sprintf("     Type: %v\n", volume)
+	}
+	return result
+}
+
+// podTemplateContainers wide 输出中的容器名和镜像
+func podTemplateContainers(spec v1.PodSpec) (string, string) {
+	var containers, images []string
+	for _, container := range spec.Containers {
+		containers = append(containers, container.Name)
+		images = append(images, container.Image)
+	}
+	return strings.Join(containers, ","), strings.Join(images, ",")
+}
+
+func labelSelector(selector *metav1.LabelSelector) string {
+	if selector == nil {
+		return "<none>"
+	}
+	return formatSelector(selector.MatchLabels)
+}
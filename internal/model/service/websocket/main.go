@@ -1,7 +1,13 @@
 package websocket
 
 import (
+	"context"
+	"sync"
+
+	"github.com/ZZGADA/easy-deploy/internal/model/conf"
 	"github.com/ZZGADA/easy-deploy/internal/model/dao"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/k8s_manage"
+	"github.com/ZZGADA/easy-deploy/internal/model/service/oss_manage"
 	"github.com/gorilla/websocket"
 )
 
@@ -12,9 +18,18 @@ type SocketService struct {
 	userK8sResourceDao             *dao.UserK8sResourceDao
 	userOssDao                     *dao.UserOssDao
 	userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao
+	userK8sResourceDriftDao        *dao.UserK8sResourceDriftDao
+	usersDao                       *dao.UsersDao
+	k8sClusterService              *k8s_manage.K8sClusterService
+	k8sNamespaceService            *k8s_manage.K8sNamespaceService
+	k8sResourceService             *k8s_manage.K8sResourceService
+	ossService                     *oss_manage.OssService // 资源文件和 chart 包按用户的 OSS 配置读取，不依赖连接是否存在
+
+	streamsMu sync.Mutex
+	streams   map[uint]map[string]context.CancelFunc // key: userID -> 流名称，用于停止日志等流式推送
 }
 
-func NewSocketService(dockerfileDao *dao.UserDockerfileDao, dockerDao dao.UserDockerDao, githubDao *dao.UserGithubDao, userK8sResourceDao *dao.UserK8sResourceDao, userOssDao *dao.UserOssDao, userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao) *SocketService {
+func NewSocketService(dockerfileDao *dao.UserDockerfileDao, dockerDao dao.UserDockerDao, githubDao *dao.UserGithubDao, userK8sResourceDao *dao.UserK8sResourceDao, userOssDao *dao.UserOssDao, userK8sResourceOperationLogDao *dao.UserK8sResourceOperationLogDao, userK8sResourceDriftDao *dao.UserK8sResourceDriftDao, usersDao *dao.UsersDao, k8sClusterService *k8s_manage.K8sClusterService, k8sNamespaceService *k8s_manage.K8sNamespaceService, k8sResourceService *k8s_manage.K8sResourceService, ossService *oss_manage.OssService) *SocketService {
 	return &SocketService{
 		userDockerfileDao:              dockerfileDao,
 		userDockerDao:                  dockerDao,
@@ -22,6 +37,13 @@ func NewSocketService(dockerfileDao *dao.UserDockerfileDao, dockerDao dao.UserDo
 		userK8sResourceDao:             userK8sResourceDao,
 		userOssDao:                     userOssDao,
 		userK8sResourceOperationLogDao: userK8sResourceOperationLogDao,
+		userK8sResourceDriftDao:        userK8sResourceDriftDao,
+		usersDao:                       usersDao,
+		k8sClusterService:              k8sClusterService,
+		k8sNamespaceService:            k8sNamespaceService,
+		k8sResourceService:             k8sResourceService,
+		ossService:                     ossService,
+		streams:                        make(map[uint]map[string]context.CancelFunc),
 	}
 }
 
@@ -44,7 +66,7 @@ func SendError(conn *websocket.Conn, message string) {
 		Success: false,
 		Message: message,
 	}
-	conn.WriteJSON(response)
+	conf.WSServer.WriteJSON(conn, response)
 }
 
 // SendSuccess 发送成功消息
@@ -54,5 +76,47 @@ func SendSuccess(conn *websocket.Conn, message string, data interface{}) {
 		Message: message,
 		Data:    data,
 	}
-	conn.WriteJSON(response)
+	conf.WSServer.WriteJSON(conn, response)
+}
+
+// startStream 为用户注册一个可取消的流，同名的旧流会被先停止
+func (s *SocketService) startStream(userID uint, name string) context.Context {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+
+	if s.streams[userID] == nil {
+		s.streams[userID] = make(map[string]context.CancelFunc)
+	}
+	if cancel, exist := s.streams[userID][name]; exist {
+		cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.streams[userID][name] = cancel
+	return ctx
+}
+
+// finishStream 流自然结束后移除登记
+func (s *SocketService) finishStream(userID uint, name string, ctx context.Context) {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+
+	// 只有当前登记的仍是这个流时才移除，避免误删同名的新流
+	if ctx.Err() == nil {
+		if cancel, exist := s.streams[userID][name]; exist {
+			cancel()
+			delete(s.streams[userID], name)
+		}
+	}
+}
+
+// StopStreams 停止用户所有的流式推送，客户端发送 stop 或断开连接时调用
+func (s *SocketService) StopStreams(userID uint) {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+
+	for _, cancel := range s.streams[userID] {
+		cancel()
+	}
+	delete(s.streams, userID)
 }
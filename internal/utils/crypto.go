@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// EncryptString 使用 AES-GCM 加密字符串，返回 base64 编码的密文（nonce 在前）
+func EncryptString(plainText string, secretKey string) (string, error) {
+	gcm, err := newGCM(secretKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("生成 nonce 失败: %v", err)
+	}
+
+	cipherText := gcm.Seal(nonce, nonce, []byte(plainText), nil)
+	return base64.StdEncoding.EncodeToString(cipherText), nil
+}
+
+// DecryptString 解密 EncryptString 生成的密文
+func DecryptString(cipherText string, secretKey string) (string, error) {
+	gcm, err := newGCM(secretKey)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(cipherText)
+	if err != nil {
+		return "", fmt.Errorf("密文格式错误: %v", err)
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("密文长度错误")
+	}
+
+	plainText, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %v", err)
+	}
+	return string(plainText), nil
+}
+
+// newGCM 由任意长度的密钥派生 AES-256 密钥
+func newGCM(secretKey string) (cipher.AEAD, error) {
+	if secretKey == "" {
+		return nil, fmt.Errorf("未配置加密密钥")
+	}
+	key := sha256.Sum256([]byte(secretKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}